@@ -0,0 +1,762 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"go-spass/quick-note/internal"
+)
+
+// argKind says what a positional argument or flag value is, so completion
+// knows what to offer for it.
+type argKind int
+
+const (
+	argNone argKind = iota
+	// argText is free text, such as a search topic; nothing is offered.
+	argText
+	argNote
+	argTag
+	argFolder
+	argFile
+	argDir
+	// argChoice is one of a fixed list of values.
+	argChoice
+)
+
+// command is a node of qn's command tree.
+type command struct {
+	name    string
+	aliases []string
+	// usage shows the positional arguments, such as "<note> [rev]".
+	usage   string
+	summary string
+	// help is shown by --help after the summary.
+	help string
+	// minArgs and maxArgs bound the number of positional arguments;
+	// maxArgs is -1 for no limit.
+	minArgs, maxArgs int
+	// args are the kinds of the positional arguments in order; the last
+	// one repeats when repeat is set.
+	args   []argKind
+	repeat bool
+	// choices are the values of an argChoice argument.
+	choices []string
+	// setup defines the command's flags on fs and returns the function
+	// that runs it. Commands without one only group subcommands.
+	setup func(fs *flagSet) runFunc
+	sub   []*command
+	// noVault commands run without $MDNOTES_DIR.
+	noVault bool
+	// raw commands get their arguments without flag parsing.
+	raw bool
+	// hidden commands are left out of help and completion.
+	hidden bool
+}
+
+// lookup returns the subcommand of c named name or one of its aliases.
+func (c *command) lookup(name string) *command {
+	for _, s := range c.sub {
+		if s.name == name {
+			return s
+		}
+		for _, a := range s.aliases {
+			if a == name {
+				return s
+			}
+		}
+	}
+	return nil
+}
+
+// subNames returns the names of c's visible subcommands.
+func (c *command) subNames() []string {
+	var names []string
+	for _, s := range c.sub {
+		if !s.hidden {
+			names = append(names, s.name)
+		}
+	}
+	return names
+}
+
+// flags returns c's flags, or nil when it only groups subcommands.
+func (c *command) flags() *flagSet {
+	if c.setup == nil {
+		return nil
+	}
+	fs := newFlagSet(c.name)
+	c.setup(fs)
+	return fs
+}
+
+// synopsis returns the usage line of c, whose full name is name.
+func (c *command) synopsis(name string) string {
+	s := name
+	if c.setup == nil {
+		return s + " <command>"
+	}
+	if fs := c.flags(); fs != nil && hasFlags(fs.FlagSet) {
+		s += " [flags]"
+	}
+	if c.usage != "" {
+		s += " " + c.usage
+	}
+	return s
+}
+
+// arg returns the kind of the i'th positional argument of c.
+func (c *command) arg(i int) argKind {
+	if i < len(c.args) {
+		return c.args[i]
+	}
+	if c.repeat && len(c.args) > 0 {
+		return c.args[len(c.args)-1]
+	}
+	return argNone
+}
+
+var shells = []string{"bash", "zsh", "fish"}
+
+// rootCommand is the command tree of qn. Running qn without a command
+// creates a note, so the root takes the create flags.
+var rootCommand *command
+
+func init() {
+	rootCommand = newCommandTree()
+}
+
+func newCommandTree() *command {
+	return &command{
+		name:    "qn",
+		summary: "Create a new note interactively",
+		setup:   createSetup,
+		sub: []*command{
+			{
+				name:    "new",
+				summary: "Create a new note interactively",
+				help: `Asks for a title, folder, tags, body and, for Resources, URLs. With
+--edit-first, writes the note in $EDITOR from the template instead and
+files it using the title, tags and folder in its frontmatter.`,
+				setup: createSetup,
+			},
+			{
+				name:    "list",
+				summary: "List the most recently updated notes",
+				help: `Days for --since and --until are YYYY-MM-DD, today, yesterday, or a
+number of days or weeks ago such as 7d or 2w.`,
+				setup: listSetup,
+			},
+			{
+				name:    "find",
+				usage:   "<topic>",
+				summary: "Search notes by topic",
+				help: `Searches titles, tags and aliases first, then bodies. With --open,
+opens a match in the editor at the first line mentioning the topic.`,
+				minArgs: 1, maxArgs: -1,
+				args: []argKind{argText}, repeat: true,
+				setup: findSetup,
+			},
+			{
+				name:    "open",
+				aliases: []string{"edit"},
+				usage:   "<note>",
+				summary: "Open a note in $VISUAL or $EDITOR",
+				help: `The note is found by path, filename, slug, title or alias, or else by
+search, asking which to open when several match.`,
+				minArgs: 1, maxArgs: -1,
+				args: []argKind{argNote},
+				setup: func(fs *flagSet) runFunc {
+					return func(e *env, args []string) error {
+						p := internal.NewPrompter(e.stdin, e.stderr)
+						return internal.Open(p, e.baseDir, strings.Join(args, " "))
+					}
+				},
+			},
+			{
+				name:    "pick",
+				summary: "Choose a note in an interactive picker",
+				help:    `Opens the chosen note in $EDITOR, or prints its path with --print.`,
+				setup: func(fs *flagSet) runFunc {
+					printPath := fs.Bool("print", false, "print the picked note's path instead of opening it")
+					return func(e *env, args []string) error {
+						return internal.Pick(e.stdout, e.baseDir, internal.PickOptions{Print: *printPath})
+					}
+				},
+			},
+			{
+				name:    "doctor",
+				summary: "Report notes that cannot be read or parsed",
+				help: `Reports unreadable files, malformed or unclosed frontmatter, invalid
+dates, empty titles and duplicate titles.`,
+				setup: func(fs *flagSet) runFunc {
+					return func(e *env, args []string) error {
+						return internal.Doctor(e.stdout, e.baseDir)
+					}
+				},
+			},
+			{
+				name:    "lint",
+				summary: "Check frontmatter against the vault schema",
+				help:    `The schema is read from .qn/schema.toml in the vault.`,
+				setup: func(fs *flagSet) runFunc {
+					fix := fs.Bool("fix", false, "add missing fields derived from the filename, modification time and schema")
+					return func(e *env, args []string) error {
+						return internal.Lint(e.stdout, e.baseDir, internal.LintOptions{Fix: *fix})
+					}
+				},
+			},
+			{
+				name:    "touch",
+				usage:   "<note>",
+				summary: "Set a note's updated field to now",
+				minArgs: 1, maxArgs: 1,
+				args: []argKind{argNote},
+				setup: func(fs *flagSet) runFunc {
+					return func(e *env, args []string) error {
+						return internal.Touch(e.stdout, e.baseDir, args[0])
+					}
+				},
+			},
+			{
+				name:    "export",
+				summary: "Export the vault as HTML or JSON",
+				sub: []*command{
+					{
+						name:    "html",
+						summary: "Export notes to a static HTML site",
+						setup:   exportHTMLSetup,
+					},
+					{
+						name:    "json",
+						summary: "Export every note, with frontmatter, body and links, as JSON",
+						setup:   exportJSONSetup,
+					},
+				},
+			},
+			{
+				name:    "import",
+				usage:   "<dir>",
+				summary: "Import notes from other tools and files",
+				help: `qn import <dir> imports a directory of markdown exported from another
+tool, named by --from.`,
+				minArgs: 1, maxArgs: 1,
+				args:  []argKind{argDir},
+				setup: importSetup("dir"),
+				sub: []*command{
+					{
+						name:    "json",
+						usage:   "<file|->",
+						summary: "Recreate notes from a JSON export without overwriting",
+						minArgs: 1, maxArgs: 1,
+						args:  []argKind{argFile},
+						setup: importSetup("json"),
+					},
+					{
+						name:    "bookmarks",
+						usage:   "<file.html>",
+						summary: "Create Resources notes from a browser bookmarks export",
+						minArgs: 1, maxArgs: 1,
+						args:  []argKind{argFile},
+						setup: importSetup("bookmarks"),
+					},
+					{
+						name:    "opml",
+						usage:   "<file.opml>",
+						summary: "Create Resources notes from an OPML feed list",
+						minArgs: 1, maxArgs: 1,
+						args:  []argKind{argFile},
+						setup: importSetup("opml"),
+					},
+				},
+			},
+			{
+				name:    "serve",
+				summary: "Serve a web UI and JSON API",
+				setup:   serveSetup,
+			},
+			{
+				name:    "watch",
+				summary: "Report notes as they are created, modified, deleted or renamed",
+				help: `Each --exec command is run through the shell for every change, with
+QN_EVENT, QN_PATH, QN_OLD_PATH and QN_TITLE set.`,
+				setup: watchSetup,
+			},
+			{
+				name:    "lsp",
+				summary: "Run a language server on stdin and stdout",
+				help: `Provides wikilink completion, go-to-definition, hovers, backlinks and
+broken link warnings.`,
+				setup: func(fs *flagSet) runFunc {
+					return func(e *env, args []string) error {
+						return internal.ServeLSP(e.stdin, e.stdout, e.baseDir)
+					}
+				},
+			},
+			{
+				name:    "check",
+				summary: "Check the vault",
+				sub: []*command{
+					{
+						name:    "urls",
+						summary: "List URLs referenced by more than one note",
+						setup: func(fs *flagSet) runFunc {
+							return func(e *env, args []string) error {
+								return internal.CheckURLs(e.stdout, e.baseDir)
+							}
+						},
+					},
+				},
+			},
+			{
+				name:    "history",
+				usage:   "<note>",
+				summary: "Show the git log for a note",
+				minArgs: 1, maxArgs: 1,
+				args: []argKind{argNote},
+				setup: func(fs *flagSet) runFunc {
+					return func(e *env, args []string) error {
+						return internal.History(e.stdout, e.baseDir, args[0])
+					}
+				},
+			},
+			{
+				name:    "diff",
+				usage:   "<note> [rev]",
+				summary: "Show changes to a note since rev (default HEAD)",
+				minArgs: 1, maxArgs: 2,
+				args: []argKind{argNote, argText},
+				setup: func(fs *flagSet) runFunc {
+					return func(e *env, args []string) error {
+						rev := ""
+						if len(args) == 2 {
+							rev = args[1]
+						}
+						return internal.Diff(e.stdout, e.baseDir, args[0], rev)
+					}
+				},
+			},
+			{
+				name:    "config",
+				summary: "Show or change qn's settings",
+				help:    configHelp(),
+				noVault: true,
+				sub: []*command{
+					{
+						name:    "list",
+						summary: "List every setting with its value and source",
+						noVault: true,
+						setup: func(fs *flagSet) runFunc {
+							return func(e *env, args []string) error {
+								cfg, err := internal.LoadConfig()
+								if err != nil {
+									return err
+								}
+								for _, k := range internal.ConfigKeys {
+									source := cfg.Source(k.Name)
+									if source == "" {
+										source = "unset"
+									}
+									_, _ = fmt.Fprintf(e.stdout, "%s = %q  (%s)\n", k.Name, cfg.Get(k.Name), source)
+								}
+								return nil
+							}
+						},
+					},
+					{
+						name:    "get",
+						usage:   "<key>",
+						summary: "Print the value of a setting",
+						minArgs: 1, maxArgs: 1,
+						args: []argKind{argChoice}, choices: configKeyNames(),
+						noVault: true,
+						setup: func(fs *flagSet) runFunc {
+							return func(e *env, args []string) error {
+								if _, err := internal.LookupConfigKey(args[0]); err != nil {
+									return usageErrorf("qn config get", "%v", err)
+								}
+								cfg, err := internal.LoadConfig()
+								if err != nil {
+									return err
+								}
+								_, _ = fmt.Fprintln(e.stdout, cfg.Get(args[0]))
+								return nil
+							}
+						},
+					},
+					{
+						name:    "set",
+						usage:   "<key> <value>",
+						summary: "Change a setting in the user or vault config",
+						minArgs: 2, maxArgs: 2,
+						args: []argKind{argChoice, argText}, choices: configKeyNames(),
+						noVault: true,
+						setup: func(fs *flagSet) runFunc {
+							vault := fs.Bool("vault", false, "change the vault's config instead of the user config")
+							return func(e *env, args []string) error {
+								if _, err := internal.LookupConfigKey(args[0]); err != nil {
+									return usageErrorf("qn config set", "%v", err)
+								}
+								path, err := configPath(*vault)
+								if err != nil {
+									return err
+								}
+								if err := internal.SetConfigValue(path, args[0], args[1], *vault); err != nil {
+									return err
+								}
+								_, _ = fmt.Fprintf(e.stdout, "Set %s in %s\n", args[0], path)
+								return nil
+							}
+						},
+					},
+					{
+						name:    "path",
+						summary: "Print the path of the user or vault config",
+						noVault: true,
+						setup: func(fs *flagSet) runFunc {
+							vault := fs.Bool("vault", false, "print the vault's config path instead")
+							return func(e *env, args []string) error {
+								path, err := configPath(*vault)
+								if err != nil {
+									return err
+								}
+								_, _ = fmt.Fprintln(e.stdout, path)
+								return nil
+							}
+						},
+					},
+				},
+			},
+			{
+				name:    "completion",
+				usage:   strings.Join(shells, "|"),
+				summary: "Print a shell completion script",
+				help: `Completes commands, flags, note slugs, tags and folders. For example:
+
+  eval "$(qn completion bash)"`,
+				minArgs: 1, maxArgs: 1,
+				args: []argKind{argChoice}, choices: shells,
+				noVault: true,
+				setup: func(fs *flagSet) runFunc {
+					return func(e *env, args []string) error {
+						if completionScripts[args[0]] == "" {
+							return usageErrorf("qn completion", "unknown shell %q", args[0])
+						}
+						return runCompletion(e.stdout, args)
+					}
+				},
+			},
+			{
+				name:    completeCommand,
+				summary: "Print completion candidates",
+				maxArgs: -1,
+				noVault: true, raw: true, hidden: true,
+				setup: func(fs *flagSet) runFunc {
+					return func(e *env, args []string) error {
+						runComplete(e.stdout, args, vaultNotes)
+						return nil
+					}
+				},
+			},
+			{
+				name:    "help",
+				usage:   "[command]",
+				summary: "Show help for qn or a command",
+				maxArgs: -1,
+				noVault: true, raw: true,
+				setup: func(fs *flagSet) runFunc {
+					return func(e *env, args []string) error {
+						cmd, name, rest := resolve(rootCommand, args)
+						if len(rest) > 0 {
+							return usageErrorf("qn help", "unknown command %q", strings.Join(args, " "))
+						}
+						printHelp(e.stdout, cmd, name, cmd.flags())
+						return nil
+					}
+				},
+			},
+		},
+	}
+}
+
+// configHelp returns the help of qn config, which lists every config key
+// with its environment variable.
+func configHelp() string {
+	var b strings.Builder
+	b.WriteString(`Settings are read from the user config, $XDG_CONFIG_HOME/qn/config.toml
+(by default ~/.config/qn/config.toml), then the vault's .qn/config.toml,
+as key = "value" lines. Each key's environment variable overrides both
+files, and command-line flags override everything. 'qn config path'
+prints where the files are.
+
+Keys:`)
+	for _, k := range internal.ConfigKeys {
+		fmt.Fprintf(&b, "\n  %-15s %s ($%s)", k.Name, k.Usage, k.Env)
+	}
+	return b.String()
+}
+
+// configKeyNames returns the names of the config keys, for completion.
+func configKeyNames() []string {
+	names := make([]string, len(internal.ConfigKeys))
+	for i, k := range internal.ConfigKeys {
+		names[i] = k.Name
+	}
+	return names
+}
+
+// configPath returns the path of the user config, or of the vault's with
+// vault set. The files themselves need not exist.
+func configPath(vault bool) (string, error) {
+	if !vault {
+		return internal.UserConfigPath()
+	}
+	cfg, err := internal.LoadConfig()
+	if err != nil {
+		return "", err
+	}
+	if cfg.VaultPath == "" {
+		return "", fmt.Errorf("no vault configured; set MDNOTES_DIR or notes_dir in %s", cfg.UserPath)
+	}
+	return cfg.VaultPath, nil
+}
+
+// createSetup defines the flags of qn and qn new.
+func createSetup(fs *flagSet) runFunc {
+	onConflict := fs.String("on-conflict", os.Getenv("MDNOTES_ON_CONFLICT"), "what to do when the filename is taken: ask, suffix, open or abort")
+	fs.complete("on-conflict", argChoice, "ask", "suffix", "open", "abort")
+	fetchDefault, _ := strconv.ParseBool(os.Getenv("MDNOTES_FETCH"))
+	fetch := fs.Bool("fetch", fetchDefault, "fetch titles and descriptions for Resources URLs")
+	editFirst := fs.Bool("edit-first", false, "write the note in the editor instead of answering prompts")
+	return func(e *env, args []string) error {
+		policy, err := internal.ParseCollisionPolicy(*onConflict)
+		if err != nil {
+			return err
+		}
+		opts := internal.CreateOptions{OnCollision: policy, EditFirst: *editFirst}
+		if *fetch {
+			opts.Fetcher = &internal.URLFetcher{}
+		}
+		p := internal.NewPrompter(e.stdin, e.stderr)
+		return internal.Create(p, e.baseDir, opts)
+	}
+}
+
+func listSetup(fs *flagSet) runFunc {
+	var opts internal.ListOptions
+	// execute has already checked $MDNOTES_LIST_LIMIT with the config.
+	limit := internal.DefaultListLimit
+	if n, err := strconv.Atoi(os.Getenv("MDNOTES_LIST_LIMIT")); err == nil {
+		limit = n
+	}
+	fs.BoolVar(&opts.All, "all", false, "list every matching note")
+	fs.IntVar(&opts.Limit, "n", limit, "how many notes to list")
+	fs.StringVar(&opts.Folder, "folder", "", "only list notes in this folder")
+	fs.complete("folder", argFolder)
+	var tags stringList
+	fs.Var(&tags, "tag", "only list notes with this tag (repeatable)")
+	fs.complete("tag", argTag)
+	fs.StringVar(&opts.Status, "status", "", "only list notes with this status")
+	since := fs.String("since", "", "only list notes dated on or after this day (YYYY-MM-DD, today, yesterday, 7d, 2w)")
+	until := fs.String("until", "", "only list notes dated on or before this day")
+	fs.StringVar(&opts.DateField, "date-field", "updated", "what --since and --until compare: "+strings.Join(internal.ListDateFields, ", "))
+	fs.complete("date-field", argChoice, internal.ListDateFields...)
+	fs.StringVar(&opts.Sort, "sort", "updated", "sort by "+strings.Join(internal.ListSorts, ", "))
+	fs.complete("sort", argChoice, internal.ListSorts...)
+	fs.BoolVar(&opts.Reverse, "reverse", false, "reverse the sort order")
+	fs.BoolVar(&opts.Strict, "strict", false, "fail instead of skipping notes that qn doctor would report")
+	fs.StringVar(&opts.DateFormat, "date-format", cmp.Or(os.Getenv("MDNOTES_DATE_FORMAT"), internal.DefaultDateFormat), "Go time layout of the dates shown")
+	formatFlag(fs, &opts.Format)
+	return func(e *env, args []string) error {
+		opts.Tags = tags
+		now := time.Now()
+		var err error
+		if *since != "" {
+			if opts.Since, err = internal.ParseListDate(*since, now); err != nil {
+				return fmt.Errorf("--since: %w", err)
+			}
+		}
+		if *until != "" {
+			if opts.Until, err = internal.ParseListDate(*until, now); err != nil {
+				return fmt.Errorf("--until: %w", err)
+			}
+		}
+		return internal.List(e.stdout, e.baseDir, opts)
+	}
+}
+
+func findSetup(fs *flagSet) runFunc {
+	var opts internal.FindOptions
+	fs.BoolVar(&opts.Strict, "strict", false, "fail instead of skipping notes that qn doctor would report")
+	open := fs.Bool("open", false, "open a matching note in the editor at the matching line")
+	formatFlag(fs, &opts.Format)
+	return func(e *env, args []string) error {
+		query := strings.Join(args, " ")
+		if *open {
+			p := internal.NewPrompter(e.stdin, e.stderr)
+			return internal.OpenFound(p, e.baseDir, query, opts)
+		}
+		return internal.Find(e.stdout, e.baseDir, query, opts)
+	}
+}
+
+// formatFlag defines the --format flag of the commands that can print
+// JSON, defaulting to $MDNOTES_OUTPUT.
+func formatFlag(fs *flagSet, format *string) {
+	outputs := []string{internal.OutputText, internal.OutputJSON}
+	fs.StringVar(format, "format", cmp.Or(os.Getenv("MDNOTES_OUTPUT"), internal.OutputText), "output format: "+strings.Join(outputs, ", "))
+	fs.complete("format", argChoice, outputs...)
+}
+
+func exportHTMLSetup(fs *flagSet) runFunc {
+	out := fs.String("out", "", "directory to write the site to (required)")
+	fs.complete("out", argDir)
+	var tags stringList
+	fs.Var(&tags, "tag", "only export notes with all of these tags (repeatable)")
+	fs.complete("tag", argTag)
+	return func(e *env, args []string) error {
+		if *out == "" {
+			return usageErrorf("qn export html", "--out is required")
+		}
+		return internal.ExportHTML(e.stdout, e.baseDir, internal.HTMLExportOptions{OutDir: *out, Tags: tags})
+	}
+}
+
+func exportJSONSetup(fs *flagSet) runFunc {
+	out := fs.String("out", "", "file to write to (default stdout)")
+	fs.complete("out", argFile)
+	return func(e *env, args []string) error {
+		if *out == "" {
+			return internal.ExportJSON(e.stdout, e.baseDir)
+		}
+		var buf bytes.Buffer
+		if err := internal.ExportJSON(&buf, e.baseDir); err != nil {
+			return err
+		}
+		return internal.WriteFileAtomic(*out, buf.Bytes(), internal.WriteOptions{})
+	}
+}
+
+// importSetup returns the setup of qn import for kind: "dir", "json",
+// "bookmarks" or "opml".
+func importSetup(kind string) func(fs *flagSet) runFunc {
+	return func(fs *flagSet) runFunc {
+		onConflict := fs.String("on-conflict", "abort", "what to do when a filename is taken: abort (report and skip the note) or suffix")
+		fs.complete("on-conflict", argChoice, "abort", "suffix")
+		folder := fs.String("folder", "", "file every note in this folder instead of choosing one by rules")
+		fs.complete("folder", argFolder)
+		from := new(string)
+		if kind == "dir" {
+			from = fs.String("from", "", "tool the directory was exported from: obsidian, logseq or plain")
+			fs.complete("from", argChoice, "obsidian", "logseq", "plain")
+		}
+		byFolder := new(bool)
+		if kind == "bookmarks" || kind == "opml" {
+			byFolder = fs.Bool("by-folder", false, "create one note per folder instead of one per link")
+		}
+		return func(e *env, args []string) error {
+			policy, err := internal.ParseCollisionPolicy(*onConflict)
+			if err != nil {
+				return err
+			}
+			opts := internal.ImportOptions{OnCollision: policy, Folder: *folder, GroupByFolder: *byFolder}
+			if kind == "dir" {
+				source, err := internal.ParseImportSource(*from)
+				if err != nil {
+					return err
+				}
+				return internal.ImportDir(e.stdout, e.baseDir, args[0], source, opts)
+			}
+
+			r, closeFn, err := openInput(e, args[0])
+			if err != nil {
+				return err
+			}
+			defer closeFn()
+			switch kind {
+			case "bookmarks":
+				return internal.ImportBookmarks(e.stdout, e.baseDir, r, opts)
+			case "opml":
+				return internal.ImportOPML(e.stdout, e.baseDir, r, opts)
+			default:
+				return internal.ImportJSON(e.stdout, e.baseDir, r, opts)
+			}
+		}
+	}
+}
+
+func serveSetup(fs *flagSet) runFunc {
+	addr := fs.String("addr", "127.0.0.1:7777", "address to listen on")
+	token := fs.String("token", os.Getenv("MDNOTES_TOKEN"), "require this bearer token on every request")
+	return func(e *env, args []string) error {
+		host, _, err := net.SplitHostPort(*addr)
+		if err != nil {
+			return fmt.Errorf("invalid address %q: %w", *addr, err)
+		}
+		if *token == "" && !isLoopback(host) {
+			_, _ = fmt.Fprintf(e.stderr, "Warning: serving %s without a token; anyone who can reach it can read and change your notes\n", *addr)
+		}
+
+		handler := internal.NewServer(e.baseDir, *token, e.stderr)
+		handler.AllowHost(host)
+		srv := &http.Server{
+			Addr:              *addr,
+			Handler:           handler,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = srv.Shutdown(shutdownCtx)
+		}()
+
+		_, _ = fmt.Fprintf(e.stderr, "Serving %s on http://%s\n", e.baseDir, *addr)
+		if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	}
+}
+
+func watchSetup(fs *flagSet) runFunc {
+	interval := internal.DefaultWatchInterval
+	var envErr error
+	if v := os.Getenv("MDNOTES_WATCH_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err != nil {
+			envErr = fmt.Errorf("invalid MDNOTES_WATCH_INTERVAL: %w", err)
+		} else {
+			interval = d
+		}
+	}
+	fs.DurationVar(&interval, "interval", interval, "time between polls")
+	var hooks stringList
+	if hook := os.Getenv("MDNOTES_WATCH_EXEC"); hook != "" {
+		hooks = append(hooks, hook)
+	}
+	fs.Var(&hooks, "exec", "shell command to run for each change (repeatable)")
+	return func(e *env, args []string) error {
+		if envErr != nil {
+			return envErr
+		}
+		if interval <= 0 {
+			return usageErrorf("qn watch", "interval must be positive")
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return internal.Watch(ctx, e.stdout, e.baseDir, internal.WatchOptions{Interval: interval, Hooks: hooks})
+	}
+}
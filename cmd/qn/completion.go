@@ -0,0 +1,258 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"go-spass/quick-note/internal"
+)
+
+// completeCommand is the hidden command the completion scripts run to ask
+// qn for candidates.
+const completeCommand = "__complete"
+
+// Candidates that tell the completion script to complete file or directory
+// names itself.
+const (
+	completeFiles = ":file"
+	completeDirs  = ":dir"
+)
+
+// completionScripts are the scripts printed by qn completion. Each passes
+// the words after qn, ending with the one being completed, to
+// qn __complete and offers the candidates it prints one per line as
+// "value<TAB>description".
+var completionScripts = map[string]string{
+	"bash": `# bash completion for qn; add to ~/.bashrc:
+#   eval "$(qn completion bash)"
+_qn_complete() {
+	local cur="${COMP_WORDS[COMP_CWORD]}" IFS=$'\n' line
+	local -a lines
+	COMPREPLY=()
+	lines=($(qn __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+	case "${lines[0]}" in
+	:file) COMPREPLY=($(compgen -f -- "$cur")); return ;;
+	:dir) COMPREPLY=($(compgen -d -- "$cur")); return ;;
+	esac
+	for line in "${lines[@]}"; do
+		COMPREPLY+=("${line%%$'\t'*}")
+	done
+}
+complete -o filenames -F _qn_complete qn
+`,
+	"zsh": `#compdef qn
+# zsh completion for qn; add to ~/.zshrc:
+#   eval "$(qn completion zsh)"
+# or save as _qn in a directory on $fpath.
+_qn() {
+	local -a lines candidates
+	local line
+	lines=("${(@f)$(qn __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+	case "$lines[1]" in
+	:file) _files; return ;;
+	:dir) _files -/; return ;;
+	esac
+	for line in $lines; do
+		[[ -z $line ]] && continue
+		if [[ $line == *$'\t'* ]]; then
+			candidates+=("${${line%%$'\t'*}//:/\\:}:${line#*$'\t'}")
+		else
+			candidates+=("${line//:/\\:}")
+		fi
+	done
+	_describe qn candidates
+}
+if [[ $funcstack[1] == _qn ]]; then
+	_qn "$@"
+else
+	compdef _qn qn
+fi
+`,
+	"fish": `# fish completion for qn; add to ~/.config/fish/config.fish:
+#   qn completion fish | source
+# or save as ~/.config/fish/completions/qn.fish.
+function __qn_complete
+	set -l words (commandline -opc)[2..-1] (commandline -ct)
+	set -l lines (qn __complete $words 2>/dev/null)
+	switch "$lines[1]"
+		case :file
+			__fish_complete_path (commandline -ct)
+		case :dir
+			__fish_complete_directories (commandline -ct)
+		case '*'
+			printf '%s\n' $lines
+	end
+end
+complete -c qn -f -a '(__qn_complete)'
+`,
+}
+
+// runCompletion prints the completion script for the shell named in args.
+func runCompletion(w io.Writer, args []string) error {
+	if len(args) != 1 || completionScripts[args[0]] == "" {
+		return fmt.Errorf("usage: qn completion %s", strings.Join(shells, "|"))
+	}
+	_, err := io.WriteString(w, completionScripts[args[0]])
+	return err
+}
+
+// completionSource supplies the vault's notes for completion. It is nil
+// when no vault is configured.
+type completionSource func() ([]internal.Note, error)
+
+// runComplete prints the candidates for the last of words, the arguments
+// after qn on the command line being completed.
+func runComplete(w io.Writer, words []string, notes completionSource) {
+	for _, c := range complete(rootCommand, words, notes) {
+		_, _ = fmt.Fprintln(w, c)
+	}
+}
+
+// complete returns the candidates for the last of words, walking the
+// command tree from root through the words before it.
+func complete(root *command, words []string, notes completionSource) []string {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	cur := words[len(words)-1]
+	cmd := root
+	fs := cmd.flags()
+	positional := 0
+	pending := false
+	var pendingKind argKind
+	var pendingChoices []string
+	for _, word := range words[:len(words)-1] {
+		if pending {
+			pending = false
+			continue
+		}
+		if word == "--" {
+			continue
+		}
+		if strings.HasPrefix(word, "-") && len(word) > 1 {
+			if fs != nil && !strings.Contains(word, "=") {
+				kind, choices, ok := fs.valueKind(word)
+				pending, pendingKind, pendingChoices = ok && kind != argNone, kind, choices
+			}
+			continue
+		}
+		if positional == 0 {
+			if sub := cmd.lookup(word); sub != nil && !sub.hidden {
+				cmd = sub
+				fs = cmd.flags()
+				continue
+			}
+		}
+		positional++
+	}
+
+	if pending {
+		return completeValue(pendingKind, pendingChoices, cur, notes)
+	}
+	if strings.HasPrefix(cur, "-") {
+		var out []string
+		if fs != nil {
+			fs.VisitAll(func(f *flag.Flag) {
+				if name := "--" + f.Name; strings.HasPrefix(name, cur) {
+					out = append(out, name+"\t"+f.Usage)
+				}
+			})
+		}
+		return out
+	}
+	if positional == 0 && len(cmd.sub) > 0 {
+		var out []string
+		for _, s := range cmd.sub {
+			if !s.hidden && strings.HasPrefix(s.name, cur) {
+				out = append(out, s.name+"\t"+s.summary)
+			}
+		}
+		if len(out) > 0 || len(cmd.args) == 0 {
+			return out
+		}
+	}
+	return completeValue(cmd.arg(positional), cmd.choices, cur, notes)
+}
+
+// completeValue returns the candidates of kind starting with cur.
+func completeValue(kind argKind, choices []string, cur string, notes completionSource) []string {
+	switch kind {
+	case argFile:
+		return []string{completeFiles}
+	case argDir:
+		return []string{completeDirs}
+	case argChoice:
+		return withPrefix(choices, cur)
+	case argFolder:
+		return withPrefix(internal.NoteFolders, cur)
+	case argTag, argNote:
+		if notes == nil {
+			return nil
+		}
+		all, err := notes()
+		if err != nil {
+			return nil
+		}
+		if kind == argTag {
+			return withPrefix(noteTags(all), cur)
+		}
+		return noteCandidates(all, cur)
+	}
+	return nil
+}
+
+// withPrefix returns the values starting with prefix, ignoring case.
+func withPrefix(values []string, prefix string) []string {
+	var out []string
+	for _, v := range values {
+		if strings.HasPrefix(strings.ToLower(v), strings.ToLower(prefix)) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// noteTags returns every tag used in notes, sorted.
+func noteTags(notes []internal.Note) []string {
+	seen := make(map[string]bool)
+	var tags []string
+	for _, n := range notes {
+		for _, t := range n.Frontmatter.Tags {
+			if !seen[t] {
+				seen[t] = true
+				tags = append(tags, t)
+			}
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// noteCandidates returns the slugs of the notes whose slug or title starts
+// with cur, each described by its title. Slugs are offered rather than
+// titles since they need no quoting, and qn open accepts either.
+func noteCandidates(notes []internal.Note, cur string) []string {
+	prefix := strings.ToLower(cur)
+	seen := make(map[string]bool)
+	var out []string
+	for _, n := range notes {
+		slug := internal.NoteSlug(n.FilePath)
+		title := n.Frontmatter.Title
+		if seen[slug] {
+			continue
+		}
+		if !strings.HasPrefix(slug, prefix) && !strings.HasPrefix(strings.ToLower(title), prefix) {
+			continue
+		}
+		seen[slug] = true
+		if title == "" {
+			title = slug
+		}
+		out = append(out, slug+"\t"+title)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go-spass/quick-note/internal"
+)
+
+func testNotes() ([]internal.Note, error) {
+	return []internal.Note{
+		{Frontmatter: internal.Frontmatter{Title: "Running Log", Tags: []string{"health", "sport"}}, FilePath: "/v/Inbox/2026-01-05-running-log.md"},
+		{Frontmatter: internal.Frontmatter{Title: "Go Concurrency", Tags: []string{"golang"}}, FilePath: "/v/Resources/go-concurrency.md"},
+		{Frontmatter: internal.Frontmatter{Title: "Health"}, FilePath: "/v/Areas/health.md"},
+	}, nil
+}
+
+func TestComplete(t *testing.T) {
+	tests := []struct {
+		words []string
+		want  []string
+	}{
+		{[]string{"li"}, []string{"list\tList the most recently updated notes", "lint\tCheck frontmatter against the vault schema"}},
+		{[]string{"--f"}, []string{"--fetch\tfetch titles and descriptions for Resources URLs"}},
+		{[]string{"--on-conflict", ""}, []string{"ask", "suffix", "open", "abort"}},
+		{[]string{"list", "--tag", ""}, []string{"golang", "health", "sport"}},
+		{[]string{"list", "--folder", "r"}, []string{"Resources"}},
+		{[]string{"list", "--sort", "t"}, []string{"title"}},
+		{[]string{"list", "--reverse", "--st"}, []string{"--status\tonly list notes with this status", "--strict\tfail instead of skipping notes that qn doctor would report"}},
+		{[]string{"open", "ru"}, []string{"running-log\tRunning Log"}},
+		{[]string{"edit", "Go"}, []string{"go-concurrency\tGo Concurrency"}},
+		{[]string{"diff", "health", ""}, nil},
+		{[]string{"touch", "health", ""}, nil},
+		{[]string{"find", "go", ""}, nil},
+		{[]string{"export", ""}, []string{"html\tExport notes to a static HTML site", "json\tExport every note, with frontmatter, body and links, as JSON"}},
+		{[]string{"export", "html", "--out", ""}, []string{":dir"}},
+		{[]string{"import", "json", ""}, []string{":file"}},
+		{[]string{"import", "./obsidian"}, []string{":dir"}},
+		{[]string{"import", "vault", "--from", ""}, []string{"obsidian", "logseq", "plain"}},
+		{[]string{"completion", "z"}, []string{"zsh"}},
+		{[]string{"check", ""}, []string{"urls\tList URLs referenced by more than one note"}},
+	}
+	for _, tt := range tests {
+		got := complete(rootCommand, tt.words, testNotes)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("complete(%q) = %q, want %q", tt.words, got, tt.want)
+		}
+	}
+}
+
+func TestCompleteWithoutVault(t *testing.T) {
+	if got := complete(rootCommand, []string{"open", ""}, nil); got != nil {
+		t.Errorf("complete() without a vault = %q", got)
+	}
+	failing := func() ([]internal.Note, error) { return nil, errors.New("no vault") }
+	if got := complete(rootCommand, []string{"list", "--tag", ""}, failing); got != nil {
+		t.Errorf("complete() with a failing vault = %q", got)
+	}
+}
+
+func TestCompletionScripts(t *testing.T) {
+	for _, shell := range shells {
+		var out strings.Builder
+		if err := runCompletion(&out, []string{shell}); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out.String(), "qn __complete") {
+			t.Errorf("%s script does not call qn __complete:\n%s", shell, out.String())
+		}
+	}
+	if err := runCompletion(&strings.Builder{}, []string{"powershell"}); err == nil {
+		t.Error("runCompletion(powershell) succeeded")
+	}
+}
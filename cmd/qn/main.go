@@ -1,71 +1,344 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"net"
 	"os"
 	"strings"
 
 	"go-spass/quick-note/internal"
 )
 
+// Exit codes returned by run.
+const (
+	exitOK    = 0
+	exitError = 1
+	// exitUsage means the command line itself was wrong: an unknown
+	// command or flag, or the wrong number of arguments.
+	exitUsage = 2
+)
+
 func main() {
-	if err := run(os.Args[1:]); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// env is what a command runs with: its standard streams and the vault.
+type env struct {
+	stdin   io.Reader
+	stdout  io.Writer
+	stderr  io.Writer
+	baseDir string
+}
+
+// runFunc runs a command with its positional arguments, after its flags
+// have been parsed.
+type runFunc func(e *env, args []string) error
+
+// usageError is an error in how qn was invoked. It is reported with a
+// pointer to the command's help and exits with exitUsage.
+type usageError struct {
+	// name is the full command, such as "qn list".
+	name string
+	msg  string
+}
+
+func (e *usageError) Error() string { return e.msg }
+
+func usageErrorf(name, format string, args ...any) error {
+	return &usageError{name: name, msg: fmt.Sprintf(format, args...)}
+}
+
+// run runs qn with args, the command line without the program name, and
+// returns the process exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	e := &env{stdin: stdin, stdout: stdout, stderr: stderr}
+	cmd, name, rest := resolve(rootCommand, args)
+	err := execute(e, cmd, name, rest)
+	var uerr *usageError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &uerr):
+		_, _ = fmt.Fprintf(stderr, "Error: %v\nRun '%s --help' for usage.\n", err, uerr.name)
+		return exitUsage
+	default:
+		_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
+		return exitError
+	}
+}
+
+// resolve follows the leading words of args down the command tree from
+// root and returns the command they name, its full name and the remaining
+// arguments.
+func resolve(root *command, args []string) (*command, string, []string) {
+	cmd, name := root, root.name
+	for len(args) > 0 {
+		sub := cmd.lookup(args[0])
+		if sub == nil {
+			break
+		}
+		cmd, name, args = sub, name+" "+sub.name, args[1:]
 	}
+	return cmd, name, args
 }
 
-func run(args []string) error {
-	// Handle help before checking MDNOTES_DIR
-	if len(args) > 0 {
-		switch args[0] {
-		case "help", "--help", "-h":
-			printUsage()
+// execute parses the flags and arguments of cmd, whose full name is name,
+// and runs it. --help prints the command's help instead.
+func execute(e *env, cmd *command, name string, args []string) error {
+	if cmd.setup == nil {
+		if len(args) == 0 {
+			return usageErrorf(name, "missing command; want one of %s", strings.Join(cmd.subNames(), ", "))
+		}
+		if args[0] == "-h" || args[0] == "--help" {
+			printHelp(e.stdout, cmd, name, nil)
 			return nil
 		}
+		return usageErrorf(name, "unknown command %q", args[0])
 	}
 
-	baseDir, err := internal.NotesDir()
-	if err != nil {
-		return err
+	// Config files supply defaults through the environment, so load them
+	// before setup reads the environment for flag defaults. Commands that
+	// need no vault skip them, so qn config can report and fix them.
+	if !cmd.noVault {
+		if err := applyConfig(); err != nil {
+			return err
+		}
 	}
 
-	if len(args) == 0 {
-		p := internal.NewPrompter(os.Stdin, os.Stderr)
-		return internal.Create(p, baseDir)
+	fs := newFlagSet(name)
+	runner := cmd.setup(fs)
+	positional := args
+	if !cmd.raw {
+		var err error
+		positional, err = parseArgs(fs.FlagSet, args)
+		if errors.Is(err, flag.ErrHelp) {
+			printHelp(e.stdout, cmd, name, fs)
+			return nil
+		}
+		if err != nil {
+			return usageErrorf(name, "%v", err)
+		}
+	}
+	if cmd == rootCommand && len(positional) > 0 {
+		return usageErrorf(name, "unknown command %q", positional[0])
+	}
+	if len(positional) < cmd.minArgs || (cmd.maxArgs >= 0 && len(positional) > cmd.maxArgs) {
+		return usageErrorf(name, "usage: %s", cmd.synopsis(name))
 	}
 
-	switch args[0] {
-	case "list":
-		showAll := false
-		for _, arg := range args[1:] {
-			if arg == "--all" {
-				showAll = true
-			}
+	if !cmd.noVault {
+		baseDir, err := internal.NotesDir()
+		if err != nil {
+			return err
 		}
-		return internal.List(os.Stdout, baseDir, showAll)
-	case "find":
-		if len(args) < 2 {
-			return fmt.Errorf("usage: qn find <topic>")
+		e.baseDir = baseDir
+	}
+	return runner(e, positional)
+}
+
+// parseArgs parses the flags in args, which may come before, between or
+// after the positional arguments, and returns the positional arguments.
+// Everything after "--" is positional.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
 		}
-		query := strings.Join(args[1:], " ")
-		return internal.Find(os.Stdout, baseDir, query)
-	default:
-		return fmt.Errorf("unknown command: %s\nRun 'qn help' for usage", args[0])
+		rest := fs.Args()
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
 	}
 }
 
-func printUsage() {
-	fmt.Println(`qn - Quick Note CLI
+// flagSet is a flag.FlagSet that also records what the values of its
+// flags are, for completion. Errors and help are reported by execute
+// rather than the flag package.
+type flagSet struct {
+	*flag.FlagSet
+	kinds   map[string]argKind
+	choices map[string][]string
+}
+
+func newFlagSet(name string) *flagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Usage = func() {}
+	return &flagSet{FlagSet: fs, kinds: make(map[string]argKind), choices: make(map[string][]string)}
+}
+
+// complete records that the value of the flag name is of kind, one of
+// choices for argChoice. Other flags complete as free text, or not at all
+// for booleans.
+func (fs *flagSet) complete(name string, kind argKind, choices ...string) {
+	fs.kinds[name] = kind
+	fs.choices[name] = choices
+}
+
+// valueKind returns the kind of the value of the flag named by arg, which
+// may be spelled -name or --name, and whether there is such a flag.
+// Boolean flags take no value and have kind argNone.
+func (fs *flagSet) valueKind(arg string) (argKind, []string, bool) {
+	name := strings.TrimLeft(arg, "-")
+	f := fs.Lookup(name)
+	if f == nil {
+		return argNone, nil, false
+	}
+	if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+		return argNone, nil, true
+	}
+	if kind, ok := fs.kinds[name]; ok {
+		return kind, fs.choices[name], true
+	}
+	return argText, nil, true
+}
+
+// printHelp writes the help for cmd, whose full name is name. fs holds its
+// flags, if it has any.
+func printHelp(w io.Writer, cmd *command, name string, fs *flagSet) {
+	if cmd == rootCommand {
+		printUsage(w)
+	} else {
+		_, _ = fmt.Fprintf(w, "Usage: %s\n\n%s.\n", cmd.synopsis(name), cmd.summary)
+		if cmd.help != "" {
+			_, _ = fmt.Fprintf(w, "\n%s\n", cmd.help)
+		}
+		if len(cmd.aliases) > 0 {
+			_, _ = fmt.Fprintf(w, "\nAliases: %s\n", strings.Join(cmd.aliases, ", "))
+		}
+		if len(cmd.sub) > 0 {
+			_, _ = fmt.Fprintln(w, "\nCommands:")
+			for _, s := range cmd.sub {
+				_, _ = fmt.Fprintf(w, "  %-10s %s\n", s.name, s.summary)
+			}
+		}
+	}
+	if fs != nil && hasFlags(fs.FlagSet) {
+		_, _ = fmt.Fprintln(w, "\nFlags:")
+		fs.SetOutput(w)
+		fs.PrintDefaults()
+		fs.SetOutput(io.Discard)
+	}
+}
+
+func hasFlags(fs *flag.FlagSet) bool {
+	found := false
+	fs.VisitAll(func(*flag.Flag) { found = true })
+	return found
+}
+
+// printUsage writes the overview of qn's commands shown by qn help.
+func printUsage(w io.Writer) {
+	_, _ = fmt.Fprint(w, `qn - Quick Note CLI
 
 Usage:
-  qn              Create a new note interactively
-  qn list         List 10 most recent notes
-  qn list --all   List all notes
-  qn find <topic> Search notes by topic
-  qn help         Show this help message
+  qn [flags]             Create a new note interactively
+  qn <command> [args]
+
+Commands:
+`)
+	for _, c := range rootCommand.sub {
+		if !c.hidden {
+			_, _ = fmt.Fprintf(w, "  %-12s %s\n", c.name, c.summary)
+		}
+	}
+	_, _ = fmt.Fprint(w, `
+Run 'qn <command> --help' for a command's arguments and flags.
 
 Environment:
-  MDNOTES_DIR     Path to the notes directory (required)
-  EDITOR          Editor to open notes in (optional)`)
+  MDNOTES_DIR     Path to the notes directory (required unless set in the
+                  user config)
+  MDNOTES_ON_CONFLICT
+                  Default for --on-conflict (ask, suffix, open or abort);
+                  overrides on_conflict in the config
+  MDNOTES_FETCH   Default for --fetch
+  MDNOTES_TOKEN   Default for qn serve --token
+  MDNOTES_WATCH_INTERVAL
+                  Default for qn watch --interval
+  MDNOTES_WATCH_EXEC
+                  Command qn watch always runs for each change
+  MDNOTES_GIT     Commit every change qn makes when the vault is a git repo
+  MDNOTES_BACKUP  Keep a .bak copy when qn rewrites a note (optional)
+  VISUAL, EDITOR  Editor to open notes in (optional)
+  MDNOTES_DEFAULT_FOLDER
+                  Folder new notes are filed in (default Inbox)
+  MDNOTES_LIST_LIMIT
+                  Default for qn list -n
+  MDNOTES_DATE_FORMAT
+                  Default for qn list --date-format
+  MDNOTES_SLUG_POLICY
+                  Which folders get date-prefixed filenames: para (Inbox
+                  and Projects), always or never
+  MDNOTES_OUTPUT  Default for --format of qn list and qn find (text, json)
+
+Settings can also be kept in the user config, $XDG_CONFIG_HOME/qn/config.toml
+(by default ~/.config/qn/config.toml), and the vault's .qn/config.toml;
+see 'qn config --help'. Flags override
+the environment, which overrides the vault config, then the user config.
+
+Exit status is 0 on success, 1 when a command fails and 2 when it is
+used incorrectly.
+`)
+}
+
+// openInput opens path for reading, treating "-" as stdin.
+func openInput(e *env, path string) (io.Reader, func(), error) {
+	if path == "-" {
+		return e.stdin, func() {}, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, func() { _ = f.Close() }, nil
+}
+
+// stringList is a flag.Value that collects every occurrence of a flag.
+type stringList []string
+
+func (s *stringList) String() string { return strings.Join(*s, ",") }
+
+func (s *stringList) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+// applyConfig loads the config files and exports their settings to the
+// environment variables that are not already set.
+func applyConfig() error {
+	cfg, err := internal.LoadConfig()
+	if err != nil {
+		return err
+	}
+	return cfg.Apply()
+}
+
+// vaultNotes scans the notes in $MDNOTES_DIR for completion.
+func vaultNotes() ([]internal.Note, error) {
+	if err := applyConfig(); err != nil {
+		return nil, err
+	}
+	baseDir, err := internal.NotesDir()
+	if err != nil {
+		return nil, err
+	}
+	return internal.ScanNotes(baseDir)
+}
+
+// isLoopback reports whether host only accepts local connections.
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
 }
@@ -0,0 +1,418 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// qn runs the command line args with stdin and returns its output and exit
+// code.
+func qn(t *testing.T, stdin string, args ...string) (string, string, int) {
+	t.Helper()
+	var stdout, stderr strings.Builder
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return stdout.String(), stderr.String(), code
+}
+
+// setupVault creates an empty vault, points $MDNOTES_DIR at it and clears
+// the environment qn reads.
+func setupVault(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, folder := range []string{"Inbox", "Projects", "Areas", "Resources", "Archive"} {
+		if err := os.MkdirAll(filepath.Join(dir, folder), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	isolateEnv(t)
+	t.Setenv("MDNOTES_DIR", dir)
+	return dir
+}
+
+// isolateEnv clears the environment qn reads and points the user config
+// at an empty directory, so no vault is configured.
+func isolateEnv(t *testing.T) {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	for _, name := range []string{"MDNOTES_DIR", "VISUAL", "EDITOR", "MDNOTES_ON_CONFLICT", "MDNOTES_FETCH", "MDNOTES_GIT", "MDNOTES_BACKUP", "MDNOTES_TOKEN", "MDNOTES_WATCH_INTERVAL", "MDNOTES_WATCH_EXEC",
+		"MDNOTES_DEFAULT_FOLDER", "MDNOTES_LIST_LIMIT", "MDNOTES_DATE_FORMAT", "MDNOTES_SLUG_POLICY", "MDNOTES_OUTPUT"} {
+		t.Setenv(name, "")
+	}
+}
+
+// recordingEditor installs a shell script as $VISUAL that records its
+// arguments, and returns the file they are written to.
+func recordingEditor(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake editor is a shell script")
+	}
+	dir := t.TempDir()
+	out := filepath.Join(dir, "args")
+	script := filepath.Join(dir, "editor")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\nprintf '%s\\n' \"$@\" > \""+out+"\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("VISUAL", script)
+	return out
+}
+
+func TestRunHelp(t *testing.T) {
+	isolateEnv(t)
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"help"}, []string{"Usage:\n  qn [flags]", "  list         List the most recently updated notes\n", "Exit status is 0", "\nFlags:\n  -edit-first"}},
+		{[]string{"--help"}, []string{"Commands:", "MDNOTES_DIR"}},
+		{[]string{"-h"}, []string{"Commands:"}},
+		{[]string{"list", "--help"}, []string{"Usage: qn list [flags]\n\nList the most recently updated notes.\n", "  -tag value\n"}},
+		{[]string{"help", "list"}, []string{"Usage: qn list [flags]"}},
+		{[]string{"help", "import", "json"}, []string{"Usage: qn import json [flags] <file|->"}},
+		{[]string{"edit", "-h"}, []string{"Usage: qn open <note>", "Aliases: edit"}},
+		{[]string{"export", "--help"}, []string{"Usage: qn export <command>", "  html       Export notes to a static HTML site"}},
+		{[]string{"config", "--help"}, []string{"Keys:\n", "  on_conflict     what to do when a new note's filename is taken: ask, suffix, open or abort ($MDNOTES_ON_CONFLICT)\n"}},
+		{[]string{"touch", "--help"}, []string{"Usage: qn touch <note>\n\nSet a note's updated field to now.\n"}},
+	}
+	for _, tt := range tests {
+		stdout, stderr, code := qn(t, "", tt.args...)
+		if code != exitOK || stderr != "" {
+			t.Errorf("qn %v: exit %d, stderr %q", tt.args, code, stderr)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(stdout, want) {
+				t.Errorf("qn %v output missing %q:\n%s", tt.args, want, stdout)
+			}
+		}
+	}
+	if stdout, _, _ := qn(t, "", "help"); strings.Contains(stdout, completeCommand) {
+		t.Errorf("help lists the hidden %s command", completeCommand)
+	}
+}
+
+func TestRunUsageErrors(t *testing.T) {
+	setupVault(t)
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"bogus"}, "Error: unknown command \"bogus\"\nRun 'qn --help' for usage.\n"},
+		{[]string{"list", "--bogus"}, "Error: flag provided but not defined: -bogus\nRun 'qn list --help' for usage.\n"},
+		{[]string{"list", "extra"}, "Error: usage: qn list [flags]\nRun 'qn list --help' for usage.\n"},
+		{[]string{"list", "-n", "many"}, "Error: invalid value \"many\" for flag -n: parse error\nRun 'qn list --help' for usage.\n"},
+		{[]string{"find"}, "Error: usage: qn find [flags] <topic>\n"},
+		{[]string{"touch"}, "Error: usage: qn touch <note>\n"},
+		{[]string{"diff", "a", "b", "c"}, "Error: usage: qn diff <note> [rev]\n"},
+		{[]string{"pick", "extra"}, "Error: usage: qn pick [flags]\n"},
+		{[]string{"doctor", "--fix"}, "Error: flag provided but not defined: -fix\n"},
+		{[]string{"export"}, "Error: missing command; want one of html, json\nRun 'qn export --help' for usage.\n"},
+		{[]string{"export", "pdf"}, "Error: unknown command \"pdf\"\n"},
+		{[]string{"export", "html"}, "Error: --out is required\nRun 'qn export html --help' for usage.\n"},
+		{[]string{"import", "json", "a.json", "--by-folder"}, "Error: flag provided but not defined: -by-folder\n"},
+		{[]string{"check"}, "Error: missing command; want one of urls\n"},
+		{[]string{"completion", "tcsh"}, "Error: unknown shell \"tcsh\"\n"},
+		{[]string{"help", "bogus"}, "Error: unknown command \"bogus\"\nRun 'qn help --help' for usage.\n"},
+		{[]string{"watch", "--interval", "0s"}, "Error: interval must be positive\n"},
+	}
+	for _, tt := range tests {
+		stdout, stderr, code := qn(t, "", tt.args...)
+		if code != exitUsage || stdout != "" || !strings.HasPrefix(stderr, strings.SplitAfter(tt.want, "\n")[0]) || !strings.Contains(stderr, tt.want) {
+			t.Errorf("qn %v: exit %d, stdout %q, stderr %q; want exit 2 and %q", tt.args, code, stdout, stderr, tt.want)
+		}
+	}
+}
+
+func TestRunFailures(t *testing.T) {
+	isolateEnv(t)
+	if _, stderr, code := qn(t, "", "list"); code != exitError || !strings.Contains(stderr, "MDNOTES_DIR environment variable is not set") {
+		t.Errorf("list without a vault: exit %d, stderr %q", code, stderr)
+	}
+
+	setupVault(t)
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"touch", "missing"}, "Error: no note matches \"missing\""},
+		{[]string{"open", "missing"}, "Error: no note matches \"missing\""},
+		{[]string{"new", "--edit-first"}, "Error: no editor configured"},
+		{[]string{"--on-conflict", "sometimes"}, "Error: invalid collision policy"},
+		{[]string{"import", "dir", "--from", "notion"}, "Error: unknown import source"},
+		{[]string{"serve", "--addr", "nowhere"}, "Error: invalid address"},
+	}
+	for _, tt := range tests {
+		_, stderr, code := qn(t, "", tt.args...)
+		if code != exitError || !strings.HasPrefix(stderr, tt.want) {
+			t.Errorf("qn %v: exit %d, stderr %q; want exit 1 and %q", tt.args, code, stderr, tt.want)
+		}
+	}
+
+	t.Setenv("MDNOTES_WATCH_INTERVAL", "soon")
+	if _, stderr, code := qn(t, "", "watch"); code != exitError || !strings.Contains(stderr, "invalid MDNOTES_WATCH_INTERVAL") {
+		t.Errorf("watch with a bad interval: exit %d, stderr %q", code, stderr)
+	}
+	t.Setenv("MDNOTES_WATCH_INTERVAL", "")
+	for _, env := range []string{"MDNOTES_LIST_LIMIT", "MDNOTES_DEFAULT_FOLDER", "MDNOTES_SLUG_POLICY", "MDNOTES_OUTPUT", "MDNOTES_ON_CONFLICT"} {
+		t.Setenv(env, "bogus")
+		if _, stderr, code := qn(t, "", "list"); code != exitError || !strings.Contains(stderr, "invalid "+env+":") {
+			t.Errorf("list with a bad $%s: exit %d, stderr %q", env, code, stderr)
+		}
+		t.Setenv(env, "")
+	}
+}
+
+func TestRunConfig(t *testing.T) {
+	isolateEnv(t)
+	vault := t.TempDir()
+
+	out, stderr, code := qn(t, "", "config", "set", "notes_dir", vault)
+	if code != exitOK {
+		t.Fatalf("config set notes_dir: exit %d, stderr %q", code, stderr)
+	}
+	userPath := strings.TrimPrefix(strings.TrimSpace(out), "Set notes_dir in ")
+	if got, _, _ := qn(t, "", "config", "path"); strings.TrimSpace(got) != userPath {
+		t.Errorf("config path = %q, want %q", got, userPath)
+	}
+	if _, stderr, code := qn(t, "", "config", "set", "--vault", "default_folder", "areas"); code != exitOK {
+		t.Fatalf("config set --vault: exit %d, stderr %q", code, stderr)
+	}
+	if _, err := os.Stat(filepath.Join(vault, ".qn", "config.toml")); err != nil {
+		t.Errorf("vault config not written: %v", err)
+	}
+	if _, stderr, code := qn(t, "", "config", "set", "list_limit", "1"); code != exitOK {
+		t.Fatalf("config set list_limit: exit %d, stderr %q", code, stderr)
+	}
+
+	if out, _, _ := qn(t, "", "config", "get", "default_folder"); out != "Areas\n" {
+		t.Errorf("config get default_folder = %q", out)
+	}
+	t.Setenv("MDNOTES_DEFAULT_FOLDER", "Resources")
+	out, _, _ = qn(t, "", "config", "list")
+	for _, want := range []string{
+		`default_folder = "Resources"  ($MDNOTES_DEFAULT_FOLDER)`,
+		`list_limit = "1"  (user config)`,
+		`slug_policy = "para"  (default)`,
+		`editor = ""  (unset)`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("config list missing %q:\n%s", want, out)
+		}
+	}
+	t.Setenv("MDNOTES_DEFAULT_FOLDER", "")
+
+	// The vault comes from the user config, and its settings apply.
+	if err := os.MkdirAll(filepath.Join(vault, "Areas"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"one", "two"} {
+		if err := os.WriteFile(filepath.Join(vault, "Areas", name+".md"), []byte("---\ntitle: "+name+"\n---\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if out, stderr, code := qn(t, "", "list"); code != exitOK || !strings.Contains(out, "Showing 1 of 2") {
+		t.Errorf("list with list_limit = 1: exit %d, stdout %q, stderr %q", code, out, stderr)
+	}
+	if out, _, _ := qn(t, "", "list", "-n", "5", "--format", "json"); !strings.Contains(out, `"path": "Areas/one.md"`) || !strings.Contains(out, `"path": "Areas/two.md"`) {
+		t.Errorf("flags should override the config, got %q", out)
+	}
+
+	for _, tt := range []struct {
+		args []string
+		code int
+		want string
+	}{
+		{[]string{"config", "get", "colour"}, exitUsage, `unknown config key "colour"`},
+		{[]string{"config", "set", "list_limit", "none"}, exitError, "want a positive number"},
+		{[]string{"config", "set", "--vault", "notes_dir", "/tmp"}, exitError, "only be set in the user config"},
+	} {
+		_, stderr, code := qn(t, "", tt.args...)
+		if code != tt.code || !strings.Contains(stderr, tt.want) {
+			t.Errorf("qn %v: exit %d, stderr %q; want exit %d and %q", tt.args, code, stderr, tt.code, tt.want)
+		}
+	}
+
+	// A broken config stops commands that need the vault, but not qn config.
+	if err := os.WriteFile(userPath, []byte("list_limit = 0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, stderr, code := qn(t, "", "list"); code != exitError || !strings.Contains(stderr, "list_limit") {
+		t.Errorf("list with a broken config: exit %d, stderr %q", code, stderr)
+	}
+	if _, stderr, code := qn(t, "", "config", "set", "list_limit", "3"); code != exitOK {
+		t.Errorf("config set with a broken config: exit %d, stderr %q", code, stderr)
+	}
+}
+
+func TestRunNotes(t *testing.T) {
+	dir := setupVault(t)
+	today := time.Now().Format("2006-01-02")
+	running := filepath.Join(dir, "Inbox", today+"-running-log.md")
+
+	// Create notes with qn and qn new.
+	_, stderr, code := qn(t, "Running Log\n1\nhealth, sport\nFive kilometres today.\n")
+	if code != exitOK || !strings.Contains(stderr, "Created: "+running) {
+		t.Fatalf("qn: exit %d, stderr %q", code, stderr)
+	}
+	_, stderr, code = qn(t, "Go Concurrency\n4\ngolang\nChannels.\nhttps://go.dev/blog\n", "new")
+	if code != exitOK || !strings.Contains(stderr, "Created: "+filepath.Join(dir, "Resources", "go-concurrency.md")) {
+		t.Fatalf("qn new: exit %d, stderr %q", code, stderr)
+	}
+
+	expect := func(args []string, want ...string) string {
+		t.Helper()
+		stdout, stderr, code := qn(t, "", args...)
+		if code != exitOK {
+			t.Fatalf("qn %v: exit %d, stderr %q", args, code, stderr)
+		}
+		for _, w := range want {
+			if !strings.Contains(stdout, w) {
+				t.Errorf("qn %v output missing %q:\n%s", args, w, stdout)
+			}
+		}
+		return stdout
+	}
+
+	expect([]string{"list"}, "Running Log  (Inbox)", "Go Concurrency  (Resources)")
+	if out := expect([]string{"list", "--tag", "golang", "--sort", "title"}, "Go Concurrency"); strings.Contains(out, "Running Log") {
+		t.Errorf("list --tag golang =\n%s", out)
+	}
+	// Flags may follow the topic.
+	expect([]string{"find", "kilometres", "--strict"}, "Running Log")
+	expect([]string{"doctor"}, "No problems found in 2 notes.")
+	expect([]string{"lint"})
+	expect([]string{"touch", "go-concurrency"}, "Touched: Resources/go-concurrency.md")
+	expect([]string{"check", "urls"})
+	expect([]string{"lsp"})
+	expect([]string{"completion", "zsh"}, "#compdef qn")
+	expect([]string{completeCommand, "open", "go"}, "go-concurrency\tGo Concurrency\n")
+	expect([]string{completeCommand, "list", "--tag", ""}, "golang\nhealth\nsport\n")
+
+	_, stderr, code = qn(t, "Running Log\n1\n\n\n", "new", "--on-conflict", "suffix")
+	if code != exitOK || !strings.Contains(stderr, "Created: "+filepath.Join(dir, "Inbox", today+"-running-log-2.md")) {
+		t.Fatalf("qn new --on-conflict suffix: exit %d, stderr %q", code, stderr)
+	}
+	if stdout, _, code := qn(t, "", "doctor"); code != exitError || !strings.Contains(stdout, "duplicate title") {
+		t.Errorf("doctor with duplicate titles: exit %d, stdout %q", code, stdout)
+	}
+
+	args := recordingEditor(t)
+	for _, cmd := range [][]string{{"open", "go-concurrency"}, {"edit", "Go", "Concurrency"}} {
+		expect(cmd)
+		if data, _ := os.ReadFile(args); string(data) != filepath.Join(dir, "Resources", "go-concurrency.md")+"\n" {
+			t.Errorf("qn %v opened %q", cmd, data)
+		}
+	}
+	expect([]string{"find", "--open", "channels"})
+	if data, _ := os.ReadFile(args); !strings.HasSuffix(string(data), "go-concurrency.md\n") {
+		t.Errorf("qn find --open opened %q", data)
+	}
+}
+
+func TestRunExportImport(t *testing.T) {
+	dir := setupVault(t)
+	if err := os.WriteFile(filepath.Join(dir, "Areas", "health.md"), []byte("---\ntitle: Health\ntags: [sleep]\n---\n\nSee [[Health]].\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	work := t.TempDir()
+
+	jsonPath := filepath.Join(work, "notes.json")
+	site := filepath.Join(work, "site")
+	for _, args := range [][]string{
+		{"export", "json", "--out", jsonPath},
+		{"export", "html", "--out", site, "--tag", "sleep"},
+	} {
+		if _, stderr, code := qn(t, "", args...); code != exitOK {
+			t.Fatalf("qn %v: exit %d, stderr %q", args, code, stderr)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(site, "index.html")); err != nil {
+		t.Errorf("export html wrote no index: %v", err)
+	}
+	stdout, _, _ := qn(t, "", "export", "json")
+	if !strings.Contains(stdout, `"title": "Health"`) {
+		t.Errorf("export json =\n%s", stdout)
+	}
+
+	// Import everything into a second vault.
+	other := setupVault(t)
+	plain := filepath.Join(work, "plain")
+	if err := os.MkdirAll(plain, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(plain, "ideas.md"), []byte("# Ideas\n\nSome.\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	bookmarks := filepath.Join(work, "bookmarks.html")
+	if err := os.WriteFile(bookmarks, []byte(`<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<DL><p>
+    <DT><A HREF="https://go.dev/doc/">Go Docs</A>
+</DL><p>
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	opml := filepath.Join(work, "feeds.opml")
+	if err := os.WriteFile(opml, []byte(`<opml version="2.0"><body><outline type="rss" text="Go Blog" xmlUrl="https://go.dev/blog/feed.atom"/></body></opml>`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(jsonPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		stdin string
+		args  []string
+		note  string
+	}{
+		{string(data), []string{"import", "json", "-"}, "Areas/health.md"},
+		{"", []string{"import", "--from", "plain", plain, "--folder", "Resources"}, "Resources/ideas.md"},
+		{"", []string{"import", "bookmarks", bookmarks}, "Resources/go-docs.md"},
+		{"", []string{"import", "opml", opml, "--by-folder"}, "Resources"},
+	} {
+		if _, stderr, code := qn(t, tt.stdin, tt.args...); code != exitOK {
+			t.Errorf("qn %v: exit %d, stderr %q", tt.args, code, stderr)
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(other, tt.note)); err != nil {
+			t.Errorf("qn %v: %v", tt.args, err)
+		}
+	}
+}
+
+func TestRunGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := setupVault(t)
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_AUTHOR_NAME", "Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+	t.Setenv("MDNOTES_GIT", "1")
+	if out, err := exec.Command("git", "-C", dir, "init", "-q").CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+
+	if _, stderr, code := qn(t, "Health\n3\n\nSleep.\n"); code != exitOK {
+		t.Fatalf("qn: exit %d, stderr %q", code, stderr)
+	}
+	stdout, stderr, code := qn(t, "", "history", "health")
+	if code != exitOK || !strings.Contains(stdout, `qn: create "Health" in Areas`) {
+		t.Errorf("history: exit %d, stdout %q, stderr %q", code, stdout, stderr)
+	}
+	path := filepath.Join(dir, "Areas", "health.md")
+	data, _ := os.ReadFile(path)
+	if err := os.WriteFile(path, append(data, "More sleep.\n"...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr, code = qn(t, "", "diff", "health")
+	if code != exitOK || !strings.Contains(stdout, "+More sleep.") {
+		t.Errorf("diff: exit %d, stdout %q, stderr %q", code, stdout, stderr)
+	}
+}
@@ -0,0 +1,38 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// AppendToNote adds text to the end of the note matching query, on a line
+// of its own, bumps its updated field and returns the note's path.
+func AppendToNote(baseDir, query, text string) (string, error) {
+	text = strings.TrimRight(text, "\n")
+	if strings.TrimSpace(text) == "" {
+		return "", errors.New("nothing to append")
+	}
+
+	note, err := LookupNote(baseDir, query)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(note.FilePath)
+	if err != nil {
+		return "", fmt.Errorf("reading note: %w", err)
+	}
+
+	// Notes without frontmatter are appended to as they are.
+	content, _ := setFrontmatterField(string(data), "updated", timestamp(time.Now()))
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	content += text + "\n"
+	if err := RewriteFile(note.FilePath, []byte(content)); err != nil {
+		return "", fmt.Errorf("writing note: %w", err)
+	}
+	return note.FilePath, nil
+}
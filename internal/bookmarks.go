@@ -0,0 +1,330 @@
+package internal
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// bookmark is a link read from a browser bookmarks file or an OPML outline.
+type bookmark struct {
+	Title       string
+	URL         string
+	FeedURL     string
+	Description string
+	// Folder is the path of bookmark folders the link was filed under.
+	Folder []string
+	// Tags are tags the browser stored on the bookmark itself.
+	Tags  []string
+	Added time.Time
+}
+
+var (
+	htmlTagPattern  = regexp.MustCompile(`<(/?)([A-Za-z][A-Za-z0-9]*)([^>]*)>`)
+	htmlAttrPattern = regexp.MustCompile(`([A-Za-z_:][-A-Za-z0-9_:.]*)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+)
+
+// ImportBookmarks creates Resources notes from a browser bookmarks export
+// in the Netscape HTML format. Each bookmark's folder path becomes its
+// tags. Bookmarks whose URL is already referenced anywhere in the vault are
+// skipped.
+func ImportBookmarks(w io.Writer, baseDir string, r io.Reader, opts ImportOptions) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("reading bookmarks: %w", err)
+	}
+	return saveBookmarks(w, baseDir, "bookmarks", parseNetscapeBookmarks(string(data)), opts)
+}
+
+// ImportOPML creates Resources notes from the feeds in an OPML file, using
+// the outline folders as tags. Feeds already referenced in the vault are
+// skipped.
+func ImportOPML(w io.Writer, baseDir string, r io.Reader, opts ImportOptions) error {
+	marks, err := parseOPML(r)
+	if err != nil {
+		return err
+	}
+	return saveBookmarks(w, baseDir, "OPML", marks, opts)
+}
+
+// parseNetscapeBookmarks reads the <DL>/<DT> structure browsers export.
+// The format is not well-formed HTML, so it is scanned tag by tag.
+func parseNetscapeBookmarks(doc string) []bookmark {
+	var (
+		marks     []bookmark
+		stack     []string
+		pending   string
+		current   *bookmark
+		textStart = -1
+		textFor   string
+		lastMark  = -1
+	)
+
+	tags := htmlTagPattern.FindAllStringSubmatchIndex(doc, -1)
+	for i, m := range tags {
+		closing := doc[m[2]:m[3]] == "/"
+		name := strings.ToUpper(doc[m[4]:m[5]])
+		attrs := parseHTMLAttrs(doc[m[6]:m[7]])
+
+		text := ""
+		if textStart >= 0 {
+			text = strings.TrimSpace(html.UnescapeString(doc[textStart:m[0]]))
+		}
+
+		switch {
+		case !closing && name == "H3":
+			textStart, textFor = m[1], "H3"
+			lastMark = -1
+		case closing && name == "H3" && textFor == "H3":
+			pending = text
+			textStart, textFor = -1, ""
+		case !closing && name == "DL":
+			stack = append(stack, pending)
+			pending = ""
+		case closing && name == "DL":
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case !closing && name == "A":
+			current = &bookmark{URL: strings.TrimSpace(attrs["HREF"]), Folder: folderPath(stack)}
+			if secs, err := strconv.ParseInt(attrs["ADD_DATE"], 10, 64); err == nil && secs > 0 {
+				current.Added = time.Unix(secs, 0)
+			}
+			if t := attrs["TAGS"]; t != "" {
+				current.Tags = strings.Split(t, ",")
+			}
+			textStart, textFor = m[1], "A"
+		case closing && name == "A" && current != nil:
+			current.Title = text
+			if isWebURL(current.URL) {
+				marks = append(marks, *current)
+				lastMark = len(marks) - 1
+			}
+			current = nil
+			textStart, textFor = -1, ""
+		case !closing && name == "DD":
+			// The description runs until the next tag.
+			end := len(doc)
+			if i+1 < len(tags) {
+				end = tags[i+1][0]
+			}
+			if lastMark >= 0 {
+				marks[lastMark].Description = strings.TrimSpace(html.UnescapeString(doc[m[1]:end]))
+			}
+		case !closing && name == "DT":
+			lastMark = -1
+		}
+	}
+	return marks
+}
+
+func parseHTMLAttrs(s string) map[string]string {
+	attrs := make(map[string]string)
+	for _, m := range htmlAttrPattern.FindAllStringSubmatch(s, -1) {
+		attrs[strings.ToUpper(m[1])] = html.UnescapeString(m[2] + m[3] + m[4])
+	}
+	return attrs
+}
+
+type opmlOutline struct {
+	Text        string        `xml:"text,attr"`
+	Title       string        `xml:"title,attr"`
+	XMLURL      string        `xml:"xmlUrl,attr"`
+	HTMLURL     string        `xml:"htmlUrl,attr"`
+	URL         string        `xml:"url,attr"`
+	Description string        `xml:"description,attr"`
+	Created     string        `xml:"created,attr"`
+	Outlines    []opmlOutline `xml:"outline"`
+}
+
+func parseOPML(r io.Reader) ([]bookmark, error) {
+	var doc struct {
+		Body struct {
+			Outlines []opmlOutline `xml:"outline"`
+		} `xml:"body"`
+	}
+	dec := xml.NewDecoder(r)
+	dec.Strict = false
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("reading OPML: %w", err)
+	}
+
+	var marks []bookmark
+	var walk func(outlines []opmlOutline, folder []string)
+	walk = func(outlines []opmlOutline, folder []string) {
+		for _, o := range outlines {
+			title := o.Title
+			if title == "" {
+				title = o.Text
+			}
+			link := o.HTMLURL
+			if link == "" {
+				link = o.URL
+			}
+			if link == "" {
+				link = o.XMLURL
+			}
+			if link == "" {
+				walk(o.Outlines, append(append([]string{}, folder...), title))
+				continue
+			}
+			b := bookmark{
+				Title:       title,
+				URL:         link,
+				Description: o.Description,
+				Folder:      append([]string{}, folder...),
+			}
+			if o.XMLURL != link {
+				b.FeedURL = o.XMLURL
+			}
+			if t, err := time.Parse(time.RFC1123Z, o.Created); err == nil {
+				b.Added = t
+			}
+			if isWebURL(b.URL) {
+				marks = append(marks, b)
+			}
+			walk(o.Outlines, append(append([]string{}, folder...), title))
+		}
+	}
+	walk(doc.Body.Outlines, nil)
+	return marks, nil
+}
+
+// saveBookmarks turns bookmarks into Resources notes built from the basic
+// template, skipping URLs the vault already references. With
+// opts.GroupByFolder one note is created per bookmark folder.
+func saveBookmarks(w io.Writer, baseDir, what string, marks []bookmark, opts ImportOptions) error {
+	existing, err := ScanNotes(baseDir)
+	if err != nil {
+		return err
+	}
+	seen := NewURLIndex(existing)
+
+	var fresh []bookmark
+	dups := 0
+	for _, b := range marks {
+		if paths := seen.Lookup(b.URL); len(paths) > 0 {
+			_, _ = fmt.Fprintf(w, "Duplicate: %s already in %s\n", b.URL, paths[0])
+			dups++
+			continue
+		}
+		if paths := seen.Lookup(b.FeedURL); b.FeedURL != "" && len(paths) > 0 {
+			_, _ = fmt.Fprintf(w, "Duplicate: %s already in %s\n", b.FeedURL, paths[0])
+			dups++
+			continue
+		}
+		seen.Add(b.URL, "this import")
+		if b.FeedURL != "" {
+			seen.Add(b.FeedURL, "this import")
+		}
+		fresh = append(fresh, b)
+	}
+
+	var notes []importNote
+	if opts.GroupByFolder {
+		var order []string
+		groups := make(map[string][]bookmark)
+		for _, b := range fresh {
+			key := strings.Join(b.Folder, "/")
+			if _, ok := groups[key]; !ok {
+				order = append(order, key)
+			}
+			groups[key] = append(groups[key], b)
+		}
+		for _, key := range order {
+			group := groups[key]
+			title := "Bookmarks"
+			if n := len(group[0].Folder); n > 0 {
+				title = group[0].Folder[n-1]
+			}
+			var links []string
+			for _, b := range group {
+				links = append(links, bookmarkLink(b))
+			}
+			note, err := bookmarkNote(baseDir, key, title, group[0].Folder, "", links, earliest(group))
+			if err != nil {
+				return err
+			}
+			notes = append(notes, note)
+		}
+	} else {
+		for _, b := range fresh {
+			title := b.Title
+			if title == "" {
+				title = b.URL
+			}
+			links := []string{b.URL}
+			if b.FeedURL != "" {
+				links = append(links, b.FeedURL)
+			}
+			tags := append(append([]string{}, b.Folder...), b.Tags...)
+			note, err := bookmarkNote(baseDir, b.URL, title, tags, b.Description, links, b.Added)
+			if err != nil {
+				return err
+			}
+			notes = append(notes, note)
+		}
+	}
+
+	if dups > 0 {
+		_, _ = fmt.Fprintf(w, "Skipped %d bookmarks already in the vault.\n", dups)
+	}
+	return saveImported(w, baseDir, what, notes, opts)
+}
+
+// bookmarkNote renders a Resources note through the same template as
+// Create and splits it back into frontmatter and body.
+func bookmarkNote(baseDir, source, title string, tags []string, description string, links []string, added time.Time) (importNote, error) {
+	date := time.Now()
+	if !added.IsZero() {
+		date = added
+	}
+	content, err := buildNoteContent(baseDir, "basic.md", title, date.Format("2006-01-02"), uniqueTags(tags), description, links)
+	if err != nil {
+		return importNote{}, err
+	}
+	fm, body, _ := ParseFrontmatterFromBytes([]byte(content))
+	return importNote{
+		Source:      source,
+		Folder:      "Resources",
+		Frontmatter: fm,
+		Body:        body,
+		ModTime:     added,
+	}, nil
+}
+
+// bookmarkLink formats a bookmark as a References entry.
+func bookmarkLink(b bookmark) string {
+	return FormatReference(b.URL, PageMeta{Title: b.Title, Description: b.Description})
+}
+
+func earliest(marks []bookmark) time.Time {
+	var t time.Time
+	for _, b := range marks {
+		if !b.Added.IsZero() && (t.IsZero() || b.Added.Before(t)) {
+			t = b.Added
+		}
+	}
+	return t
+}
+
+// folderPath drops the unnamed root entries from a folder stack.
+func folderPath(stack []string) []string {
+	var path []string
+	for _, f := range stack {
+		if f != "" {
+			path = append(path, f)
+		}
+	}
+	return path
+}
+
+func isWebURL(u string) bool {
+	lower := strings.ToLower(u)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
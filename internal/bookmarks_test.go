@@ -0,0 +1,158 @@
+package internal
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testBookmarks = `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">
+<TITLE>Bookmarks</TITLE>
+<H1>Bookmarks</H1>
+<DL><p>
+    <DT><H3 ADD_DATE="1700000000">Dev</H3>
+    <DL><p>
+        <DT><H3>Go Lang</H3>
+        <DL><p>
+            <DT><A HREF="https://go.dev/doc/" ADD_DATE="1767225600" TAGS="docs">Go &amp; Docs</A>
+            <DD>Official documentation
+            <DT><A HREF="https://pkg.go.dev/">Packages</A>
+        </DL><p>
+        <DT><A HREF="https://example.com/already">Already Saved</A>
+    </DL><p>
+    <DT><A HREF="javascript:void(0)">Bookmarklet</A>
+    <DT><A HREF="https://news.example.com/">News</A>
+</DL><p>
+`
+
+func TestParseNetscapeBookmarks(t *testing.T) {
+	marks := parseNetscapeBookmarks(testBookmarks)
+	if len(marks) != 4 {
+		t.Fatalf("parsed %d bookmarks, want 4: %+v", len(marks), marks)
+	}
+
+	first := marks[0]
+	if first.Title != "Go & Docs" || first.URL != "https://go.dev/doc/" {
+		t.Errorf("first bookmark = %+v", first)
+	}
+	if !sliceEqual(first.Folder, []string{"Dev", "Go Lang"}) || !sliceEqual(first.Tags, []string{"docs"}) {
+		t.Errorf("first folder/tags = %v/%v", first.Folder, first.Tags)
+	}
+	if first.Description != "Official documentation" {
+		t.Errorf("description = %q", first.Description)
+	}
+	if first.Added.Format("2006-01-02") != "2026-01-01" {
+		t.Errorf("added = %v", first.Added)
+	}
+	if marks[1].Description != "" {
+		t.Errorf("description leaked to next bookmark: %q", marks[1].Description)
+	}
+	if !sliceEqual(marks[2].Folder, []string{"Dev"}) {
+		t.Errorf("folder after nested list closed = %v, want [Dev]", marks[2].Folder)
+	}
+	if len(marks[3].Folder) != 0 {
+		t.Errorf("top-level bookmark folder = %v, want none", marks[3].Folder)
+	}
+}
+
+func TestImportBookmarks(t *testing.T) {
+	dir := setupTestNotesDir(t)
+	writeFiles(t, dir, map[string]string{
+		"Areas/reading.md": "---\ntitle: \"Reading\"\n---\nSee https://example.com/already for more.\n",
+	})
+
+	var log bytes.Buffer
+	if err := ImportBookmarks(&log, dir, strings.NewReader(testBookmarks), ImportOptions{}); err != nil {
+		t.Fatalf("ImportBookmarks() error: %v", err)
+	}
+	out := log.String()
+	if !strings.Contains(out, "Duplicate: https://example.com/already already in "+filepath.Join(dir, "Areas", "reading.md")) {
+		t.Errorf("expected duplicate report, got: %s", out)
+	}
+	if !strings.Contains(out, "Imported 3 notes, 0 conflicts.") {
+		t.Errorf("unexpected summary: %s", out)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "Resources", "go-docs.md"))
+	if err != nil {
+		t.Fatalf("expected note for bookmark: %v", err)
+	}
+	content := string(data)
+	for _, want := range []string{
+		`title: "Go & Docs"`,
+		"date: 2026-01-01",
+		"tags: [dev, go-lang, docs]",
+		"Official documentation",
+		"- https://go.dev/doc/",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("note missing %q:\n%s", want, content)
+		}
+	}
+
+	// A second import finds every URL already in the vault.
+	log.Reset()
+	if err := ImportBookmarks(&log, dir, strings.NewReader(testBookmarks), ImportOptions{}); err != nil {
+		t.Fatalf("second ImportBookmarks() error: %v", err)
+	}
+	if !strings.Contains(log.String(), "Imported 0 notes") {
+		t.Errorf("expected nothing imported twice, got: %s", log.String())
+	}
+}
+
+func TestImportBookmarksGroupByFolder(t *testing.T) {
+	dir := setupTestNotesDir(t)
+
+	var log bytes.Buffer
+	if err := ImportBookmarks(&log, dir, strings.NewReader(testBookmarks), ImportOptions{GroupByFolder: true}); err != nil {
+		t.Fatalf("ImportBookmarks() error: %v", err)
+	}
+	if !strings.Contains(log.String(), "Imported 3 notes") {
+		t.Errorf("expected one note per folder, got: %s", log.String())
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "Resources", "go-lang.md"))
+	if err != nil {
+		t.Fatalf("expected folder note: %v", err)
+	}
+	content := string(data)
+	if !strings.Contains(content, "- [Go & Docs](https://go.dev/doc/) — Official documentation\n- [Packages](https://pkg.go.dev/)") {
+		t.Errorf("folder note missing bookmark list:\n%s", content)
+	}
+}
+
+func TestImportOPML(t *testing.T) {
+	dir := setupTestNotesDir(t)
+	opml := `<?xml version="1.0" encoding="UTF-8"?>
+<opml version="2.0">
+  <head><title>Feeds</title></head>
+  <body>
+    <outline text="Tech">
+      <outline type="rss" text="Go Blog" xmlUrl="https://go.dev/blog/feed.atom" htmlUrl="https://go.dev/blog" description="News from the Go team"/>
+      <outline type="rss" text="Feed Only" xmlUrl="https://example.com/feed.xml"/>
+    </outline>
+  </body>
+</opml>`
+
+	var log bytes.Buffer
+	if err := ImportOPML(&log, dir, strings.NewReader(opml), ImportOptions{}); err != nil {
+		t.Fatalf("ImportOPML() error: %v", err)
+	}
+	if !strings.Contains(log.String(), "Imported 2 notes") {
+		t.Errorf("unexpected summary: %s", log.String())
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "Resources", "go-blog.md"))
+	if err != nil {
+		t.Fatalf("expected feed note: %v", err)
+	}
+	content := string(data)
+	for _, want := range []string{"tags: [tech]", "News from the Go team", "- https://go.dev/blog\n- https://go.dev/blog/feed.atom"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("feed note missing %q:\n%s", want, content)
+		}
+	}
+}
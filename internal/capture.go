@@ -0,0 +1,175 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// captureFolderKey is the frontmatter field of a draft that says which
+// folder to file the note in. It is removed before the note is written.
+const captureFolderKey = "folder"
+
+// CaptureEditorFirst writes a draft note from the basic template to a
+// temporary file, opens it in the user's editor and, once the editor
+// exits, files the note using the title, tags and folder from its
+// frontmatter. The title may also be given as the draft's first heading.
+// A draft left empty or unchanged is discarded. If the editor fails or the
+// note cannot be filed, the draft is kept and its path reported so nothing
+// is lost.
+func CaptureEditorFirst(p *Prompter, baseDir string, opts CreateOptions) error {
+	if !hasEditor() {
+		return ErrNoEditor
+	}
+	draft, err := captureDraft(baseDir)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp("", "qn-draft-*.md")
+	if err != nil {
+		return fmt.Errorf("creating draft: %w", err)
+	}
+	tmp := f.Name()
+	_, err = f.WriteString(draft)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("writing draft: %w", err)
+	}
+
+	// The draft starts with "---" and then the title.
+	if err := runEditor(tmp, 2); err != nil {
+		_, _ = fmt.Fprintf(p.Writer, "Draft kept: %s\n", tmp)
+		return err
+	}
+	data, err := os.ReadFile(tmp)
+	if err != nil {
+		_, _ = fmt.Fprintf(p.Writer, "Draft kept: %s\n", tmp)
+		return fmt.Errorf("reading draft: %w", err)
+	}
+	if strings.TrimSpace(string(data)) == "" || string(data) == draft {
+		_ = os.Remove(tmp)
+		_, _ = fmt.Fprintln(p.Writer, "Aborted: the draft was left empty or unchanged.")
+		return nil
+	}
+
+	destPath, folder, title, err := fileDraft(p, baseDir, data, opts.OnCollision)
+	if err != nil {
+		_, _ = fmt.Fprintf(p.Writer, "Draft kept: %s\n", tmp)
+		return err
+	}
+	if destPath == "" {
+		_, _ = fmt.Fprintf(p.Writer, "Draft kept: %s\n", tmp)
+		return nil
+	}
+	_ = os.Remove(tmp)
+	_, _ = fmt.Fprintf(p.Writer, "Created: %s\n", destPath)
+	autoCommit(p.Writer, baseDir, fmt.Sprintf("qn: create %q in %s", title, folder), destPath)
+	return nil
+}
+
+// captureDraft renders the basic template with an empty title and today's
+// date, plus a folder field set to the default folder.
+func captureDraft(baseDir string) (string, error) {
+	content, err := buildNoteContent(baseDir, "basic.md", "", time.Now().Format("2006-01-02"), nil, "", nil)
+	if err != nil {
+		return "", err
+	}
+	content, _ = setFrontmatterField(content, captureFolderKey, DefaultFolder())
+	return content, nil
+}
+
+// fileDraft writes the edited draft data as a note under baseDir and
+// returns its path, folder and title. The path is empty when the user
+// chose to open an existing note instead.
+func fileDraft(p *Prompter, baseDir string, data []byte, policy CollisionPolicy) (string, string, string, error) {
+	if _, ok := frontmatterEnd(strings.Split(string(data), "\n")); !ok {
+		return "", "", "", errors.New("the draft has no frontmatter")
+	}
+	fm, body, err := ParseFrontmatterFromBytes(data)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	folder := DefaultFolder()
+	var extra []Field
+	for _, f := range fm.Extra {
+		if f.Key != captureFolderKey {
+			extra = append(extra, f)
+			continue
+		}
+		folder = ""
+		value := strings.Trim(strings.TrimSpace(f.Value), `"'`)
+		for _, name := range NoteFolders {
+			if strings.EqualFold(name, value) {
+				folder = name
+			}
+		}
+		if folder == "" {
+			return "", "", "", fmt.Errorf("unknown folder %q (want %s)", value, strings.Join(NoteFolders, ", "))
+		}
+	}
+	fm.Extra = extra
+
+	title := strings.TrimSpace(fm.Title)
+	heading := -1
+	lines := strings.Split(body, "\n")
+	for i, l := range lines {
+		t := strings.TrimSpace(l)
+		if t == "#" || strings.HasPrefix(t, "# ") {
+			heading = i
+			if title == "" {
+				title = strings.TrimSpace(strings.TrimPrefix(t, "#"))
+			}
+			break
+		}
+	}
+	if title == "" {
+		return "", "", "", errors.New("title is required: set it in the frontmatter or as the first heading")
+	}
+	if heading >= 0 && strings.TrimSpace(lines[heading]) == "#" {
+		lines[heading] = "# " + title
+		body = strings.Join(lines, "\n")
+	}
+
+	fm.Title = title
+	fm.Date = noteDate(fm.Date)
+	fm.Tags = NormalizeTags(strings.Join(fm.Tags, ","))
+	if folder == "Projects" {
+		if !containsTag(fm.Tags, "project") {
+			fm.Tags = append(fm.Tags, "project")
+		}
+		if fm.Status == "" || fm.Status == "draft" {
+			fm.Status = "active"
+		}
+	}
+	now := timestamp(time.Now())
+	fm.Created, fm.Updated = now, now
+	content := []byte(FormatFrontmatter(fm) + body)
+
+	path := filepath.Join(baseDir, folder, NoteFilename(folder, title, fm.Date))
+	destPath, err := saveNote(path, content, policy)
+	if errors.Is(err, ErrNoteExists) {
+		if policy == "" || policy == CollisionAsk {
+			policy = askCollisionPolicy(p, path)
+		}
+		switch policy {
+		case CollisionSuffix:
+			destPath, err = saveNote(path, content, CollisionSuffix)
+		case CollisionOpen:
+			_, _ = fmt.Fprintf(p.Writer, "Exists: %s\n", path)
+			openInEditor(p.Writer, path, 0)
+			return "", folder, title, nil
+		}
+	}
+	if err != nil {
+		return "", "", "", err
+	}
+	return destPath, folder, title, nil
+}
@@ -0,0 +1,150 @@
+package internal
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// draftEditor installs a shell script as $VISUAL that replaces the file it
+// is given with content, or leaves it alone when content is nil.
+func draftEditor(t *testing.T, content []byte) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake editor is a shell script")
+	}
+	dir := t.TempDir()
+	script := "#!/bin/sh\n"
+	if content != nil {
+		src := filepath.Join(dir, "draft")
+		if err := os.WriteFile(src, content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		script += "for f; do :; done\ncat \"" + src + "\" > \"$f\"\n"
+	}
+	path := filepath.Join(dir, "editor")
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("VISUAL", path)
+}
+
+func TestCaptureEditorFirst(t *testing.T) {
+	dir := setupListDir(t)
+	draftEditor(t, []byte("---\ntitle: \"\"\ndate: 2026-03-14\ntags: [Go, api design]\nstatus: draft\nfolder: projects\n---\n\n# Release Plan\n\n## Goal\n\nShip it.\n"))
+
+	var out bytes.Buffer
+	p := NewPrompter(strings.NewReader(""), &out)
+	if err := Create(p, dir, CreateOptions{EditFirst: true}); err != nil {
+		t.Fatalf("Create() error: %v\n%s", err, out.String())
+	}
+	path := filepath.Join(dir, "Projects", "2026-03-14-release-plan.md")
+	if !strings.Contains(out.String(), "Created: "+path) {
+		t.Errorf("output = %q", out.String())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fm, body, _ := ParseFrontmatterFromBytes(data)
+	if fm.Title != "Release Plan" || fm.Status != "active" || strings.Join(fm.Tags, ",") != "go,api-design,project" {
+		t.Errorf("frontmatter = %+v", fm)
+	}
+	if len(fm.Extra) != 0 || fm.Created == "" || fm.Updated != fm.Created {
+		t.Errorf("frontmatter extra %v, created %q, updated %q", fm.Extra, fm.Created, fm.Updated)
+	}
+	if body != "\n# Release Plan\n\n## Goal\n\nShip it.\n" {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func TestCaptureEditorFirstFillsHeading(t *testing.T) {
+	dir := setupListDir(t)
+	draftEditor(t, []byte("---\ntitle: Reading List\n---\n\n# \n\n## Notes\n\nBooks.\n"))
+
+	p := NewPrompter(strings.NewReader(""), &bytes.Buffer{})
+	if err := CaptureEditorFirst(p, dir, CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "Inbox", time.Now().Format("2006-01-02")+"-reading-list.md")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "\n# Reading List\n\n## Notes\n\nBooks.\n") {
+		t.Errorf("note:\n%s", data)
+	}
+}
+
+func TestCaptureEditorFirstAborts(t *testing.T) {
+	for name, content := range map[string][]byte{
+		"unchanged": nil,
+		"empty":     []byte("  \n"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := setupListDir(t)
+			draftEditor(t, content)
+			var out bytes.Buffer
+			p := NewPrompter(strings.NewReader(""), &out)
+			if err := CaptureEditorFirst(p, dir, CreateOptions{}); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(out.String(), "Aborted:") {
+				t.Errorf("output = %q", out.String())
+			}
+			if notes, _ := ScanNotes(dir); len(notes) != 0 {
+				t.Errorf("created %d notes", len(notes))
+			}
+		})
+	}
+}
+
+func TestCaptureEditorFirstKeepsDraft(t *testing.T) {
+	dir := setupListDir(t)
+	draftEditor(t, []byte("---\ntitle: Lost\nfolder: Elsewhere\n---\n\nText.\n"))
+
+	var out bytes.Buffer
+	p := NewPrompter(strings.NewReader(""), &out)
+	err := CaptureEditorFirst(p, dir, CreateOptions{})
+	if err == nil || !strings.Contains(err.Error(), `unknown folder "Elsewhere"`) {
+		t.Fatalf("error = %v", err)
+	}
+	kept, ok := strings.CutPrefix(strings.TrimSpace(out.String()), "Draft kept: ")
+	if !ok {
+		t.Fatalf("output = %q", out.String())
+	}
+	defer os.Remove(kept)
+	if data, _ := os.ReadFile(kept); !strings.Contains(string(data), "Text.") {
+		t.Errorf("kept draft = %q", data)
+	}
+}
+
+func TestCaptureEditorFirstEditorFails(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake editor is a shell script")
+	}
+	dir := setupListDir(t)
+	editor := filepath.Join(t.TempDir(), "editor")
+	if err := os.WriteFile(editor, []byte("#!/bin/sh\nfor f; do :; done\necho 'Half typed.' >> \"$f\"\nexit 1\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("VISUAL", editor)
+
+	var out bytes.Buffer
+	p := NewPrompter(strings.NewReader(""), &out)
+	if err := CaptureEditorFirst(p, dir, CreateOptions{}); err == nil {
+		t.Fatal("CaptureEditorFirst() should fail when the editor does")
+	}
+	kept, ok := strings.CutPrefix(strings.TrimSpace(out.String()), "Draft kept: ")
+	if !ok {
+		t.Fatalf("output = %q", out.String())
+	}
+	defer os.Remove(kept)
+	if data, _ := os.ReadFile(kept); !strings.Contains(string(data), "Half typed.") {
+		t.Errorf("kept draft = %q", data)
+	}
+}
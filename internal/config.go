@@ -0,0 +1,373 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// VaultConfigFile is the vault's own config, relative to the vault.
+const VaultConfigFile = ".qn/config.toml"
+
+// Where a config value came from, as reported by Config.Source.
+const (
+	SourceDefault = "default"
+	SourceUser    = "user config"
+	SourceVault   = "vault config"
+)
+
+// ConfigKey is a setting that can be given in a config file.
+type ConfigKey struct {
+	Name string
+	// Env is the environment variable that overrides the file.
+	Env   string
+	Usage string
+	// Default is the value used when nothing sets the key.
+	Default string
+	// UserOnly keys cannot be set in a vault's config.
+	UserOnly bool
+	// check validates a value and returns it normalized.
+	check func(string) (string, error)
+}
+
+// ConfigKeys are the settings qn reads from its config files.
+var ConfigKeys = []ConfigKey{
+	{Name: "notes_dir", Env: "MDNOTES_DIR", Usage: "path to the notes directory", UserOnly: true, check: checkNonEmpty},
+	{Name: "editor", Env: "EDITOR", Usage: "editor command to open notes in; $VISUAL also overrides it", check: checkNonEmpty},
+	{Name: "default_folder", Env: "MDNOTES_DEFAULT_FOLDER", Usage: "folder new notes are filed in unless another is chosen", Default: "Inbox", check: checkFolder},
+	{Name: "list_limit", Env: "MDNOTES_LIST_LIMIT", Usage: "how many notes qn list shows", Default: strconv.Itoa(DefaultListLimit), check: checkListLimit},
+	{Name: "date_format", Env: "MDNOTES_DATE_FORMAT", Usage: "Go time layout for the dates qn list shows", Default: DefaultDateFormat, check: checkNonEmpty},
+	{Name: "slug_policy", Env: "MDNOTES_SLUG_POLICY", Usage: "which folders get date-prefixed filenames: para, always or never", Default: SlugPolicyPARA, check: checkSlugPolicy},
+	{Name: "on_conflict", Env: "MDNOTES_ON_CONFLICT", Usage: "what to do when a new note's filename is taken: ask, suffix, open or abort", Default: string(CollisionAsk), check: checkOnConflict},
+	{Name: "output", Env: "MDNOTES_OUTPUT", Usage: "output format of qn list and qn find: text or json", Default: OutputText, check: checkOutput},
+}
+
+// Slug policies for the slug_policy setting.
+const (
+	// SlugPolicyPARA date-prefixes filenames in Inbox and Projects only.
+	SlugPolicyPARA   = "para"
+	SlugPolicyAlways = "always"
+	SlugPolicyNever  = "never"
+)
+
+// Output formats for the output setting.
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+)
+
+// DefaultDateFormat is how qn list shows dates unless date_format is set.
+const DefaultDateFormat = "2006-01-02"
+
+// LookupConfigKey returns the ConfigKey named name.
+func LookupConfigKey(name string) (ConfigKey, error) {
+	for _, k := range ConfigKeys {
+		if k.Name == name {
+			return k, nil
+		}
+	}
+	names := make([]string, len(ConfigKeys))
+	for i, k := range ConfigKeys {
+		names[i] = k.Name
+	}
+	return ConfigKey{}, fmt.Errorf("unknown config key %q; want one of %s", name, strings.Join(names, ", "))
+}
+
+// Config is qn's settings merged from the user config, the vault config
+// and the environment, in increasing order of precedence. Command-line
+// flags, applied by each command, override all three.
+type Config struct {
+	// UserPath is the user config file, which need not exist.
+	UserPath string
+	// VaultPath is the vault's config file, or empty when no vault is
+	// configured.
+	VaultPath string
+	values    map[string]string
+	sources   map[string]string
+	// files holds the values set by the config files alone.
+	files map[string]string
+}
+
+// UserConfigPath returns the path of the user config file:
+// $XDG_CONFIG_HOME/qn/config.toml, by default ~/.config/qn/config.toml.
+// macOS uses ~/.config too, as other command-line tools do; Windows uses
+// %AppData%\qn\config.toml.
+func UserConfigPath() (string, error) {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" || !filepath.IsAbs(dir) {
+		var err error
+		if runtime.GOOS == "windows" {
+			dir, err = os.UserConfigDir()
+		} else {
+			dir, err = os.UserHomeDir()
+			dir = filepath.Join(dir, ".config")
+		}
+		if err != nil {
+			return "", fmt.Errorf("finding the user config directory: %w", err)
+		}
+	}
+	return filepath.Join(dir, "qn", "config.toml"), nil
+}
+
+// LoadConfig reads the user config, then the config of the vault it or
+// $MDNOTES_DIR names, and applies environment overrides. Missing files
+// are not an error, but invalid values are, whether they come from a file
+// or the environment.
+func LoadConfig() (*Config, error) {
+	c := &Config{
+		values:  make(map[string]string),
+		sources: make(map[string]string),
+		files:   make(map[string]string),
+	}
+	for _, k := range ConfigKeys {
+		if k.Default != "" {
+			c.values[k.Name], c.sources[k.Name] = k.Default, SourceDefault
+		}
+	}
+
+	userPath, err := UserConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	c.UserPath = userPath
+	if err := c.readFile(userPath, SourceUser); err != nil {
+		return nil, err
+	}
+
+	notesDir := os.Getenv("MDNOTES_DIR")
+	if notesDir == "" {
+		notesDir = expandHome(c.files["notes_dir"])
+	}
+	if notesDir != "" {
+		c.VaultPath = filepath.Join(notesDir, filepath.FromSlash(VaultConfigFile))
+		if err := c.readFile(c.VaultPath, SourceVault); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, k := range ConfigKeys {
+		env := k.Env
+		if k.Name == "editor" && os.Getenv("VISUAL") != "" {
+			env = "VISUAL"
+		}
+		v := os.Getenv(env)
+		if v == "" {
+			continue
+		}
+		v, err := k.check(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", env, err)
+		}
+		c.values[k.Name], c.sources[k.Name] = v, "$"+env
+	}
+	return c, nil
+}
+
+// readFile merges the config file at path into c. Vault files may not set
+// user-only keys.
+func (c *Config) readFile(path, source string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading config: %w", err)
+	}
+	doc, err := parseTOML(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	for name := range doc {
+		if name != "" {
+			return fmt.Errorf("%s: unexpected section [%s]", path, name)
+		}
+	}
+	for name, raw := range doc[""] {
+		k, err := LookupConfigKey(name)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if k.UserOnly && source == SourceVault {
+			return fmt.Errorf("%s: %s can only be set in the user config", path, name)
+		}
+		var value string
+		switch v := raw.(type) {
+		case string:
+			value = v
+		case int64:
+			value = strconv.FormatInt(v, 10)
+		default:
+			return fmt.Errorf("%s: %s must be a string", path, name)
+		}
+		if value, err = k.check(value); err != nil {
+			return fmt.Errorf("%s: %s: %w", path, name, err)
+		}
+		c.values[name], c.sources[name], c.files[name] = value, source, value
+	}
+	return nil
+}
+
+// Get returns the value of the key name, or "" when it is not set.
+func (c *Config) Get(name string) string {
+	return c.values[name]
+}
+
+// Source returns where the value of the key name came from: SourceDefault,
+// SourceUser, SourceVault or the environment variable, such as
+// "$MDNOTES_DIR". It is "" when the key is not set.
+func (c *Config) Source(name string) string {
+	return c.sources[name]
+}
+
+// Apply exports the values set by the config files as the environment
+// variables qn reads them from, leaving variables that are already set
+// alone so the environment keeps precedence. The editor is only exported
+// when neither $VISUAL nor $EDITOR is set.
+func (c *Config) Apply() error {
+	for _, k := range ConfigKeys {
+		v, ok := c.files[k.Name]
+		if !ok || os.Getenv(k.Env) != "" {
+			continue
+		}
+		if k.Name == "editor" && os.Getenv("VISUAL") != "" {
+			continue
+		}
+		if k.Name == "notes_dir" {
+			v = expandHome(v)
+		}
+		if err := os.Setenv(k.Env, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetConfigValue sets the key name to value in the config file at path,
+// creating the file if needed and keeping its other lines as they are.
+// vault says whether path is a vault's config.
+func SetConfigValue(path, name, value string, vault bool) error {
+	k, err := LookupConfigKey(name)
+	if err != nil {
+		return err
+	}
+	if k.UserOnly && vault {
+		return fmt.Errorf("%s can only be set in the user config", name)
+	}
+	if value, err = k.check(value); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("reading config: %w", err)
+	}
+	if _, err := parseTOML(data); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	line := name + " = " + strconv.Quote(value)
+	if name == "list_limit" {
+		line = name + " = " + value
+	}
+	var lines []string
+	if len(data) > 0 {
+		lines = strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	}
+	replaced := false
+	for i, l := range lines {
+		t := strings.TrimSpace(l)
+		if strings.HasPrefix(t, "[") {
+			break
+		}
+		if eq := strings.Index(t, "="); eq > 0 {
+			if key, err := parseTOMLKey(strings.TrimSpace(t[:eq])); err == nil && key == name {
+				lines[i] = line
+				replaced = true
+				break
+			}
+		}
+	}
+	if !replaced {
+		lines = append(lines, line)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("creating config directory: %w", err)
+	}
+	return WriteFileAtomic(path, []byte(strings.Join(lines, "\n")+"\n"), WriteOptions{})
+}
+
+// expandHome replaces a leading ~/ in path with the home directory.
+func expandHome(path string) string {
+	rest, ok := strings.CutPrefix(path, "~/")
+	if !ok {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, rest)
+}
+
+func checkNonEmpty(v string) (string, error) {
+	if strings.TrimSpace(v) == "" {
+		return "", errors.New("must not be empty")
+	}
+	return v, nil
+}
+
+func checkFolder(v string) (string, error) {
+	for _, f := range Folders {
+		if strings.EqualFold(f, strings.TrimSpace(v)) {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown folder %q; want one of %s", v, strings.Join(Folders, ", "))
+}
+
+func checkListLimit(v string) (string, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil || n <= 0 {
+		return "", fmt.Errorf("want a positive number, got %q", v)
+	}
+	return strconv.Itoa(n), nil
+}
+
+func checkSlugPolicy(v string) (string, error) {
+	switch p := strings.ToLower(strings.TrimSpace(v)); p {
+	case SlugPolicyPARA, SlugPolicyAlways, SlugPolicyNever:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown slug policy %q; want para, always or never", v)
+}
+
+func checkOnConflict(v string) (string, error) {
+	p, err := ParseCollisionPolicy(v)
+	if err != nil {
+		return "", err
+	}
+	return string(p), nil
+}
+
+// outputFormat validates the output format v, which defaults to
+// OutputText when empty.
+func outputFormat(v string) (string, error) {
+	if strings.TrimSpace(v) == "" {
+		return OutputText, nil
+	}
+	return checkOutput(v)
+}
+
+func checkOutput(v string) (string, error) {
+	switch f := strings.ToLower(strings.TrimSpace(v)); f {
+	case OutputText, OutputJSON:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown output format %q; want text or json", v)
+}
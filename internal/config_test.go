@@ -0,0 +1,227 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// setupConfig points the user config at a temporary directory, clears the
+// environment variables config keys map to, and returns the user config
+// path.
+func setupConfig(t *testing.T) string {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("VISUAL", "")
+	for _, k := range ConfigKeys {
+		t.Setenv(k.Env, "")
+	}
+	path, err := UserConfigPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestUserConfigPath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows uses %AppData%")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	if got, err := UserConfigPath(); err != nil || got != filepath.Join(home, ".config", "qn", "config.toml") {
+		t.Errorf("UserConfigPath() = %q, %v; want ~/.config/qn/config.toml", got, err)
+	}
+	xdg := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+	if got, err := UserConfigPath(); err != nil || got != filepath.Join(xdg, "qn", "config.toml") {
+		t.Errorf("UserConfigPath() = %q, %v; want it under $XDG_CONFIG_HOME", got, err)
+	}
+}
+
+func writeConfig(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	userPath := setupConfig(t)
+	vault := t.TempDir()
+	writeConfig(t, userPath, `# my settings
+notes_dir = "`+filepath.ToSlash(vault)+`"
+default_folder = "areas"
+list_limit = 5
+output = "json"
+`)
+	writeConfig(t, filepath.Join(vault, ".qn", "config.toml"), `list_limit = 20
+slug_policy = "never"
+on_conflict = "Suffix"
+`)
+	t.Setenv("MDNOTES_OUTPUT", "text")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error: %v", err)
+	}
+	if cfg.VaultPath != filepath.Join(vault, ".qn", "config.toml") {
+		t.Errorf("VaultPath = %q", cfg.VaultPath)
+	}
+	tests := []struct{ key, value, source string }{
+		{"notes_dir", filepath.ToSlash(vault), SourceUser},
+		{"default_folder", "Areas", SourceUser},
+		{"list_limit", "20", SourceVault},
+		{"slug_policy", "never", SourceVault},
+		{"output", "text", "$MDNOTES_OUTPUT"},
+		{"on_conflict", "suffix", SourceVault},
+		{"date_format", DefaultDateFormat, SourceDefault},
+		{"editor", "", ""},
+	}
+	for _, tt := range tests {
+		if got := cfg.Get(tt.key); got != tt.value {
+			t.Errorf("Get(%q) = %q, want %q", tt.key, got, tt.value)
+		}
+		if got := cfg.Source(tt.key); got != tt.source {
+			t.Errorf("Source(%q) = %q, want %q", tt.key, got, tt.source)
+		}
+	}
+
+	if err := cfg.Apply(); err != nil {
+		t.Fatalf("Apply() error: %v", err)
+	}
+	for env, want := range map[string]string{
+		"MDNOTES_DIR":            filepath.ToSlash(vault),
+		"MDNOTES_LIST_LIMIT":     "20",
+		"MDNOTES_DEFAULT_FOLDER": "Areas",
+		"MDNOTES_OUTPUT":         "text",
+		"MDNOTES_ON_CONFLICT":    "suffix",
+		"MDNOTES_DATE_FORMAT":    "",
+	} {
+		if got := os.Getenv(env); got != want {
+			t.Errorf("after Apply, $%s = %q, want %q", env, got, want)
+		}
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	tests := []struct {
+		name, user, vault, want string
+	}{
+		{"unknown key", `colour = "blue"`, "", `unknown config key "colour"`},
+		{"bad value", `list_limit = 0`, "", "list_limit: want a positive number"},
+		{"bad folder", `default_folder = "Desk"`, "", `unknown folder "Desk"`},
+		{"not a string", `editor = true`, "", "editor must be a string"},
+		{"section", "[ui]\noutput = \"json\"", "", "unexpected section [ui]"},
+		{"notes_dir in vault", "", `notes_dir = "/tmp"`, "notes_dir can only be set in the user config"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userPath := setupConfig(t)
+			vault := t.TempDir()
+			t.Setenv("MDNOTES_DIR", vault)
+			if tt.user != "" {
+				writeConfig(t, userPath, tt.user+"\n")
+			}
+			if tt.vault != "" {
+				writeConfig(t, filepath.Join(vault, ".qn", "config.toml"), tt.vault+"\n")
+			}
+			_, err := LoadConfig()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("LoadConfig() error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigEnvErrors(t *testing.T) {
+	for env, value := range map[string]string{
+		"MDNOTES_DEFAULT_FOLDER": "Desk",
+		"MDNOTES_LIST_LIMIT":     "lots",
+		"MDNOTES_SLUG_POLICY":    "sometimes",
+		"MDNOTES_OUTPUT":         "yaml",
+		"MDNOTES_ON_CONFLICT":    "overwrite",
+	} {
+		setupConfig(t)
+		t.Setenv(env, value)
+		_, err := LoadConfig()
+		if err == nil || !strings.HasPrefix(err.Error(), "invalid "+env+": ") {
+			t.Errorf("LoadConfig() with $%s=%q: error = %v", env, value, err)
+		}
+	}
+}
+
+func TestSetConfigValue(t *testing.T) {
+	userPath := setupConfig(t)
+	writeConfig(t, userPath, `# my settings
+editor = "vim"   # for now
+output = "text"
+`)
+
+	if err := SetConfigValue(userPath, "editor", `code --wait`, false); err != nil {
+		t.Fatalf("SetConfigValue() error: %v", err)
+	}
+	if err := SetConfigValue(userPath, "list_limit", "25", false); err != nil {
+		t.Fatalf("SetConfigValue() error: %v", err)
+	}
+	data, err := os.ReadFile(userPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# my settings
+editor = "code --wait"
+output = "text"
+list_limit = 25
+`
+	if string(data) != want {
+		t.Errorf("config = %q, want %q", data, want)
+	}
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error: %v", err)
+	}
+	if cfg.Get("editor") != "code --wait" || cfg.Get("list_limit") != "25" {
+		t.Errorf("editor = %q, list_limit = %q", cfg.Get("editor"), cfg.Get("list_limit"))
+	}
+
+	for _, tt := range []struct {
+		name, value string
+		vault       bool
+		want        string
+	}{
+		{"list_limit", "many", false, "want a positive number"},
+		{"slug_policy", "sometimes", false, "unknown slug policy"},
+		{"on_conflict", "replace", false, "invalid collision policy"},
+		{"notes_dir", "/tmp", true, "only be set in the user config"},
+		{"colour", "blue", false, "unknown config key"},
+	} {
+		err := SetConfigValue(userPath, tt.name, tt.value, tt.vault)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("SetConfigValue(%q, %q) error = %v, want it to contain %q", tt.name, tt.value, err, tt.want)
+		}
+	}
+}
+
+func TestNoteFilenameSlugPolicy(t *testing.T) {
+	tests := []struct {
+		policy, folder, want string
+	}{
+		{"", "Inbox", "2026-02-14-my-note.md"},
+		{"", "Areas", "my-note.md"},
+		{"always", "Areas", "2026-02-14-my-note.md"},
+		{"never", "Projects", "my-note.md"},
+	}
+	for _, tt := range tests {
+		t.Setenv("MDNOTES_SLUG_POLICY", tt.policy)
+		if got := NoteFilename(tt.folder, "My Note", "2026-02-14"); got != tt.want {
+			t.Errorf("policy %q: NoteFilename(%q) = %q, want %q", tt.policy, tt.folder, got, tt.want)
+		}
+	}
+}
@@ -1,10 +1,14 @@
 package internal
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 )
@@ -12,10 +16,73 @@ import (
 // Folders defines the PARA folder names and their indices.
 var Folders = []string{"Inbox", "Projects", "Areas", "Resources"}
 
+// CollisionPolicy controls what Create does when the target filename is
+// already taken by another note.
+type CollisionPolicy string
+
+const (
+	// CollisionAsk asks the user which of the other policies to apply.
+	CollisionAsk CollisionPolicy = "ask"
+	// CollisionSuffix appends -2, -3, ... to the filename until it is free.
+	CollisionSuffix CollisionPolicy = "suffix"
+	// CollisionOpen leaves the existing note untouched and opens it instead.
+	CollisionOpen CollisionPolicy = "open"
+	// CollisionAbort stops without writing anything.
+	CollisionAbort CollisionPolicy = "abort"
+)
+
+// ErrNoteExists is returned when a note cannot be created because its
+// filename is already taken and the collision policy is CollisionAbort.
+var ErrNoteExists = errors.New("note already exists")
+
+// ErrDuplicateURL is returned when the user stops Create because a URL is
+// already referenced by another note.
+var ErrDuplicateURL = errors.New("URL already in the vault")
+
+// maxSuffix bounds the number of -N suffixes tried by CollisionSuffix.
+const maxSuffix = 1000
+
+// ParseCollisionPolicy converts a flag or environment value into a
+// CollisionPolicy. An empty string selects CollisionAsk.
+func ParseCollisionPolicy(s string) (CollisionPolicy, error) {
+	switch CollisionPolicy(strings.ToLower(strings.TrimSpace(s))) {
+	case "", CollisionAsk:
+		return CollisionAsk, nil
+	case CollisionSuffix:
+		return CollisionSuffix, nil
+	case CollisionOpen:
+		return CollisionOpen, nil
+	case CollisionAbort:
+		return CollisionAbort, nil
+	}
+	return "", fmt.Errorf("invalid collision policy %q (want ask, suffix, open or abort)", s)
+}
+
+// CreateOptions holds the non-interactive settings for Create.
+type CreateOptions struct {
+	// OnCollision decides what happens when the note's filename is taken.
+	OnCollision CollisionPolicy
+	// Fetcher, when set, looks up the title, description and canonical URL
+	// of each Resources URL. The title prompt may then be left blank to use
+	// the first page's title.
+	Fetcher *URLFetcher
+	// EditFirst skips the prompts and writes the note in the editor
+	// instead; see CaptureEditorFirst.
+	EditFirst bool
+}
+
 // Create runs the interactive note creation flow.
-func Create(p *Prompter, baseDir string) error {
-	title := p.AskRequired("Title: ")
-	folderIdx := p.AskMenu("Folder:", Folders, 0)
+func Create(p *Prompter, baseDir string, opts CreateOptions) error {
+	if opts.EditFirst {
+		return CaptureEditorFirst(p, baseDir, opts)
+	}
+	var title string
+	if opts.Fetcher != nil {
+		title = p.Ask("Title (blank to use the page title): ")
+	} else {
+		title = p.AskRequired("Title: ")
+	}
+	folderIdx := p.AskMenu("Folder:", Folders, slices.Index(Folders, DefaultFolder()))
 	folder := Folders[folderIdx]
 
 	tagsInput := p.Ask("Tags (comma-separated): ")
@@ -23,7 +90,7 @@ func Create(p *Prompter, baseDir string) error {
 
 	body := p.Ask("Body: ")
 
-	var urls []string
+	var urls, related []string
 	if folder == "Resources" {
 		urlInput := p.Ask("URLs (comma-separated): ")
 		if urlInput != "" {
@@ -34,73 +101,328 @@ func Create(p *Prompter, baseDir string) error {
 				}
 			}
 		}
+		var err error
+		related, err = checkDuplicateURLs(p, baseDir, urls)
+		if err != nil {
+			return err
+		}
+		if opts.Fetcher != nil && len(urls) > 0 {
+			var suggested string
+			urls, suggested = enrichURLs(p.Writer, opts.Fetcher, urls)
+			if title == "" && suggested != "" {
+				title = p.Ask(fmt.Sprintf("Title [%s]: ", suggested))
+				if title == "" {
+					title = suggested
+				}
+			}
+		}
+	}
+	if title == "" {
+		title = p.AskRequired("Title: ")
+	}
+
+	note := NewNote{
+		Title:  title,
+		Folder: folder,
+		Tags:   tags,
+		Body:   body,
+		URLs:   append(urls, related...),
+		Date:   time.Now().Format("2006-01-02"),
+	}
+	destPath, err := CreateNote(baseDir, note, opts.OnCollision)
+	if errors.Is(err, ErrNoteExists) {
+		policy := opts.OnCollision
+		if policy == "" || policy == CollisionAsk {
+			policy = askCollisionPolicy(p, note.Path(baseDir))
+		}
+		switch policy {
+		case CollisionSuffix:
+			destPath, err = CreateNote(baseDir, note, CollisionSuffix)
+		case CollisionOpen:
+			_, _ = fmt.Fprintf(p.Writer, "Exists: %s\n", note.Path(baseDir))
+			openInEditor(p.Writer, note.Path(baseDir), 0)
+			return nil
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(p.Writer, "Created: %s\n", destPath)
+	autoCommit(p.Writer, baseDir, fmt.Sprintf("qn: create %q in %s", title, folder), destPath)
+
+	// Offer to open in editor
+	if hasEditor() && p.AskYesNo("Open in editor?") {
+		line := 0
+		if data, err := os.ReadFile(destPath); err == nil {
+			line = bodyLine(string(data))
+		}
+		openInEditor(p.Writer, destPath, line)
+	}
+
+	return nil
+}
+
+// NewNote describes a note for CreateNote.
+type NewNote struct {
+	Title  string
+	Folder string
+	Tags   []string
+	Body   string
+	// URLs are listed under the References heading.
+	URLs []string
+	// Date is the note's YYYY-MM-DD date; empty means today.
+	Date string
+}
+
+// Path returns where the note is filed under baseDir, before any
+// collision suffix.
+func (n NewNote) Path(baseDir string) string {
+	return filepath.Join(baseDir, n.Folder, NoteFilename(n.Folder, n.Title, noteDate(n.Date)))
+}
+
+// CreateNote writes n from the folder's template without prompting and
+// returns the path it used. Projects notes always get the project tag.
+// CollisionAsk behaves like CollisionAbort, since there is nobody to ask.
+func CreateNote(baseDir string, n NewNote, policy CollisionPolicy) (string, error) {
+	if strings.TrimSpace(n.Title) == "" {
+		return "", errors.New("title is required")
+	}
+	if !isNoteFolder(n.Folder) {
+		return "", fmt.Errorf("unknown folder %q", n.Folder)
 	}
 
 	// Select template
 	templateName := "basic.md"
-	if folder == "Projects" {
+	tags := n.Tags
+	if n.Folder == "Projects" {
 		templateName = "project.md"
 		// Ensure "project" tag is present
-		hasProject := false
-		for _, t := range tags {
-			if t == "project" {
-				hasProject = true
-				break
-			}
-		}
-		if !hasProject {
+		if !containsTag(tags, "project") {
 			tags = append(tags, "project")
 		}
 	}
 
-	// Generate filename
-	slug := Slugify(title)
-	today := time.Now().Format("2006-01-02")
-	var filename string
-	if folder == "Inbox" || folder == "Projects" {
-		filename = fmt.Sprintf("%s-%s.md", today, slug)
-	} else {
-		filename = slug + ".md"
+	// Read and fill template
+	content, err := buildNoteContent(baseDir, templateName, n.Title, noteDate(n.Date), tags, n.Body, n.URLs)
+	if err != nil {
+		return "", err
 	}
+	return saveNote(n.Path(baseDir), []byte(content), policy)
+}
 
-	destDir := filepath.Join(baseDir, folder)
-	destPath := filepath.Join(destDir, filename)
+// enrichURLs fetches each URL's metadata and returns the References
+// entries to write along with a suggested title from the first page that
+// has one. URLs that cannot be fetched are kept as bare links.
+func enrichURLs(w io.Writer, f *URLFetcher, urls []string) ([]string, string) {
+	var refs []string
+	suggested := ""
+	for _, u := range urls {
+		meta, err := f.Fetch(context.Background(), u)
+		if err != nil {
+			_, _ = fmt.Fprintf(w, "Warning: could not fetch %s: %v\n", u, err)
+			refs = append(refs, u)
+			continue
+		}
+		if suggested == "" {
+			suggested = meta.Title
+		}
+		refs = append(refs, FormatReference(u, meta))
+	}
+	return refs, suggested
+}
+
+// URLMatch is a URL of a new note that other notes already reference.
+type URLMatch struct {
+	URL string `json:"url"`
+	// Notes are the paths of the notes referencing URL, relative to the
+	// notes directory.
+	Notes []string `json:"notes"`
+}
 
-	// Check for duplicate
-	if _, err := os.Stat(destPath); err == nil {
-		_, _ = fmt.Fprintf(p.Writer, "Warning: file already exists: %s\n", destPath)
+// Ways of handling a new note's URLs that other notes already reference,
+// for the API's on_duplicate_url and the capture form. The prompt in
+// Create offers the same choices.
+const (
+	// DuplicateLink creates the note with links to the other notes.
+	DuplicateLink = "link"
+	// DuplicateIgnore creates the note as it is.
+	DuplicateIgnore = "ignore"
+	// DuplicateAbort stops with an error wrapping ErrDuplicateURL.
+	DuplicateAbort = "abort"
+)
+
+// findDuplicateURLs returns the urls that notes in baseDir already
+// reference, and the "See [[Title]]" links to those notes for the new
+// note's References.
+func findDuplicateURLs(baseDir string, urls []string) ([]URLMatch, []string, error) {
+	if len(urls) == 0 {
+		return nil, nil, nil
+	}
+	notes, err := ScanNotes(baseDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	byPath := make(map[string]Note, len(notes))
+	for _, n := range notes {
+		byPath[n.FilePath] = n
 	}
+	index := NewURLIndex(notes)
 
-	// Read and fill template
-	content, err := buildNoteContent(baseDir, templateName, title, today, tags, body, urls)
+	var dups []URLMatch
+	var links []string
+	seen := make(map[string]bool)
+	for _, u := range urls {
+		paths := index.Lookup(u)
+		if len(paths) == 0 {
+			continue
+		}
+		dups = append(dups, URLMatch{URL: u, Notes: relPaths(baseDir, paths)})
+		for _, path := range paths {
+			if !seen[path] {
+				seen[path] = true
+				links = append(links, "See [["+noteTitle(byPath[path])+"]]")
+			}
+		}
+	}
+	return dups, links, nil
+}
+
+// resolveDuplicateURLs checks urls against the notes in baseDir without
+// asking, handling duplicates as policy says. It returns the duplicates
+// and the links to add to the new note's References.
+func resolveDuplicateURLs(baseDir string, urls []string, policy string) ([]URLMatch, []string, error) {
+	dups, links, err := findDuplicateURLs(baseDir, urls)
+	if err != nil || len(dups) == 0 {
+		return nil, nil, err
+	}
+	switch policy {
+	case DuplicateLink:
+		return dups, links, nil
+	case DuplicateIgnore:
+		return dups, nil, nil
+	}
+	var msgs []string
+	for _, d := range dups {
+		msgs = append(msgs, fmt.Sprintf("%s is referenced in %s", d.URL, strings.Join(d.Notes, ", ")))
+	}
+	return dups, nil, fmt.Errorf("%w: %s", ErrDuplicateURL, strings.Join(msgs, "; "))
+}
+
+// checkDuplicateURLs warns about URLs that other notes already reference
+// and asks whether to link to those notes, create the note anyway or stop.
+// It returns the wikilinks to add to the new note's References.
+func checkDuplicateURLs(p *Prompter, baseDir string, urls []string) ([]string, error) {
+	dups, links, err := findDuplicateURLs(baseDir, urls)
 	if err != nil {
-		return err
+		_, _ = fmt.Fprintf(p.Writer, "Warning: could not check for duplicate URLs: %v\n", err)
+		return nil, nil
+	}
+	if len(dups) == 0 {
+		return nil, nil
+	}
+	for _, d := range dups {
+		_, _ = fmt.Fprintf(p.Writer, "Warning: %s is already referenced in %s\n", d.URL, strings.Join(d.Notes, ", "))
 	}
 
-	// Ensure folder exists
-	if err := os.MkdirAll(destDir, 0o755); err != nil {
-		return fmt.Errorf("creating directory %s: %w", destDir, err)
+	options := []string{"Create and link to existing notes", "Create anyway", "Abort"}
+	switch p.AskMenu("Action:", options, 0) {
+	case 0:
+		return links, nil
+	case 1:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrDuplicateURL, strings.Join(urls, ", "))
 	}
+}
 
-	if err := os.WriteFile(destPath, []byte(content), 0o644); err != nil {
-		return fmt.Errorf("writing note: %w", err)
+// NoteFilename returns the filename for a note titled title in folder.
+// By default Inbox and Projects notes are prefixed with date and other
+// folders use the slug alone; $MDNOTES_SLUG_POLICY (slug_policy in the
+// config) set to "always" or "never" prefixes every note or none.
+func NoteFilename(folder, title, date string) string {
+	slug := Slugify(title)
+	if datePrefixed(folder) {
+		return fmt.Sprintf("%s-%s.md", date, slug)
 	}
+	return slug + ".md"
+}
 
-	_, _ = fmt.Fprintf(p.Writer, "Created: %s\n", destPath)
+// datePrefixed reports whether filenames in folder start with the date
+// under the current slug policy.
+func datePrefixed(folder string) bool {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv("MDNOTES_SLUG_POLICY"))) {
+	case SlugPolicyAlways:
+		return true
+	case SlugPolicyNever:
+		return false
+	}
+	return folder == "Inbox" || folder == "Projects"
+}
 
-	// Offer to open in editor
-	editor := os.Getenv("EDITOR")
-	if editor != "" && p.AskYesNo("Open in editor?") {
-		cmd := exec.Command(editor, destPath)
-		cmd.Stdin = os.Stdin
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		if err := cmd.Run(); err != nil {
-			_, _ = fmt.Fprintf(p.Writer, "Error opening editor: %v\n", err)
+// DefaultFolder returns the folder new notes go in unless another is
+// chosen: $MDNOTES_DEFAULT_FOLDER (default_folder in the config) when it
+// names one of Folders, or else Inbox.
+func DefaultFolder() string {
+	if f, err := checkFolder(os.Getenv("MDNOTES_DEFAULT_FOLDER")); err == nil {
+		return f
+	}
+	return Folders[0]
+}
+
+// saveNote writes data as a new note at path, creating its folder if
+// needed, and returns the path it used. If the filename is taken,
+// CollisionSuffix picks the next free -N name; any other policy leaves the
+// existing file alone and returns an error wrapping ErrNoteExists.
+func saveNote(path string, data []byte, policy CollisionPolicy) (string, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("creating directory %s: %w", dir, err)
+	}
+
+	err := WriteFileAtomic(path, data, WriteOptions{Exclusive: true})
+	if errors.Is(err, fs.ErrExist) {
+		if policy == CollisionSuffix {
+			return createWithSuffix(path, data)
 		}
+		return "", fmt.Errorf("%w: %s", ErrNoteExists, path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("writing note: %w", err)
 	}
+	return path, nil
+}
 
-	return nil
+// askCollisionPolicy asks the user how to handle an existing note at path.
+func askCollisionPolicy(p *Prompter, path string) CollisionPolicy {
+	_, _ = fmt.Fprintf(p.Writer, "A note already exists: %s\n", path)
+	options := []string{"Add suffix", "Open existing", "Abort"}
+	switch p.AskMenu("Action:", options, 0) {
+	case 0:
+		return CollisionSuffix
+	case 1:
+		return CollisionOpen
+	default:
+		return CollisionAbort
+	}
+}
+
+// createWithSuffix writes data to the first free path of the form
+// name-2.md, name-3.md, ... next to path and returns the path it used.
+func createWithSuffix(path string, data []byte) (string, error) {
+	ext := filepath.Ext(path)
+	stem := strings.TrimSuffix(path, ext)
+	for i := 2; i <= maxSuffix; i++ {
+		candidate := fmt.Sprintf("%s-%d%s", stem, i, ext)
+		err := WriteFileAtomic(candidate, data, WriteOptions{Exclusive: true})
+		if err == nil {
+			return candidate, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("%w: no free name after %s-%d%s", ErrNoteExists, stem, maxSuffix, ext)
 }
 
 func buildNoteContent(baseDir, templateName, title, date string, tags []string, body string, urls []string) (string, error) {
@@ -115,12 +437,15 @@ func buildNoteContent(baseDir, templateName, title, date string, tags []string,
 	content = strings.ReplaceAll(content, "{{date}}", date)
 
 	// Replace the frontmatter tags and status
+	now := timestamp(time.Now())
 	fm := Frontmatter{
 		Title:   title,
 		Date:    date,
 		Tags:    tags,
 		Status:  "draft",
 		Aliases: nil,
+		Created: now,
+		Updated: now,
 	}
 	if templateName == "project.md" {
 		fm.Status = "active"
@@ -164,12 +489,15 @@ func buildNoteContent(baseDir, templateName, title, date string, tags []string,
 }
 
 func buildFallbackContent(title, date string, tags []string, body string, urls []string, isProject bool) string {
+	now := timestamp(time.Now())
 	fm := Frontmatter{
 		Title:   title,
 		Date:    date,
 		Tags:    tags,
 		Status:  "draft",
 		Aliases: nil,
+		Created: now,
+		Updated: now,
 	}
 	if isProject {
 		fm.Status = "active"
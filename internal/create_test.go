@@ -2,6 +2,7 @@ package internal
 
 import (
 	"bytes"
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
@@ -84,7 +85,7 @@ func TestCreateInbox(t *testing.T) {
 	// Unset EDITOR to avoid open prompt issues
 	t.Setenv("EDITOR", "")
 
-	err := Create(p, dir)
+	err := Create(p, dir, CreateOptions{})
 	if err != nil {
 		t.Fatalf("Create() error: %v", err)
 	}
@@ -126,7 +127,7 @@ func TestCreateProject(t *testing.T) {
 	p := NewPrompter(r, w)
 	t.Setenv("EDITOR", "")
 
-	err := Create(p, dir)
+	err := Create(p, dir, CreateOptions{})
 	if err != nil {
 		t.Fatalf("Create() error: %v", err)
 	}
@@ -159,7 +160,7 @@ func TestCreateResource(t *testing.T) {
 	p := NewPrompter(r, w)
 	t.Setenv("EDITOR", "")
 
-	err := Create(p, dir)
+	err := Create(p, dir, CreateOptions{})
 	if err != nil {
 		t.Fatalf("Create() error: %v", err)
 	}
@@ -196,3 +197,105 @@ func TestBuildNoteContentFallback(t *testing.T) {
 		t.Errorf("expected heading in fallback content, got:\n%s", content)
 	}
 }
+
+func TestCreateCollisionSuffix(t *testing.T) {
+	dir := setupTestNotesDir(t)
+	t.Setenv("EDITOR", "")
+
+	existing := filepath.Join(dir, "Areas", "health.md")
+	if err := os.WriteFile(existing, []byte("original\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range []string{"health-2.md", "health-3.md"} {
+		w := &bytes.Buffer{}
+		p := NewPrompter(strings.NewReader("Health\n3\n\n\nn\n"), w)
+		if err := Create(p, dir, CreateOptions{OnCollision: CollisionSuffix}); err != nil {
+			t.Fatalf("Create() #%d error: %v", i, err)
+		}
+		if !strings.Contains(w.String(), filepath.Join("Areas", want)) {
+			t.Errorf("expected %s in output, got: %s", want, w.String())
+		}
+	}
+
+	data, _ := os.ReadFile(existing)
+	if string(data) != "original\n" {
+		t.Errorf("existing note was overwritten: %q", data)
+	}
+}
+
+func TestCreateCollisionAbort(t *testing.T) {
+	dir := setupTestNotesDir(t)
+	t.Setenv("EDITOR", "")
+
+	existing := filepath.Join(dir, "Areas", "health.md")
+	if err := os.WriteFile(existing, []byte("original\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewPrompter(strings.NewReader("Health\n3\n\n\n"), &bytes.Buffer{})
+	err := Create(p, dir, CreateOptions{OnCollision: CollisionAbort})
+	if !errors.Is(err, ErrNoteExists) {
+		t.Fatalf("Create() error = %v, want ErrNoteExists", err)
+	}
+
+	entries, _ := os.ReadDir(filepath.Join(dir, "Areas"))
+	if len(entries) != 1 {
+		t.Errorf("expected only the existing note in Areas, got %d files", len(entries))
+	}
+	data, _ := os.ReadFile(existing)
+	if string(data) != "original\n" {
+		t.Errorf("existing note was overwritten: %q", data)
+	}
+}
+
+func TestCreateCollisionAsk(t *testing.T) {
+	dir := setupTestNotesDir(t)
+	t.Setenv("EDITOR", "")
+
+	existing := filepath.Join(dir, "Areas", "health.md")
+	if err := os.WriteFile(existing, []byte("original\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Choose "Open existing" from the collision menu.
+	w := &bytes.Buffer{}
+	p := NewPrompter(strings.NewReader("Health\n3\n\n\n2\n"), w)
+	if err := Create(p, dir, CreateOptions{}); err != nil {
+		t.Fatalf("Create() error: %v", err)
+	}
+	if !strings.Contains(w.String(), "Exists: "+existing) {
+		t.Errorf("expected existing path in output, got: %s", w.String())
+	}
+	entries, _ := os.ReadDir(filepath.Join(dir, "Areas"))
+	if len(entries) != 1 {
+		t.Errorf("expected no new file in Areas, got %d files", len(entries))
+	}
+}
+
+func TestParseCollisionPolicy(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    CollisionPolicy
+		wantErr bool
+	}{
+		{"", CollisionAsk, false},
+		{"ask", CollisionAsk, false},
+		{"Suffix", CollisionSuffix, false},
+		{"open", CollisionOpen, false},
+		{" abort ", CollisionAbort, false},
+		{"overwrite", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseCollisionPolicy(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCollisionPolicy(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseCollisionPolicy(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Problem is something wrong with a note file that a normal scan would
+// silently skip or misread.
+type Problem struct {
+	Path string
+	// Line is the 1-based line the problem is on, or 0 when it concerns
+	// the whole file.
+	Line    int
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Line == 0 {
+		return fmt.Sprintf("%s: %s", p.Path, p.Message)
+	}
+	return fmt.Sprintf("%s:%d: %s", p.Path, p.Line, p.Message)
+}
+
+// ProblemError is returned by strict commands when the vault has problems.
+// Its problem paths are relative to the vault.
+type ProblemError struct {
+	Problems []Problem
+}
+
+func (e *ProblemError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d problems found:", len(e.Problems))
+	for _, p := range e.Problems {
+		b.WriteString("\n  " + p.String())
+	}
+	return b.String()
+}
+
+// Diagnose scans baseDir like ScanNotes and also returns the problems it
+// finds: unreadable files, malformed or unterminated frontmatter, invalid
+// dates, empty titles and titles shared by several notes. Dates are
+// checked against the date_format of the vault schema, as lint does.
+// Problems are ordered by path and line, with paths relative to baseDir.
+func Diagnose(ctx context.Context, baseDir string) ([]Note, []Problem, error) {
+	schema, err := LoadSchema(baseDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	jobs, err := scanJobs(ctx, baseDir, ScanOptions{}, schema)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var problems []Problem
+	for _, j := range jobs {
+		if j.err != nil {
+			problems = append(problems, Problem{Path: j.path, Message: "cannot read: " + j.err.Error()})
+			continue
+		}
+		for _, p := range j.problems {
+			p.Path = j.path
+			problems = append(problems, p)
+		}
+	}
+	problems = append(problems, duplicateTitles(baseDir, jobs)...)
+
+	for i := range problems {
+		problems[i].Path = relPaths(baseDir, []string{problems[i].Path})[0]
+	}
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].Path != problems[j].Path {
+			return problems[i].Path < problems[j].Path
+		}
+		return problems[i].Line < problems[j].Line
+	})
+	return jobNotes(jobs), problems, nil
+}
+
+// scanStrict is ScanNotes for commands run with --strict: any problem
+// Diagnose finds is returned as a *ProblemError.
+func scanStrict(baseDir string) ([]Note, error) {
+	notes, problems, err := Diagnose(context.Background(), baseDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(problems) > 0 {
+		return nil, &ProblemError{Problems: problems}
+	}
+	return notes, nil
+}
+
+// Doctor prints every problem Diagnose finds in baseDir, one per line as
+// path:line: message. It returns an error when there are any, so scripts
+// can rely on the exit status.
+func Doctor(w io.Writer, baseDir string) error {
+	notes, problems, err := Diagnose(context.Background(), baseDir)
+	if err != nil {
+		return err
+	}
+	if len(problems) == 0 {
+		_, _ = fmt.Fprintf(w, "No problems found in %d notes.\n", len(notes))
+		return nil
+	}
+
+	files := make(map[string]bool)
+	for _, p := range problems {
+		_, _ = fmt.Fprintln(w, p)
+		files[p.Path] = true
+	}
+	_, _ = fmt.Fprintf(w, "\n%d problems in %d files.\n", len(problems), len(files))
+	return fmt.Errorf("%d problems found", len(problems))
+}
+
+// checkNoteData checks the frontmatter of a note file the way
+// ParseFrontmatterFromBytes reads it, reporting what that parser would
+// skip or misread, and dates not in the dateFormat layout. It also
+// returns the line of the title field, or 0.
+func checkNoteData(data []byte, dateFormat string) ([]Problem, int) {
+	lines := strings.Split(string(data), "\n")
+	if strings.TrimSpace(lines[0]) != "---" {
+		return []Problem{{Line: 1, Message: "no frontmatter, so the note has no title"}}, 0
+	}
+
+	end, ok := frontmatterEnd(lines)
+	if !ok {
+		return []Problem{{Line: 1, Message: "frontmatter is never closed with ---, so the whole file is read as body"}}, 0
+	}
+
+	var problems []Problem
+	titleLine := 0
+	lastKey := ""
+	for i, line := range lines[1:end] {
+		n := i + 2
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if isYAMLContinuation(line) {
+			if lastKey == "" {
+				problems = append(problems, Problem{Line: n, Message: fmt.Sprintf("malformed frontmatter line %q: indented line before any field", trimmed)})
+			}
+			continue
+		}
+		key, value, ok := parseYAMLLine(line)
+		if !ok || key == "" {
+			problems = append(problems, Problem{Line: n, Message: fmt.Sprintf("malformed frontmatter line %q: want key: value", trimmed)})
+			continue
+		}
+		lastKey = key
+		switch key {
+		case "title":
+			titleLine = n
+			if unquote(value) == "" {
+				problems = append(problems, Problem{Line: n, Message: "title is empty"})
+			}
+		case "date":
+			date := unquote(value)
+			if _, err := time.Parse(dateFormat, date); date != "" && err != nil {
+				problems = append(problems, Problem{Line: n, Message: fmt.Sprintf("date %q does not match format %q", date, dateFormat)})
+			}
+		}
+	}
+	if titleLine == 0 {
+		problems = append(problems, Problem{Line: 1, Message: "frontmatter has no title"})
+	}
+	return problems, titleLine
+}
+
+// unclosedFrontmatter reports whether data opens a frontmatter block that
+// is never closed.
+func unclosedFrontmatter(data []byte) bool {
+	lines := strings.Split(string(data), "\n")
+	_, closed := frontmatterEnd(lines)
+	return strings.TrimSpace(lines[0]) == "---" && !closed
+}
+
+// duplicateTitles reports every note whose title, ignoring case, is also
+// used by another note. Such notes cannot be told apart by title in
+// wikilinks or lookups.
+func duplicateTitles(baseDir string, jobs []scanJob) []Problem {
+	byTitle := make(map[string][]scanJob)
+	for _, j := range jobs {
+		title := strings.ToLower(strings.TrimSpace(j.note.Frontmatter.Title))
+		if j.err != nil || title == "" {
+			continue
+		}
+		byTitle[title] = append(byTitle[title], j)
+	}
+
+	var problems []Problem
+	for _, group := range byTitle {
+		if len(group) < 2 {
+			continue
+		}
+		for _, j := range group {
+			var others []string
+			for _, o := range group {
+				if o.path != j.path {
+					others = append(others, o.path)
+				}
+			}
+			problems = append(problems, Problem{
+				Path:    j.path,
+				Line:    j.titleLine,
+				Message: fmt.Sprintf("duplicate title %q, also used by %s", j.note.Frontmatter.Title, strings.Join(relPaths(baseDir, others), ", ")),
+			})
+		}
+	}
+	return problems
+}
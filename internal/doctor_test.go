@@ -0,0 +1,132 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func setupDoctorVault(t *testing.T) string {
+	t.Helper()
+	dir := setupListDir(t)
+	files := map[string]string{
+		"Inbox/ok.md":           "---\ntitle: Fine\ndate: 2026-02-13\n---\nBody.\n",
+		"Inbox/unclosed.md":     "---\ntitle: Never Closed\ndate: 2026-02-13\n\nBody.\n",
+		"Areas/go.md":           "---\ntitle: Go\n---\n",
+		"Resources/go.md":       "---\ntitle: \"go\"\ndate: 13/02/2026\njust some words\n---\n",
+		"Resources/untitled.md": "---\ntitle: \"\"\ndate: 2026-02-13\n---\n",
+		"Resources/no-fm.md":    "Just text.\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestDoctor(t *testing.T) {
+	dir := setupDoctorVault(t)
+
+	var buf bytes.Buffer
+	err := Doctor(&buf, dir)
+	if err == nil {
+		t.Fatal("Doctor() should fail when there are problems")
+	}
+	want := `Areas/go.md:2: duplicate title "Go", also used by Resources/go.md
+Inbox/unclosed.md:1: frontmatter is never closed with ---, so the whole file is read as body
+Resources/go.md:2: duplicate title "go", also used by Areas/go.md
+Resources/go.md:3: date "13/02/2026" does not match format "2006-01-02"
+Resources/go.md:4: malformed frontmatter line "just some words": want key: value
+Resources/no-fm.md:1: no frontmatter, so the note has no title
+Resources/untitled.md:2: title is empty
+
+7 problems in 5 files.
+`
+	if buf.String() != want {
+		t.Errorf("Doctor() output:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestDiagnoseSchemaDateFormat(t *testing.T) {
+	dir := setupListDir(t)
+	writeSchema(t, dir, "[default]\ndate_format = \"02/01/2006\"\n")
+	files := map[string]string{
+		"Inbox/uk.md":  "---\ntitle: UK\ndate: 13/02/2026\n---\n",
+		"Inbox/iso.md": "---\ntitle: ISO\ndate: 2026-02-13\n---\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, problems, err := Diagnose(t.Context(), dir)
+	if err != nil {
+		t.Fatalf("Diagnose() error: %v", err)
+	}
+	if len(problems) != 1 || problems[0].String() != `Inbox/iso.md:3: date "2026-02-13" does not match format "02/01/2006"` {
+		t.Errorf("problems = %v", problems)
+	}
+}
+
+func TestDoctorClean(t *testing.T) {
+	dir := setupListDir(t)
+	if err := os.WriteFile(filepath.Join(dir, "Inbox", "ok.md"), []byte("---\ntitle: Fine\ntags:\n  - a\n---\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := Doctor(&buf, dir); err != nil {
+		t.Fatalf("Doctor() error: %v", err)
+	}
+	if buf.String() != "No problems found in 1 notes.\n" {
+		t.Errorf("Doctor() output = %q", buf.String())
+	}
+}
+
+func TestDiagnoseUnreadable(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can read files without permission")
+	}
+	dir := setupListDir(t)
+	path := filepath.Join(dir, "Inbox", "secret.md")
+	if err := os.WriteFile(path, []byte("---\ntitle: Secret\n---\n"), 0o000); err != nil {
+		t.Fatal(err)
+	}
+
+	notes, problems, err := Diagnose(t.Context(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) != 0 || len(problems) != 1 || problems[0].Path != "Inbox/secret.md" || !strings.HasPrefix(problems[0].Message, "cannot read:") {
+		t.Errorf("notes = %d, problems = %v", len(notes), problems)
+	}
+}
+
+func TestStrictListAndFind(t *testing.T) {
+	dir := setupDoctorVault(t)
+
+	var buf bytes.Buffer
+	if err := List(&buf, dir, ListOptions{}); err != nil {
+		t.Fatalf("List() error: %v", err)
+	}
+
+	var perr *ProblemError
+	err := List(&buf, dir, ListOptions{Strict: true})
+	if !errors.As(err, &perr) || len(perr.Problems) != 7 {
+		t.Fatalf("List(--strict) error = %v", err)
+	}
+	if !strings.Contains(err.Error(), "\n  Inbox/unclosed.md:1: frontmatter is never closed") {
+		t.Errorf("error does not list problems:\n%v", err)
+	}
+
+	buf.Reset()
+	err = Find(&buf, dir, "fine", FindOptions{Strict: true})
+	if !errors.As(err, &perr) || buf.Len() != 0 {
+		t.Errorf("Find(--strict) error = %v, output %q", err, buf.String())
+	}
+}
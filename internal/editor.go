@@ -0,0 +1,190 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ErrNoEditor is returned when neither $VISUAL nor $EDITOR is set.
+var ErrNoEditor = errors.New("no editor configured; set $VISUAL or $EDITOR")
+
+// editorCommand returns the user's editor as a program and its arguments,
+// from $VISUAL or else $EDITOR. The variable is split into words the way
+// a shell would, so commands such as `code --wait` or
+// `"/Applications/My Editor" -w` work.
+func editorCommand() ([]string, error) {
+	for _, name := range []string{"VISUAL", "EDITOR"} {
+		argv, err := splitShellWords(os.Getenv(name))
+		if err != nil {
+			return nil, fmt.Errorf("$%s: %w", name, err)
+		}
+		if len(argv) > 0 {
+			return argv, nil
+		}
+	}
+	return nil, ErrNoEditor
+}
+
+// hasEditor reports whether $VISUAL or $EDITOR names an editor.
+func hasEditor() bool {
+	_, err := editorCommand()
+	return err == nil
+}
+
+// splitShellWords splits s into words like a POSIX shell: on unquoted
+// whitespace, with 'single quotes' taken literally, "double quotes"
+// allowing \", \\, \$ and \` escapes, and a backslash outside quotes
+// escaping the next character. It does not expand variables or globs.
+func splitShellWords(s string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case c == '\\':
+			inWord = true
+			if i+1 < len(s) {
+				i++
+				word.WriteByte(s[i])
+			}
+		case c == '\'':
+			inWord = true
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return nil, errors.New("unterminated single quote")
+			}
+			word.WriteString(s[i+1 : i+1+end])
+			i += end + 1
+		case c == '"':
+			inWord = true
+			closed := false
+			for i++; i < len(s); i++ {
+				if s[i] == '"' {
+					closed = true
+					break
+				}
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte("\"\\$`", s[i+1]) >= 0 {
+					i++
+				}
+				word.WriteByte(s[i])
+			}
+			if !closed {
+				return nil, errors.New("unterminated double quote")
+			}
+		default:
+			inWord = true
+			word.WriteByte(c)
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// editorArgs returns the arguments to open path in the editor argv, at
+// the 1-based line when it is positive and the editor is one qn knows how
+// to position. Other editors just open the file.
+func editorArgs(argv []string, path string, line int) []string {
+	args := append([]string{}, argv[1:]...)
+	if line <= 0 {
+		return append(args, path)
+	}
+	name := strings.TrimSuffix(strings.ToLower(filepath.Base(argv[0])), ".exe")
+	switch name {
+	case "vi", "vim", "nvim", "gvim", "mvim", "view", "nano", "pico", "emacs", "emacsclient", "micro", "kak", "joe", "mg":
+		return append(args, "+"+strconv.Itoa(line), path)
+	case "code", "code-insiders", "codium", "vscodium", "cursor", "windsurf":
+		return append(args, "--goto", path+":"+strconv.Itoa(line))
+	case "subl", "sublime_text", "hx", "helix", "zed":
+		return append(args, path+":"+strconv.Itoa(line))
+	}
+	return append(args, path)
+}
+
+// runEditor opens path in the user's editor on the current terminal, at
+// the 1-based line when line is positive, and waits for it to exit.
+func runEditor(path string, line int) error {
+	argv, err := editorCommand()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(argv[0], editorArgs(argv, path, line)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("running %s: %w", argv[0], err)
+	}
+	return nil
+}
+
+// openInEditor opens path at line in the user's editor, if one is set,
+// reporting failures to w.
+func openInEditor(w io.Writer, path string, line int) {
+	if err := runEditor(path, line); err != nil && !errors.Is(err, ErrNoEditor) {
+		_, _ = fmt.Fprintf(w, "Error opening editor: %v\n", err)
+	}
+}
+
+// bodyLine returns the 1-based line where the text of a newly created note
+// goes: the first line of its Notes or Goal section, or the line after
+// the frontmatter when it has neither.
+func bodyLine(content string) int {
+	lines := strings.Split(content, "\n")
+	for i, l := range lines {
+		if t := strings.TrimSpace(l); t != "## Notes" && t != "## Goal" {
+			continue
+		}
+		for j := i + 1; j < len(lines); j++ {
+			t := strings.TrimSpace(lines[j])
+			if t == "" {
+				continue
+			}
+			if strings.HasPrefix(t, "#") {
+				// The section is empty: start on the line under the heading.
+				return i + 2
+			}
+			return j + 1
+		}
+		return i + 2
+	}
+	if end, ok := frontmatterEnd(lines); ok {
+		return end + 2
+	}
+	return 1
+}
+
+// matchLine returns the 1-based line of the first line of the note file
+// at path, outside its frontmatter, that contains the lowercased query q,
+// or 0 when none does.
+func matchLine(path, q string) int {
+	data, err := os.ReadFile(path)
+	if err != nil || q == "" {
+		return 0
+	}
+	lines := strings.Split(string(data), "\n")
+	start := 0
+	if end, ok := frontmatterEnd(lines); ok {
+		start = end + 1
+	}
+	for i := start; i < len(lines); i++ {
+		if strings.Contains(strings.ToLower(lines[i]), q) {
+			return i + 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,127 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestSplitShellWords(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"vim", []string{"vim"}},
+		{"  code   --wait ", []string{"code", "--wait"}},
+		{"emacsclient -t", []string{"emacsclient", "-t"}},
+		{`"/Applications/My Editor" -w`, []string{"/Applications/My Editor", "-w"}},
+		{`'it''s' a\ b`, []string{"its", "a b"}},
+		{`"say \"hi\" \n"`, []string{`say "hi" \n`}},
+		{`""`, []string{""}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		got, err := splitShellWords(tt.in)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitShellWords(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []string{`"open`, `'open`} {
+		if _, err := splitShellWords(in); err == nil {
+			t.Errorf("splitShellWords(%q) succeeded", in)
+		}
+	}
+}
+
+func TestEditorArgs(t *testing.T) {
+	tests := []struct {
+		argv []string
+		line int
+		want []string
+	}{
+		{[]string{"vim"}, 0, []string{"n.md"}},
+		{[]string{"/usr/bin/nvim"}, 7, []string{"+7", "n.md"}},
+		{[]string{"emacsclient", "-t"}, 3, []string{"-t", "+3", "n.md"}},
+		{[]string{"code", "--wait"}, 12, []string{"--wait", "--goto", "n.md:12"}},
+		{[]string{"hx"}, 2, []string{"n.md:2"}},
+		{[]string{"ed"}, 5, []string{"n.md"}},
+	}
+	for _, tt := range tests {
+		if got := editorArgs(tt.argv, "n.md", tt.line); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("editorArgs(%q, %d) = %q, want %q", tt.argv, tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestBodyLine(t *testing.T) {
+	tests := []struct {
+		content string
+		want    int
+	}{
+		{"---\ntitle: A\n---\n\n# A\n\n## Notes\n\n", 8},
+		{"---\ntitle: A\n---\n\n## Goal\n\nShip it.\n\n## Tasks\n", 7},
+		{"---\ntitle: A\n---\n## Notes\n## Links\n", 5},
+		{"---\ntitle: A\n---\nText.\n", 4},
+		{"Just text.\n", 1},
+	}
+	for _, tt := range tests {
+		if got := bodyLine(tt.content); got != tt.want {
+			t.Errorf("bodyLine(%q) = %d, want %d", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestOpenAtMatchingLine(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake editor is a shell script")
+	}
+	dir := setupOpenVault(t)
+	path := filepath.Join(dir, "Resources", "go.md")
+	if got := matchLine(path, "kilometres"); got != 4 {
+		t.Errorf("matchLine() = %d, want 4", got)
+	}
+	if got := matchLine(path, "go notes"); got != 0 {
+		t.Errorf("matchLine() matched the frontmatter: %d", got)
+	}
+
+	// A fake vim records the arguments it is run with.
+	bin := t.TempDir()
+	out := filepath.Join(bin, "args")
+	script := filepath.Join(bin, "vim")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\nprintf '%s\\n' \"$@\" > \""+out+"\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("VISUAL", "'"+script+"'")
+
+	p := NewPrompter(strings.NewReader(""), &bytes.Buffer{})
+	if err := OpenFound(p, dir, "sleep", FindOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(out)
+	if want := "+4\n" + filepath.Join(dir, "Areas", "health.md") + "\n"; string(data) != want {
+		t.Errorf("editor args = %q, want %q", data, want)
+	}
+
+	// An exact match opens at the top.
+	if err := Open(p, dir, "Health"); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = os.ReadFile(out)
+	if want := filepath.Join(dir, "Areas", "health.md") + "\n"; string(data) != want {
+		t.Errorf("editor args = %q, want %q", data, want)
+	}
+
+	// Strict refuses to open anything while a note is broken.
+	if err := os.WriteFile(filepath.Join(dir, "Inbox", "broken.md"), []byte("---\ntitle: Broken\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var perr *ProblemError
+	if err := OpenFound(p, dir, "sleep", FindOptions{Strict: true}); !errors.As(err, &perr) {
+		t.Errorf("OpenFound(strict) error = %v, want a *ProblemError", err)
+	}
+}
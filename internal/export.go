@@ -0,0 +1,322 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// HTMLExportOptions configures ExportHTML.
+type HTMLExportOptions struct {
+	// OutDir is the directory the site is written to.
+	OutDir string
+	// Tags limits the export to notes carrying every one of these tags.
+	// An empty list exports every note.
+	Tags []string
+}
+
+type pageLink struct {
+	Title string
+	Href  string
+}
+
+type pageGroup struct {
+	Name  string
+	Href  string
+	Pages []pageLink
+}
+
+type pageData struct {
+	Title     string
+	Root      string
+	Date      string
+	Folder    pageLink
+	Tags      []pageLink
+	Body      template.HTML
+	Backlinks []pageLink
+	Groups    []pageGroup
+}
+
+var siteTemplate = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 46rem; margin: 2rem auto; padding: 0 1rem; line-height: 1.5; color: #222; }
+nav, .meta { font-size: 0.9rem; color: #666; }
+a { color: #0b5fa5; }
+pre { background: #f5f5f5; padding: 0.75rem; overflow-x: auto; }
+code { font-size: 0.9em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ddd; padding: 0.25rem 0.5rem; }
+li.task { list-style: none; }
+.missing { color: #999; }
+.tag { margin-right: 0.5rem; }
+</style>
+</head>
+<body>
+<nav><a href="{{.Root}}index.html">Index</a></nav>
+<h1>{{.Title}}</h1>
+{{- if .Folder.Title}}
+<p class="meta">{{if .Date}}{{.Date}} · {{end}}<a href="{{.Folder.Href}}">{{.Folder.Title}}</a>{{range .Tags}} <a class="tag" href="{{.Href}}">#{{.Title}}</a>{{end}}</p>
+{{- end}}
+{{.Body}}
+{{- if .Backlinks}}
+<section class="backlinks">
+<h2>Backlinks</h2>
+<ul>
+{{- range .Backlinks}}
+<li><a href="{{.Href}}">{{.Title}}</a></li>
+{{- end}}
+</ul>
+</section>
+{{- end}}
+{{- range .Groups}}
+<section>
+<h2>{{if .Href}}<a href="{{.Href}}">{{.Name}}</a>{{else}}{{.Name}}{{end}}</h2>
+<ul>
+{{- range .Pages}}
+<li><a href="{{.Href}}">{{.Title}}</a></li>
+{{- end}}
+</ul>
+</section>
+{{- end}}
+</body>
+</html>
+`))
+
+// ExportHTML renders notes to a static HTML site under opts.OutDir. Each
+// note becomes a page with its wikilinks resolved to relative links and a
+// list of backlinks. Index pages are generated for the site, every folder
+// and every tag. Wikilinks to notes outside the export are rendered as
+// plain text so unpublished notes are not exposed.
+func ExportHTML(w io.Writer, baseDir string, opts HTMLExportOptions) error {
+	if opts.OutDir == "" {
+		return fmt.Errorf("output directory is required")
+	}
+
+	notes, err := ScanNotes(baseDir)
+	if err != nil {
+		return err
+	}
+
+	var selected []Note
+	for _, n := range notes {
+		if hasAllTags(n.Frontmatter.Tags, opts.Tags) {
+			selected = append(selected, n)
+		}
+	}
+	sortByTitle(selected)
+
+	pages := make(map[string]string, len(selected))
+	for _, n := range selected {
+		stem := strings.TrimSuffix(filepath.Base(n.FilePath), ".md")
+		pages[n.FilePath] = n.Folder + "/" + stem + ".html"
+	}
+	index := NewLinkIndex(selected)
+
+	folders := make(map[string][]Note)
+	tags := make(map[string][]Note)
+	for _, n := range selected {
+		folders[n.Folder] = append(folders[n.Folder], n)
+		for _, t := range n.Frontmatter.Tags {
+			tags[t] = append(tags[t], n)
+		}
+	}
+
+	tagPages := tagPageNames(sortedKeys(tags))
+
+	for _, n := range selected {
+		page := pages[n.FilePath]
+		dir := path.Dir(page)
+		resolve := func(target string) (string, bool) {
+			linked, ok := index.Resolve(target)
+			if !ok {
+				return "", false
+			}
+			return relHref(dir, pages[linked.FilePath]), true
+		}
+
+		data := pageData{
+			Title:  noteTitle(n),
+			Root:   relRoot(dir),
+			Date:   n.Frontmatter.Date,
+			Folder: pageLink{Title: n.Folder, Href: relHref(dir, n.Folder+"/index.html")},
+			Body:   template.HTML(RenderMarkdown(stripTitleHeading(n.Body, noteTitle(n)), resolve)),
+		}
+		for _, t := range n.Frontmatter.Tags {
+			data.Tags = append(data.Tags, pageLink{Title: t, Href: relHref(dir, tagPages[t])})
+		}
+		for _, b := range index.Backlinks(n) {
+			data.Backlinks = append(data.Backlinks, pageLink{Title: noteTitle(b), Href: relHref(dir, pages[b.FilePath])})
+		}
+		if err := writeSitePage(opts.OutDir, page, data); err != nil {
+			return err
+		}
+	}
+
+	listing := func(dir string, notes []Note) []pageLink {
+		var links []pageLink
+		for _, n := range notes {
+			links = append(links, pageLink{Title: noteTitle(n), Href: relHref(dir, pages[n.FilePath])})
+		}
+		return links
+	}
+
+	for folder, notes := range folders {
+		page := folder + "/index.html"
+		data := pageData{
+			Title:  folder,
+			Root:   relRoot(folder),
+			Groups: []pageGroup{{Name: "Notes", Pages: listing(folder, notes)}},
+		}
+		if err := writeSitePage(opts.OutDir, page, data); err != nil {
+			return err
+		}
+	}
+
+	for tag, notes := range tags {
+		page := tagPages[tag]
+		data := pageData{
+			Title:  "#" + tag,
+			Root:   relRoot("tags"),
+			Groups: []pageGroup{{Name: "Notes", Pages: listing("tags", notes)}},
+		}
+		if err := writeSitePage(opts.OutDir, page, data); err != nil {
+			return err
+		}
+	}
+
+	home := pageData{Title: "Notes", Root: ""}
+	for _, folder := range sortedKeys(folders) {
+		home.Groups = append(home.Groups, pageGroup{
+			Name:  folder,
+			Href:  folder + "/index.html",
+			Pages: listing(".", folders[folder]),
+		})
+	}
+	if len(tags) > 0 {
+		group := pageGroup{Name: "Tags"}
+		for _, tag := range sortedKeys(tags) {
+			group.Pages = append(group.Pages, pageLink{
+				Title: fmt.Sprintf("#%s (%d)", tag, len(tags[tag])),
+				Href:  tagPages[tag],
+			})
+		}
+		home.Groups = append(home.Groups, group)
+	}
+	if err := writeSitePage(opts.OutDir, "index.html", home); err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(w, "Exported %d notes to %s\n", len(selected), opts.OutDir)
+	return nil
+}
+
+func writeSitePage(outDir, page string, data pageData) error {
+	var buf bytes.Buffer
+	if err := siteTemplate.Execute(&buf, data); err != nil {
+		return fmt.Errorf("rendering %s: %w", page, err)
+	}
+	dest := filepath.Join(outDir, filepath.FromSlash(page))
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return fmt.Errorf("creating directory %s: %w", filepath.Dir(dest), err)
+	}
+	if err := WriteFileAtomic(dest, buf.Bytes(), WriteOptions{}); err != nil {
+		return fmt.Errorf("writing %s: %w", dest, err)
+	}
+	return nil
+}
+
+// hasAllTags reports whether tags contains every tag in want.
+func hasAllTags(tags, want []string) bool {
+	for _, w := range want {
+		found := false
+		for _, t := range tags {
+			if strings.EqualFold(t, w) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// noteTitle returns the note's title, falling back to its filename.
+func noteTitle(n Note) string {
+	if n.Frontmatter.Title != "" {
+		return n.Frontmatter.Title
+	}
+	return strings.TrimSuffix(filepath.Base(n.FilePath), ".md")
+}
+
+// stripTitleHeading drops a leading "# Title" heading from body, since the
+// page already shows the title.
+func stripTitleHeading(body, title string) string {
+	trimmed := strings.TrimLeft(body, "\n")
+	first, rest, _ := strings.Cut(trimmed, "\n")
+	if strings.TrimSpace(strings.TrimPrefix(first, "# ")) == title && strings.HasPrefix(first, "# ") {
+		return rest
+	}
+	return body
+}
+
+// tagPageNames returns the page of each of tags, sorted, under tags/.
+// Pages are named by the tag's slug; tags whose slug is empty, such as
+// ones written entirely in other scripts, are named "tag", and a tag whose
+// name is taken by an earlier one gets a -2, -3, ... suffix, so "c++" and
+// "c" never share a page.
+func tagPageNames(tags []string) map[string]string {
+	pages := make(map[string]string, len(tags))
+	taken := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		base := Slugify(tag)
+		if base == "" {
+			base = "tag"
+		}
+		name := base
+		for i := 2; taken[name]; i++ {
+			name = fmt.Sprintf("%s-%d", base, i)
+		}
+		taken[name] = true
+		pages[tag] = "tags/" + name + ".html"
+	}
+	return pages
+}
+
+// relHref returns the link from a page in fromDir to target, both relative
+// to the site root.
+func relHref(fromDir, target string) string {
+	if rest, ok := strings.CutPrefix(target, fromDir+"/"); ok {
+		return rest
+	}
+	return relRoot(fromDir) + target
+}
+
+// relRoot returns the prefix leading from dir back to the site root.
+func relRoot(dir string) string {
+	if dir == "." || dir == "" {
+		return ""
+	}
+	return strings.Repeat("../", strings.Count(dir, "/")+1)
+}
+
+func sortedKeys(m map[string][]Note) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,112 @@
+package internal
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExportHTML(t *testing.T) {
+	dir := setupListDir(t)
+	writeNote := func(rel, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, rel), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeNote("Resources/go-tips.md", `---
+title: "Go Tips"
+date: 2026-02-13
+tags: [go, public]
+---
+
+# Go Tips
+
+Read [[Python Basics]] and [[Private Plans]].
+
+- [x] learn channels
+`)
+	writeNote("Inbox/2026-02-12-python-basics.md", `---
+title: "Python Basics"
+date: 2026-02-12
+tags: [python, public]
+---
+
+Compare with [[Go Tips]].
+`)
+	writeNote("Projects/2026-02-11-private-plans.md", `---
+title: "Private Plans"
+tags: [project]
+---
+
+Secret [[Go Tips]] notes.
+`)
+
+	out := filepath.Join(t.TempDir(), "site")
+	var buf bytes.Buffer
+	if err := ExportHTML(&buf, dir, HTMLExportOptions{OutDir: out, Tags: []string{"public"}}); err != nil {
+		t.Fatalf("ExportHTML() error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Exported 2 notes") {
+		t.Errorf("unexpected summary: %s", buf.String())
+	}
+
+	read := func(rel string) string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(out, rel))
+		if err != nil {
+			t.Fatalf("reading %s: %v", rel, err)
+		}
+		return string(data)
+	}
+
+	page := read("Resources/go-tips.html")
+	for _, want := range []string{
+		`<a class="wikilink" href="../Inbox/2026-02-12-python-basics.html">Python Basics</a>`,
+		`<span class="wikilink missing">Private Plans</span>`,
+		`<input type="checkbox" disabled checked> learn channels`,
+		`<h2>Backlinks</h2>`,
+		`href="../tags/public.html"`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("go-tips.html missing %q:\n%s", want, page)
+		}
+	}
+	if strings.Count(page, "Go Tips</h1>") != 1 {
+		t.Errorf("title heading should appear once:\n%s", page)
+	}
+	if strings.Contains(page, "Private Plans</a>") {
+		t.Errorf("unexported note leaked into backlinks:\n%s", page)
+	}
+
+	if _, err := os.Stat(filepath.Join(out, "Projects")); !os.IsNotExist(err) {
+		t.Errorf("Projects should not be exported, stat error: %v", err)
+	}
+	if tag := read("tags/public.html"); !strings.Contains(tag, `href="../Resources/go-tips.html"`) {
+		t.Errorf("tag page missing note link:\n%s", tag)
+	}
+	if folder := read("Inbox/index.html"); !strings.Contains(folder, `href="2026-02-12-python-basics.html"`) {
+		t.Errorf("folder index missing note link:\n%s", folder)
+	}
+	if index := read("index.html"); !strings.Contains(index, `href="tags/go.html"`) {
+		t.Errorf("site index missing tag link:\n%s", index)
+	}
+}
+
+func TestTagPageNames(t *testing.T) {
+	got := tagPageNames([]string{"c", "c++", "go", "日本語", "한국어"})
+	want := map[string]string{
+		"c":   "tags/c.html",
+		"c++": "tags/c-2.html",
+		"go":  "tags/go.html",
+		"日本語": "tags/tag.html",
+		"한국어": "tags/tag-2.html",
+	}
+	for tag, page := range want {
+		if got[tag] != page {
+			t.Errorf("page of %q = %q, want %q", tag, got[tag], page)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Default limits for URLFetcher.
+const (
+	DefaultFetchTimeout  = 10 * time.Second
+	DefaultFetchMaxBytes = 1 << 20
+)
+
+// PageMeta is the metadata URLFetcher reads from a web page.
+type PageMeta struct {
+	Title       string
+	Description string
+	// Canonical is the page's canonical URL, resolved against the URL it
+	// was fetched from. It is empty when the page does not declare one.
+	Canonical string
+}
+
+// URLFetcher retrieves titles, descriptions and canonical URLs for
+// Resources notes. The zero value is ready to use with http.DefaultClient
+// and the default limits.
+type URLFetcher struct {
+	// Client performs the requests. Tests can point it at an httptest server.
+	Client *http.Client
+	// Timeout bounds each fetch, including reading the body.
+	Timeout time.Duration
+	// MaxBytes bounds how much of each page is read.
+	MaxBytes int64
+}
+
+// Fetch downloads rawURL and extracts its metadata from the page head.
+func (f *URLFetcher) Fetch(ctx context.Context, rawURL string) (PageMeta, error) {
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	timeout := f.Timeout
+	if timeout <= 0 {
+		timeout = DefaultFetchTimeout
+	}
+	maxBytes := f.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultFetchMaxBytes
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return PageMeta{}, err
+	}
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	req.Header.Set("User-Agent", "qn (quick-note)")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return PageMeta{}, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return PageMeta{}, fmt.Errorf("fetching %s: %s", rawURL, resp.Status)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "" {
+		mediaType, _, _ := mime.ParseMediaType(ct)
+		if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+			return PageMeta{}, fmt.Errorf("fetching %s: not an HTML page (%s)", rawURL, mediaType)
+		}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes))
+	if err != nil {
+		return PageMeta{}, fmt.Errorf("fetching %s: %w", rawURL, err)
+	}
+
+	meta := parsePageMeta(string(data))
+	if meta.Canonical != "" {
+		meta.Canonical = resolveURL(resp.Request.URL, meta.Canonical)
+	}
+	return meta, nil
+}
+
+// parsePageMeta reads the title, description and canonical link from the
+// head of an HTML document. Open Graph values fill in for missing ones.
+func parsePageMeta(doc string) PageMeta {
+	var meta PageMeta
+	var ogTitle, ogDesc, ogURL string
+
+	tags := htmlTagPattern.FindAllStringSubmatchIndex(doc, -1)
+scan:
+	for i, m := range tags {
+		closing := doc[m[2]:m[3]] == "/"
+		name := strings.ToLower(doc[m[4]:m[5]])
+		if closing {
+			if name == "head" {
+				break scan
+			}
+			continue
+		}
+		attrs := parseHTMLAttrs(doc[m[6]:m[7]])
+
+		switch name {
+		case "body":
+			break scan
+		case "title":
+			if meta.Title == "" && i+1 < len(tags) {
+				meta.Title = collapseSpace(html.UnescapeString(doc[m[1]:tags[i+1][0]]))
+			}
+		case "meta":
+			content := collapseSpace(attrs["CONTENT"])
+			switch strings.ToLower(attrs["NAME"] + attrs["PROPERTY"]) {
+			case "description":
+				meta.Description = content
+			case "og:title":
+				ogTitle = content
+			case "og:description":
+				ogDesc = content
+			case "og:url":
+				ogURL = strings.TrimSpace(attrs["CONTENT"])
+			}
+		case "link":
+			for _, rel := range strings.Fields(strings.ToLower(attrs["REL"])) {
+				if rel == "canonical" {
+					meta.Canonical = strings.TrimSpace(attrs["HREF"])
+				}
+			}
+		}
+	}
+
+	if meta.Title == "" {
+		meta.Title = ogTitle
+	}
+	if meta.Description == "" {
+		meta.Description = ogDesc
+	}
+	if meta.Canonical == "" {
+		meta.Canonical = ogURL
+	}
+	return meta
+}
+
+// FormatReference renders a References entry for u, using its fetched
+// metadata when available: "[Title](url) — description".
+func FormatReference(u string, meta PageMeta) string {
+	if meta.Canonical != "" {
+		u = meta.Canonical
+	}
+	if meta.Title == "" {
+		return u
+	}
+	ref := "[" + strings.ReplaceAll(meta.Title, "]", "\\]") + "](" + u + ")"
+	if meta.Description != "" {
+		ref += " — " + meta.Description
+	}
+	return ref
+}
+
+// resolveURL resolves ref against base, returning ref unchanged if either
+// cannot be parsed.
+func resolveURL(base *url.URL, ref string) string {
+	r, err := url.Parse(ref)
+	if err != nil || base == nil {
+		return ref
+	}
+	return base.ResolveReference(r).String()
+}
+
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
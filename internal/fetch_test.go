@@ -0,0 +1,154 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testPage = `<!DOCTYPE html>
+<html><head>
+<meta charset="utf-8">
+<title>
+  Effective Go &amp; You
+</title>
+<meta name="description" content="Tips for writing clear, idiomatic Go.">
+<link rel="canonical" href="/doc/effective_go">
+</head>
+<body><title>not this</title></body></html>`
+
+func newTestSite(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, testPage)
+	})
+	mux.HandleFunc("/og", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<head><meta property="og:title" content="OG Title"><meta property="og:description" content="OG desc"></head>`)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+	})
+	mux.HandleFunc("/big", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<head>"+strings.Repeat(" ", 4096)+"<title>Too Far</title></head>")
+	})
+	mux.HandleFunc("/pdf", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestURLFetcher(t *testing.T) {
+	srv := newTestSite(t)
+	f := &URLFetcher{Client: srv.Client(), Timeout: 200 * time.Millisecond, MaxBytes: 1024}
+	ctx := context.Background()
+
+	meta, err := f.Fetch(ctx, srv.URL+"/page")
+	if err != nil {
+		t.Fatalf("Fetch(/page) error: %v", err)
+	}
+	want := PageMeta{
+		Title:       "Effective Go & You",
+		Description: "Tips for writing clear, idiomatic Go.",
+		Canonical:   srv.URL + "/doc/effective_go",
+	}
+	if meta != want {
+		t.Errorf("Fetch(/page) = %+v, want %+v", meta, want)
+	}
+
+	meta, err = f.Fetch(ctx, srv.URL+"/og")
+	if err != nil {
+		t.Fatalf("Fetch(/og) error: %v", err)
+	}
+	if meta.Title != "OG Title" || meta.Description != "OG desc" {
+		t.Errorf("Fetch(/og) = %+v, want Open Graph values", meta)
+	}
+
+	meta, err = f.Fetch(ctx, srv.URL+"/big")
+	if err != nil {
+		t.Fatalf("Fetch(/big) error: %v", err)
+	}
+	if meta.Title != "" {
+		t.Errorf("Fetch(/big) read past MaxBytes: %+v", meta)
+	}
+
+	if _, err := f.Fetch(ctx, srv.URL+"/slow"); err == nil {
+		t.Error("Fetch(/slow) should time out")
+	}
+	if _, err := f.Fetch(ctx, srv.URL+"/pdf"); err == nil {
+		t.Error("Fetch(/pdf) should reject non-HTML content")
+	}
+	if _, err := f.Fetch(ctx, srv.URL+"/missing"); err == nil {
+		t.Error("Fetch(/missing) should fail on 404")
+	}
+}
+
+func TestFormatReference(t *testing.T) {
+	tests := []struct {
+		name string
+		meta PageMeta
+		want string
+	}{
+		{"bare", PageMeta{}, "https://a.example/x"},
+		{"title only", PageMeta{Title: "A [draft]"}, "[A [draft\\]](https://a.example/x)"},
+		{"full", PageMeta{Title: "A", Description: "About A", Canonical: "https://a.example/"}, "[A](https://a.example/) — About A"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatReference("https://a.example/x", tt.meta); got != tt.want {
+				t.Errorf("FormatReference() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateResourceWithFetcher(t *testing.T) {
+	dir := setupTestNotesDir(t)
+	srv := newTestSite(t)
+	t.Setenv("EDITOR", "")
+
+	// Blank title, Resources, no tags or body, two URLs, accept suggested title.
+	input := "\n4\n\n\n" + srv.URL + "/page, " + srv.URL + "/pdf\n\n"
+	w := &bytes.Buffer{}
+	p := NewPrompter(strings.NewReader(input), w)
+	opts := CreateOptions{Fetcher: &URLFetcher{Client: srv.Client()}}
+	if err := Create(p, dir, opts); err != nil {
+		t.Fatalf("Create() error: %v", err)
+	}
+
+	if !strings.Contains(w.String(), "Title [Effective Go & You]: ") {
+		t.Errorf("expected suggested title prompt, got: %s", w.String())
+	}
+	if !strings.Contains(w.String(), "Warning: could not fetch "+srv.URL+"/pdf") {
+		t.Errorf("expected fetch warning, got: %s", w.String())
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "Resources", "effective-go-you.md"))
+	if err != nil {
+		t.Fatalf("expected note named after page title: %v", err)
+	}
+	content := string(data)
+	for _, want := range []string{
+		`title: "Effective Go & You"`,
+		"- [Effective Go & You](" + srv.URL + "/doc/effective_go) — Tips for writing clear, idiomatic Go.",
+		"- " + srv.URL + "/pdf",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("note missing %q:\n%s", want, content)
+		}
+	}
+}
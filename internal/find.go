@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"sort"
@@ -12,25 +13,51 @@ type searchResult struct {
 	Score int
 }
 
+// FindOptions configures Find.
+type FindOptions struct {
+	// Strict fails with a *ProblemError when any note has a problem
+	// Doctor would report.
+	Strict bool
+	// Format is OutputText (the default) or OutputJSON, which writes the
+	// results as a JSON array of SearchHit.
+	Format string
+}
+
 // Find searches for notes matching the given query and displays results.
-func Find(w io.Writer, baseDir, query string) error {
+func Find(w io.Writer, baseDir, query string, opts FindOptions) error {
 	if query == "" {
 		return fmt.Errorf("search query is required")
 	}
 
-	notes, err := ScanNotes(baseDir)
+	format, err := outputFormat(opts.Format)
 	if err != nil {
 		return err
 	}
 
-	q := strings.ToLower(query)
-	var results []searchResult
+	scan := ScanNotes
+	if opts.Strict {
+		scan = scanStrict
+	}
+	notes, err := scan(baseDir)
+	if err != nil {
+		return err
+	}
 
-	for _, note := range notes {
-		score := scoreNote(note, q)
-		if score > 0 {
-			results = append(results, searchResult{Note: note, Score: score})
+	q := strings.ToLower(query)
+	results := searchNotes(notes, q)
+
+	if format == OutputJSON {
+		hits := []SearchHit{}
+		for _, r := range results {
+			hits = append(hits, SearchHit{
+				NoteSummary: newNoteSummary(baseDir, r.Note),
+				Score:       r.Score,
+				Excerpt:     findExcerpt(r.Note.Body, q),
+			})
 		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(hits)
 	}
 
 	if len(results) == 0 {
@@ -38,11 +65,6 @@ func Find(w io.Writer, baseDir, query string) error {
 		return nil
 	}
 
-	// Sort by score descending
-	sort.Slice(results, func(i, j int) bool {
-		return results[i].Score > results[j].Score
-	})
-
 	for _, r := range results {
 		title := r.Note.Frontmatter.Title
 		if title == "" {
@@ -64,6 +86,24 @@ func Find(w io.Writer, baseDir, query string) error {
 	return nil
 }
 
+// searchNotes returns the notes matching the lowercased query q, best
+// match first.
+func searchNotes(notes []Note, q string) []searchResult {
+	var results []searchResult
+	for _, note := range notes {
+		score := scoreNote(note, q)
+		if score > 0 {
+			results = append(results, searchResult{Note: note, Score: score})
+		}
+	}
+
+	// Sort by score descending
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	return results
+}
+
 func scoreNote(note Note, query string) int {
 	score := 0
 
@@ -2,6 +2,7 @@ package internal
 
 import (
 	"bytes"
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"strings"
@@ -12,7 +13,7 @@ func TestFindByTitle(t *testing.T) {
 	dir := setupFindTestDir(t)
 
 	var buf bytes.Buffer
-	err := Find(&buf, dir, "Golang")
+	err := Find(&buf, dir, "Golang", FindOptions{})
 	if err != nil {
 		t.Fatalf("Find() error: %v", err)
 	}
@@ -27,7 +28,7 @@ func TestFindByTag(t *testing.T) {
 	dir := setupFindTestDir(t)
 
 	var buf bytes.Buffer
-	err := Find(&buf, dir, "python")
+	err := Find(&buf, dir, "python", FindOptions{})
 	if err != nil {
 		t.Fatalf("Find() error: %v", err)
 	}
@@ -42,7 +43,7 @@ func TestFindByContent(t *testing.T) {
 	dir := setupFindTestDir(t)
 
 	var buf bytes.Buffer
-	err := Find(&buf, dir, "concurrency")
+	err := Find(&buf, dir, "concurrency", FindOptions{})
 	if err != nil {
 		t.Fatalf("Find() error: %v", err)
 	}
@@ -57,7 +58,7 @@ func TestFindNoResults(t *testing.T) {
 	dir := setupFindTestDir(t)
 
 	var buf bytes.Buffer
-	err := Find(&buf, dir, "nonexistent-topic-xyz")
+	err := Find(&buf, dir, "nonexistent-topic-xyz", FindOptions{})
 	if err != nil {
 		t.Fatalf("Find() error: %v", err)
 	}
@@ -72,7 +73,7 @@ func TestFindEmptyQuery(t *testing.T) {
 	dir := setupFindTestDir(t)
 
 	var buf bytes.Buffer
-	err := Find(&buf, dir, "")
+	err := Find(&buf, dir, "", FindOptions{})
 	if err == nil {
 		t.Error("Find() should error on empty query")
 	}
@@ -82,7 +83,7 @@ func TestFindRanking(t *testing.T) {
 	dir := setupFindTestDir(t)
 
 	var buf bytes.Buffer
-	err := Find(&buf, dir, "go")
+	err := Find(&buf, dir, "go", FindOptions{})
 	if err != nil {
 		t.Fatalf("Find() error: %v", err)
 	}
@@ -94,6 +95,33 @@ func TestFindRanking(t *testing.T) {
 	}
 }
 
+func TestFindJSON(t *testing.T) {
+	dir := setupFindTestDir(t)
+
+	var buf bytes.Buffer
+	if err := Find(&buf, dir, "concurrency", FindOptions{Format: OutputJSON}); err != nil {
+		t.Fatalf("Find() error: %v", err)
+	}
+	var hits []SearchHit
+	if err := json.Unmarshal(buf.Bytes(), &hits); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, buf.String())
+	}
+	if len(hits) != 1 || hits[0].Title != "Golang Tips" || hits[0].Path != "Resources/golang-tips.md" || hits[0].Score == 0 {
+		t.Fatalf("unexpected hits: %+v", hits)
+	}
+	if !strings.Contains(hits[0].Excerpt, "concurrency") {
+		t.Errorf("expected an excerpt around the match, got %q", hits[0].Excerpt)
+	}
+
+	buf.Reset()
+	if err := Find(&buf, dir, "nothing-matches", FindOptions{Format: OutputJSON}); err != nil {
+		t.Fatalf("Find() error: %v", err)
+	}
+	if strings.TrimSpace(buf.String()) != "[]" {
+		t.Errorf("expected an empty JSON array, got: %s", buf.String())
+	}
+}
+
 func setupFindTestDir(t *testing.T) string {
 	t.Helper()
 	dir := t.TempDir()
@@ -0,0 +1,128 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// GitAutoCommit reports whether mutating commands should commit their
+// changes, which is enabled by setting MDNOTES_GIT to a true value.
+func GitAutoCommit() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv("MDNOTES_GIT"))
+	return enabled
+}
+
+// GitCommit stages the given files and commits only those files in the git
+// repository containing baseDir. Changes the user already staged for other
+// files are left alone.
+func GitCommit(baseDir, message string, paths ...string) error {
+	rel := make([]string, 0, len(paths))
+	for _, p := range paths {
+		r, err := vaultRelPath(baseDir, p)
+		if err != nil {
+			return err
+		}
+		rel = append(rel, r)
+	}
+
+	addArgs := append([]string{"add", "--all", "--"}, rel...)
+	if _, err := runGit(baseDir, addArgs...); err != nil {
+		return err
+	}
+	commitArgs := append([]string{"commit", "--quiet", "-m", message, "--"}, rel...)
+	_, err := runGit(baseDir, commitArgs...)
+	return err
+}
+
+// autoCommit commits paths when GitAutoCommit is on. A failed commit is
+// reported to w rather than returned, since the note itself was written.
+func autoCommit(w io.Writer, baseDir, message string, paths ...string) {
+	if !GitAutoCommit() {
+		return
+	}
+	if err := GitCommit(baseDir, message, paths...); err != nil {
+		_, _ = fmt.Fprintf(w, "Warning: git commit failed: %v\n", err)
+	}
+}
+
+// History writes the commit log for the note matching query.
+func History(w io.Writer, baseDir, query string) error {
+	note, err := LookupNote(baseDir, query)
+	if err != nil {
+		return err
+	}
+	rel, err := vaultRelPath(baseDir, note.FilePath)
+	if err != nil {
+		return err
+	}
+
+	out, err := runGit(baseDir, "log", "--follow", "--date=short", "--format=%h  %ad  %an  %s", "--", rel)
+	if err != nil {
+		return err
+	}
+	if len(out) == 0 {
+		_, _ = fmt.Fprintf(w, "No history for %s.\n", rel)
+		return nil
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+// Diff writes the changes to the note matching query since rev, which
+// defaults to HEAD. A rev starting with "-" is rejected, since git would
+// read it as an option.
+func Diff(w io.Writer, baseDir, query, rev string) error {
+	if strings.HasPrefix(rev, "-") {
+		return fmt.Errorf("invalid revision %q", rev)
+	}
+	note, err := LookupNote(baseDir, query)
+	if err != nil {
+		return err
+	}
+	rel, err := vaultRelPath(baseDir, note.FilePath)
+	if err != nil {
+		return err
+	}
+	if rev == "" {
+		rev = "HEAD"
+	}
+
+	out, err := runGit(baseDir, "diff", rev, "--", rel)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+// runGit runs git with args in dir and returns its standard output.
+func runGit(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			return nil, fmt.Errorf("git %s: %w", args[0], err)
+		}
+		return nil, fmt.Errorf("git %s: %s", args[0], msg)
+	}
+	return stdout.Bytes(), nil
+}
+
+// vaultRelPath returns path relative to baseDir using forward slashes.
+func vaultRelPath(baseDir, path string) (string, error) {
+	rel, err := filepath.Rel(baseDir, path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
@@ -0,0 +1,106 @@
+package internal
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func setupGitNotesDir(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := setupTestNotesDir(t)
+
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_AUTHOR_NAME", "Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+	if _, err := runGit(dir, "init", "--quiet"); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestCreateGitAutoCommit(t *testing.T) {
+	dir := setupGitNotesDir(t)
+	t.Setenv("EDITOR", "")
+	t.Setenv("MDNOTES_GIT", "1")
+
+	// Something the user staged themselves must not be swept into qn's commit.
+	other := filepath.Join(dir, "Areas", "staged.md")
+	if err := os.WriteFile(other, []byte("staged\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runGit(dir, "add", "Areas/staged.md"); err != nil {
+		t.Fatal(err)
+	}
+
+	w := &bytes.Buffer{}
+	p := NewPrompter(strings.NewReader("Git Note\n3\n\n\n"), w)
+	if err := Create(p, dir, CreateOptions{}); err != nil {
+		t.Fatalf("Create() error: %v", err)
+	}
+	if strings.Contains(w.String(), "Warning") {
+		t.Fatalf("unexpected warning: %s", w.String())
+	}
+
+	out, err := runGit(dir, "show", "--name-only", "--format=%s", "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(out)
+	if !strings.Contains(got, `qn: create "Git Note" in Areas`) {
+		t.Errorf("commit message missing, got:\n%s", got)
+	}
+	if !strings.Contains(got, "Areas/git-note.md") {
+		t.Errorf("expected new note in commit, got:\n%s", got)
+	}
+	if strings.Contains(got, "staged.md") {
+		t.Errorf("commit should not include other staged files, got:\n%s", got)
+	}
+}
+
+func TestHistoryAndDiff(t *testing.T) {
+	dir := setupGitNotesDir(t)
+	path := filepath.Join(dir, "Resources", "go-tips.md")
+
+	if err := os.WriteFile(path, []byte("---\ntitle: \"Go Tips\"\n---\n\nfirst\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := GitCommit(dir, "add go tips", path); err != nil {
+		t.Fatalf("GitCommit() error: %v", err)
+	}
+	if err := os.WriteFile(path, []byte("---\ntitle: \"Go Tips\"\n---\n\nsecond\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := History(&buf, dir, "Go Tips"); err != nil {
+		t.Fatalf("History() error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "add go tips") {
+		t.Errorf("expected commit in history, got: %s", buf.String())
+	}
+
+	buf.Reset()
+	if err := Diff(&buf, dir, "go-tips", ""); err != nil {
+		t.Fatalf("Diff() error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "-first") || !strings.Contains(buf.String(), "+second") {
+		t.Errorf("expected working tree change in diff, got: %s", buf.String())
+	}
+
+	out := filepath.Join(t.TempDir(), "written")
+	if err := Diff(&buf, dir, "go-tips", "--output="+out); err == nil || !strings.Contains(err.Error(), "invalid revision") {
+		t.Errorf("Diff() with an option as rev: error = %v", err)
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Errorf("git wrote %s: %v", out, err)
+	}
+}
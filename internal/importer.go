@@ -0,0 +1,433 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ImportSource names the tool a directory of markdown was exported from.
+type ImportSource string
+
+const (
+	// SourceObsidian reads YAML frontmatter and inline #tags.
+	SourceObsidian ImportSource = "obsidian"
+	// SourceLogseq reads "key:: value" page properties, #tags and
+	// #[[multi word]] tags, and namespaced or journal filenames.
+	SourceLogseq ImportSource = "logseq"
+	// SourcePlain reads any markdown, such as a Bear export, using the
+	// first heading as the title and inline #tags.
+	SourcePlain ImportSource = "plain"
+)
+
+// ParseImportSource converts a --from value into an ImportSource.
+func ParseImportSource(s string) (ImportSource, error) {
+	switch src := ImportSource(strings.ToLower(strings.TrimSpace(s))); src {
+	case SourceObsidian, SourceLogseq, SourcePlain:
+		return src, nil
+	}
+	return "", fmt.Errorf("unknown import source %q (want obsidian, logseq or plain)", s)
+}
+
+// ImportOptions configures the importers.
+type ImportOptions struct {
+	// OnCollision decides what happens when a note's filename is taken.
+	// CollisionSuffix imports under the next free -N name; every other
+	// policy skips the note and reports it as a conflict.
+	OnCollision CollisionPolicy
+	// Folder files every imported note in this folder instead of choosing
+	// one with the folder rules.
+	Folder string
+	// GroupByFolder makes bookmark and OPML imports create one note per
+	// bookmark folder instead of one per bookmark.
+	GroupByFolder bool
+}
+
+// importNote is a note converted from another format, ready to be filed.
+type importNote struct {
+	// Source identifies where the note came from in reports.
+	Source      string
+	Folder      string
+	Frontmatter Frontmatter
+	Body        string
+	ModTime     time.Time
+}
+
+var (
+	inlineTagPattern  = regexp.MustCompile(`(?:^|[\s(])#([\p{L}\p{N}_][\p{L}\p{N}_/-]*)`)
+	bracketTagPattern = regexp.MustCompile(`#\[\[([^\]]+)\]\]`)
+	propertyPattern   = regexp.MustCompile(`^([A-Za-z][\w-]*)::\s*(.*)$`)
+	firstHeading      = regexp.MustCompile(`(?m)^#\s+(.+?)\s*#*\s*$`)
+	journalName       = regexp.MustCompile(`^(\d{4})[_-](\d{2})[_-](\d{2})$`)
+)
+
+// ImportDir walks srcDir for markdown files exported from another tool,
+// maps their titles, tags, aliases and properties onto Frontmatter, picks a
+// folder for each with the folder rules, and files them like Create does.
+// Notes whose filename is already taken are reported instead of being
+// overwritten.
+func ImportDir(w io.Writer, baseDir, srcDir string, source ImportSource, opts ImportOptions) error {
+	var notes []importNote
+	err := filepath.WalkDir(srcDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != srcDir && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || (source == SourceLogseq && name == "logseq")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(strings.ToLower(name), ".md") {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(srcDir, path)
+		if err != nil {
+			return err
+		}
+
+		n := convertNote(filepath.ToSlash(rel), data, source)
+		n.ModTime = info.ModTime()
+		if n.Frontmatter.Date == "" {
+			n.Frontmatter.Date = n.ModTime.Format("2006-01-02")
+		}
+		n.Folder = chooseFolder(n)
+		notes = append(notes, n)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", srcDir, err)
+	}
+
+	return saveImported(w, baseDir, fmt.Sprintf("%s export %s", source, filepath.Base(srcDir)), notes, opts)
+}
+
+// convertNote maps a markdown file from source onto an importNote. rel is
+// the file's path within the export, used for titles, dates and folders.
+func convertNote(rel string, data []byte, source ImportSource) importNote {
+	fm, body, _ := ParseFrontmatterFromBytes(data)
+	stem := strings.TrimSuffix(filepath.Base(rel), filepath.Ext(rel))
+
+	if source == SourceLogseq {
+		var props []Field
+		props, body = splitLogseqProperties(body)
+		for _, p := range props {
+			switch strings.ToLower(p.Key) {
+			case "title":
+				fm.Title = p.Value
+			case "tags":
+				fm.Tags = append(fm.Tags, splitLogseqList(p.Value)...)
+			case "alias", "aliases":
+				fm.Aliases = append(fm.Aliases, splitLogseqList(p.Value)...)
+			case "status":
+				fm.Status = strings.ToLower(p.Value)
+			case "date":
+				fm.Date = p.Value
+			default:
+				fm.Extra = append(fm.Extra, p)
+			}
+		}
+		// Namespaced pages are stored as "a___b.md" or "a%2Fb.md".
+		stem = strings.ReplaceAll(stem, "___", "/")
+		if unescaped, err := url.PathUnescape(stem); err == nil {
+			stem = unescaped
+		}
+		if m := journalName.FindStringSubmatch(stem); m != nil && fm.Date == "" {
+			fm.Date = m[1] + "-" + m[2] + "-" + m[3]
+		}
+	}
+
+	if fm.Title == "" {
+		if m := firstHeading.FindStringSubmatch(body); m != nil {
+			fm.Title = strings.TrimSpace(m[1])
+		} else {
+			fm.Title = stem
+		}
+	}
+	if fm.Date == "" {
+		if len(fm.Created) >= 10 {
+			fm.Date = fm.Created[:10]
+		}
+	}
+	if fm.Status == "" {
+		fm.Status = "draft"
+	}
+
+	tags := append([]string{}, fm.Tags...)
+	tags = append(tags, inlineTags(body)...)
+	fm.Tags = uniqueTags(tags)
+
+	return importNote{Source: rel, Frontmatter: fm, Body: body}
+}
+
+// splitLogseqProperties removes the leading "key:: value" lines of a Logseq
+// page and returns them with the rest of the body.
+func splitLogseqProperties(body string) ([]Field, string) {
+	lines := strings.Split(body, "\n")
+	var props []Field
+	i := 0
+	for ; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" && len(props) == 0 {
+			continue
+		}
+		m := propertyPattern.FindStringSubmatch(strings.TrimPrefix(line, "- "))
+		if m == nil {
+			break
+		}
+		props = append(props, Field{Key: m[1], Value: strings.TrimSpace(m[2])})
+	}
+	if len(props) == 0 {
+		return nil, body
+	}
+	return props, strings.TrimLeft(strings.Join(lines[i:], "\n"), "\n")
+}
+
+// splitLogseqList splits a Logseq property list such as
+// "a, [[b c]], #d" into its items.
+func splitLogseqList(s string) []string {
+	var items []string
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		part = strings.TrimPrefix(part, "#")
+		part = strings.TrimPrefix(part, "[[")
+		part = strings.TrimSuffix(part, "]]")
+		if part != "" {
+			items = append(items, part)
+		}
+	}
+	return items
+}
+
+// inlineTags returns the #tags and #[[multi word]] tags in body, ignoring
+// headings, code fences and inline code.
+func inlineTags(body string) []string {
+	var tags []string
+	inFence := false
+	for _, line := range strings.Split(body, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		line = stripInlineCode(line)
+		for _, m := range bracketTagPattern.FindAllStringSubmatch(line, -1) {
+			tags = append(tags, m[1])
+		}
+		line = bracketTagPattern.ReplaceAllString(line, "")
+		for _, m := range inlineTagPattern.FindAllStringSubmatch(line, -1) {
+			// Skip issue numbers and colours like #42 or #fff000.
+			if strings.IndexFunc(m[1], isLetter) >= 0 && !isHexColour(m[1]) {
+				tags = append(tags, m[1])
+			}
+		}
+	}
+	return tags
+}
+
+// uniqueTags normalizes tags and drops duplicates, keeping first-seen order.
+func uniqueTags(tags []string) []string {
+	seen := make(map[string]bool)
+	var result []string
+	for _, t := range tags {
+		for _, n := range NormalizeTags(strings.ReplaceAll(strings.TrimPrefix(t, "#"), "/", "-")) {
+			if !seen[n] {
+				seen[n] = true
+				result = append(result, n)
+			}
+		}
+	}
+	return result
+}
+
+// chooseFolder picks a PARA folder for an imported note. Rules are tried in
+// order:
+//
+//  1. a top-level directory in the export named after a folder, such as
+//     "Projects" or "1 Projects", files the note there;
+//  2. a "project" tag files it in Projects;
+//  3. a "resource" or "reference" tag, or a url/source field, files it in
+//     Resources;
+//  4. an "area" tag files it in Areas;
+//  5. everything else goes to the Inbox.
+func chooseFolder(n importNote) string {
+	if top, _, ok := strings.Cut(n.Source, "/"); ok {
+		name := strings.TrimLeft(top, "0123456789 ._-")
+		for _, f := range NoteFolders {
+			if strings.EqualFold(name, f) {
+				return f
+			}
+		}
+	}
+
+	has := func(tag string) bool {
+		for _, t := range n.Frontmatter.Tags {
+			if t == tag || t == tag+"s" {
+				return true
+			}
+		}
+		return false
+	}
+	switch {
+	case has("project"):
+		return "Projects"
+	case has("resource") || has("reference"):
+		return "Resources"
+	}
+	if _, ok := extraValue(n.Frontmatter, "url"); ok {
+		return "Resources"
+	}
+	if _, ok := extraValue(n.Frontmatter, "source"); ok {
+		return "Resources"
+	}
+	if has("area") {
+		return "Areas"
+	}
+	return "Inbox"
+}
+
+// saveImported files converted notes with the same filename rules as
+// Create, reports conflicts and commits the new files as one change.
+func saveImported(w io.Writer, baseDir, what string, notes []importNote, opts ImportOptions) error {
+	if opts.Folder != "" && !isNoteFolder(opts.Folder) {
+		return fmt.Errorf("unknown folder %q", opts.Folder)
+	}
+
+	var created []string
+	conflicts := 0
+	for _, n := range notes {
+		fm := n.Frontmatter
+		if fm.Title == "" {
+			fm.Title = NoteSlug(n.Source)
+		}
+		folder := n.Folder
+		if opts.Folder != "" {
+			folder = opts.Folder
+		}
+		if !isNoteFolder(folder) {
+			folder = "Inbox"
+		}
+		if folder == "Projects" && !containsTag(fm.Tags, "project") {
+			fm.Tags = append(fm.Tags, "project")
+		}
+		stamp := time.Now()
+		if !n.ModTime.IsZero() {
+			stamp = n.ModTime
+		}
+		if fm.Created == "" {
+			fm.Created = timestamp(stamp)
+		}
+		if fm.Updated == "" {
+			fm.Updated = timestamp(stamp)
+		}
+
+		path := filepath.Join(baseDir, folder, NoteFilename(folder, fm.Title, noteDate(fm.Date)))
+		saved, err := saveNote(path, []byte(FormatFrontmatter(fm)+n.Body), opts.OnCollision)
+		if errors.Is(err, ErrNoteExists) {
+			_, _ = fmt.Fprintf(w, "Conflict: %s: %s already exists\n", n.Source, path)
+			conflicts++
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !n.ModTime.IsZero() {
+			_ = os.Chtimes(saved, n.ModTime, n.ModTime)
+		}
+		created = append(created, saved)
+	}
+
+	_, _ = fmt.Fprintf(w, "Imported %d notes, %d conflicts.\n", len(created), conflicts)
+	if len(created) > 0 {
+		autoCommit(w, baseDir, fmt.Sprintf("qn: import %d notes from %s", len(created), what), created...)
+	}
+	return nil
+}
+
+// isNoteFolder reports whether name is one of NoteFolders.
+func isNoteFolder(name string) bool {
+	for _, f := range NoteFolders {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// noteDate returns date if it is a YYYY-MM-DD date, or today otherwise.
+func noteDate(date string) string {
+	date = strings.TrimSpace(date)
+	if _, err := time.Parse("2006-01-02", date); err == nil {
+		return date
+	}
+	return time.Now().Format("2006-01-02")
+}
+
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func extraValue(fm Frontmatter, key string) (string, bool) {
+	for _, f := range fm.Extra {
+		if strings.EqualFold(f.Key, key) {
+			return unquote(strings.TrimSpace(f.Value)), true
+		}
+	}
+	return "", false
+}
+
+// stripInlineCode blanks out `code spans` so their contents are ignored.
+func stripInlineCode(line string) string {
+	for {
+		start := strings.Index(line, "`")
+		if start < 0 {
+			return line
+		}
+		end := strings.Index(line[start+1:], "`")
+		if end < 0 {
+			return line
+		}
+		line = line[:start] + line[start+1+end+1:]
+	}
+}
+
+func isLetter(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r > 127
+}
+
+func isHexColour(s string) bool {
+	if len(s) != 3 && len(s) != 6 {
+		return false
+	}
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,183 @@
+package internal
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for rel, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestConvertNote(t *testing.T) {
+	tests := []struct {
+		name        string
+		source      ImportSource
+		rel         string
+		input       string
+		wantTitle   string
+		wantDate    string
+		wantTags    []string
+		wantAliases []string
+		wantBody    string
+	}{
+		{
+			name:   "obsidian frontmatter and inline tags",
+			source: SourceObsidian,
+			rel:    "Ideas/Garden.md",
+			input: `---
+tags:
+  - Gardening
+aliases: [Yard]
+created: 2025-04-01T09:30
+---
+# Spring Garden
+
+Plant #tomatoes and #herbs/basil, not #42 or #fff.
+` + "`#not-a-tag`\n```\n#also-not\n```\n",
+			wantTitle:   "Spring Garden",
+			wantDate:    "2025-04-01",
+			wantTags:    []string{"gardening", "tomatoes", "herbs-basil"},
+			wantAliases: []string{"Yard"},
+		},
+		{
+			name:   "logseq properties",
+			source: SourceLogseq,
+			rel:    "pages/tools___go.md",
+			input: `title:: Go Tooling
+tags:: golang, [[dev tools]]
+alias:: gotools
+source:: https://go.dev
+
+- Uses #[[static analysis]] daily
+`,
+			wantTitle:   "Go Tooling",
+			wantTags:    []string{"golang", "dev-tools", "static-analysis"},
+			wantAliases: []string{"gotools"},
+			wantBody:    "- Uses #[[static analysis]] daily\n",
+		},
+		{
+			name:      "logseq journal and namespaced title",
+			source:    SourceLogseq,
+			rel:       "journals/2026_02_13.md",
+			input:     "- met with #team\n",
+			wantTitle: "2026_02_13",
+			wantDate:  "2026-02-13",
+			wantTags:  []string{"team"},
+		},
+		{
+			name:      "plain falls back to filename",
+			source:    SourcePlain,
+			rel:       "Shopping list.md",
+			input:     "milk, eggs #errands\n",
+			wantTitle: "Shopping list",
+			wantTags:  []string{"errands"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := convertNote(tt.rel, []byte(tt.input), tt.source)
+			fm := n.Frontmatter
+			if fm.Title != tt.wantTitle {
+				t.Errorf("Title = %q, want %q", fm.Title, tt.wantTitle)
+			}
+			if fm.Date != tt.wantDate {
+				t.Errorf("Date = %q, want %q", fm.Date, tt.wantDate)
+			}
+			if !sliceEqual(fm.Tags, tt.wantTags) {
+				t.Errorf("Tags = %v, want %v", fm.Tags, tt.wantTags)
+			}
+			if !sliceEqual(fm.Aliases, tt.wantAliases) {
+				t.Errorf("Aliases = %v, want %v", fm.Aliases, tt.wantAliases)
+			}
+			if tt.wantBody != "" && n.Body != tt.wantBody {
+				t.Errorf("Body = %q, want %q", n.Body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestChooseFolder(t *testing.T) {
+	tests := []struct {
+		name string
+		note importNote
+		want string
+	}{
+		{"numbered PARA directory", importNote{Source: "1 Projects/launch.md"}, "Projects"},
+		{"archive directory", importNote{Source: "archive/old.md"}, "Archive"},
+		{"project tag", importNote{Source: "launch.md", Frontmatter: Frontmatter{Tags: []string{"project"}}}, "Projects"},
+		{"reference tag", importNote{Source: "x/y.md", Frontmatter: Frontmatter{Tags: []string{"references"}}}, "Resources"},
+		{"url field", importNote{Source: "clip.md", Frontmatter: Frontmatter{Extra: []Field{{Key: "url", Value: "https://x"}}}}, "Resources"},
+		{"area tag", importNote{Source: "health.md", Frontmatter: Frontmatter{Tags: []string{"area"}}}, "Areas"},
+		{"default", importNote{Source: "misc/thing.md"}, "Inbox"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := chooseFolder(tt.note); got != tt.want {
+				t.Errorf("chooseFolder() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportDir(t *testing.T) {
+	src := t.TempDir()
+	writeFiles(t, src, map[string]string{
+		"Projects/Launch.md":     "# Website Launch\n\n- [ ] ship #web\n",
+		"Resources/Go Docs.md":   "---\ntitle: Go Docs\ndate: 2026-01-02\n---\nhttps://go.dev\n",
+		".obsidian/workspace.md": "ignored",
+		"_templates/basic.md":    "ignored",
+		"Inbox/Existing.md":      "# Existing\n",
+		"Inbox/attachment.png":   "ignored",
+	})
+
+	dst := setupListDir(t)
+	writeFiles(t, dst, map[string]string{"Resources/go-docs.md": "original\n"})
+
+	var log bytes.Buffer
+	if err := ImportDir(&log, dst, src, SourceObsidian, ImportOptions{}); err != nil {
+		t.Fatalf("ImportDir() error: %v", err)
+	}
+	if !strings.Contains(log.String(), "Imported 2 notes, 1 conflicts.") {
+		t.Errorf("unexpected summary: %s", log.String())
+	}
+	if !strings.Contains(log.String(), "Conflict: Resources/Go Docs.md") {
+		t.Errorf("expected conflict report, got: %s", log.String())
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dst, "Resources", "go-docs.md"))
+	if string(data) != "original\n" {
+		t.Errorf("existing note was overwritten: %q", data)
+	}
+
+	projects, _ := filepath.Glob(filepath.Join(dst, "Projects", "*-website-launch.md"))
+	if len(projects) != 1 {
+		t.Fatalf("expected imported project, got %v", projects)
+	}
+	fm, _, _ := ParseFrontmatter(projects[0])
+	if !sliceEqual(fm.Tags, []string{"web", "project"}) {
+		t.Errorf("project tags = %v, want [web project]", fm.Tags)
+	}
+
+	notes, _ := ScanNotes(dst)
+	if len(notes) != 3 {
+		t.Errorf("expected 3 notes after import, got %d", len(notes))
+	}
+
+	if err := ImportDir(&log, dst, src, SourceObsidian, ImportOptions{Folder: "Nowhere"}); err == nil {
+		t.Error("ImportDir() should reject an unknown folder")
+	}
+}
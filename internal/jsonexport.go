@@ -0,0 +1,164 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// vaultFormatVersion is bumped when the JSON document layout changes.
+const vaultFormatVersion = 1
+
+// VaultExport is the JSON document written by ExportJSON and read by
+// ImportJSON.
+type VaultExport struct {
+	Version  int          `json:"version"`
+	Exported time.Time    `json:"exported"`
+	Notes    []NoteRecord `json:"notes"`
+}
+
+// NoteRecord is the JSON form of a single note.
+type NoteRecord struct {
+	// Path is relative to the notes directory, using forward slashes.
+	Path        string            `json:"path"`
+	Folder      string            `json:"folder"`
+	ModTime     time.Time         `json:"mtime"`
+	Frontmatter FrontmatterRecord `json:"frontmatter"`
+	Body        string            `json:"body"`
+	Links       []LinkRecord      `json:"links,omitempty"`
+}
+
+// FrontmatterRecord is the JSON form of Frontmatter. Fields qn does not
+// interpret are kept in Extra with their raw YAML values.
+type FrontmatterRecord struct {
+	Title   string            `json:"title"`
+	Date    string            `json:"date,omitempty"`
+	Tags    []string          `json:"tags,omitempty"`
+	Status  string            `json:"status,omitempty"`
+	Aliases []string          `json:"aliases,omitempty"`
+	Created string            `json:"created,omitempty"`
+	Updated string            `json:"updated,omitempty"`
+	Extra   map[string]string `json:"extra,omitempty"`
+}
+
+// LinkRecord is a wikilink and, when it resolves, the path of its target.
+type LinkRecord struct {
+	Target string `json:"target"`
+	Path   string `json:"path,omitempty"`
+}
+
+// NewNoteRecord converts a note to its JSON form. Links are resolved with
+// index when it is not nil.
+func NewNoteRecord(baseDir string, note Note, index *LinkIndex) NoteRecord {
+	rel, err := vaultRelPath(baseDir, note.FilePath)
+	if err != nil {
+		rel = filepath.ToSlash(note.FilePath)
+	}
+
+	fm := note.Frontmatter
+	rec := NoteRecord{
+		Path:    rel,
+		Folder:  note.Folder,
+		ModTime: note.ModTime.UTC(),
+		Frontmatter: FrontmatterRecord{
+			Title:   fm.Title,
+			Date:    fm.Date,
+			Tags:    fm.Tags,
+			Status:  fm.Status,
+			Aliases: fm.Aliases,
+			Created: fm.Created,
+			Updated: fm.Updated,
+		},
+		Body: note.Body,
+	}
+	if len(fm.Extra) > 0 {
+		rec.Frontmatter.Extra = make(map[string]string, len(fm.Extra))
+		for _, f := range fm.Extra {
+			rec.Frontmatter.Extra[f.Key] = f.Value
+		}
+	}
+
+	for _, l := range ExtractLinks(note.Body) {
+		link := LinkRecord{Target: l.Target}
+		if index != nil {
+			if target, ok := index.Resolve(l.Target); ok {
+				link.Path, _ = vaultRelPath(baseDir, target.FilePath)
+			}
+		}
+		rec.Links = append(rec.Links, link)
+	}
+	return rec
+}
+
+// Frontmatter converts the record back to a Frontmatter. Extra fields are
+// ordered by key, since JSON objects carry no order.
+func (r FrontmatterRecord) Frontmatter() Frontmatter {
+	fm := Frontmatter{
+		Title:   r.Title,
+		Date:    r.Date,
+		Tags:    r.Tags,
+		Status:  r.Status,
+		Aliases: r.Aliases,
+		Created: r.Created,
+		Updated: r.Updated,
+	}
+	keys := make([]string, 0, len(r.Extra))
+	for k := range r.Extra {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fm.Extra = append(fm.Extra, Field{Key: k, Value: r.Extra[k]})
+	}
+	return fm
+}
+
+// ExportJSON writes every note in the vault to w as a single JSON document.
+func ExportJSON(w io.Writer, baseDir string) error {
+	notes, err := ScanNotes(baseDir)
+	if err != nil {
+		return err
+	}
+	index := NewLinkIndex(notes)
+
+	doc := VaultExport{
+		Version:  vaultFormatVersion,
+		Exported: time.Now().UTC().Truncate(time.Second),
+		Notes:    make([]NoteRecord, 0, len(notes)),
+	}
+	for _, n := range notes {
+		doc.Notes = append(doc.Notes, NewNoteRecord(baseDir, n, index))
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// ImportJSON recreates the notes in a document written by ExportJSON. Each
+// note is filed with the same filename rules as Create, using its own date
+// for the date prefix, and never overwrites an existing note.
+func ImportJSON(w io.Writer, baseDir string, r io.Reader, opts ImportOptions) error {
+	var doc VaultExport
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return fmt.Errorf("reading JSON export: %w", err)
+	}
+	if doc.Version > vaultFormatVersion {
+		return fmt.Errorf("unsupported export version %d", doc.Version)
+	}
+
+	notes := make([]importNote, 0, len(doc.Notes))
+	for _, rec := range doc.Notes {
+		notes = append(notes, importNote{
+			Source:      rec.Path,
+			Folder:      rec.Folder,
+			Frontmatter: rec.Frontmatter.Frontmatter(),
+			Body:        rec.Body,
+			ModTime:     rec.ModTime,
+		})
+	}
+	return saveImported(w, baseDir, "JSON", notes, opts)
+}
@@ -0,0 +1,106 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportImportJSON(t *testing.T) {
+	src := setupFindTestDir(t)
+	extra := `---
+title: "Reading List"
+date: 2026-01-05
+tags: [books]
+status: draft
+aliases: []
+source: obsidian
+---
+
+See [[Golang Tips]] and [[Missing]].
+`
+	path := filepath.Join(src, "Inbox", "2026-01-05-reading-list.md")
+	if err := os.WriteFile(path, []byte(extra), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2026, 1, 6, 12, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := ExportJSON(&out, src); err != nil {
+		t.Fatalf("ExportJSON() error: %v", err)
+	}
+
+	var doc VaultExport
+	if err := json.Unmarshal(out.Bytes(), &doc); err != nil {
+		t.Fatalf("export is not valid JSON: %v", err)
+	}
+	if len(doc.Notes) != 4 {
+		t.Fatalf("exported %d notes, want 4", len(doc.Notes))
+	}
+	var reading NoteRecord
+	for _, n := range doc.Notes {
+		if n.Frontmatter.Title == "Reading List" {
+			reading = n
+		}
+	}
+	if reading.Path != "Inbox/2026-01-05-reading-list.md" || reading.Folder != "Inbox" {
+		t.Errorf("path/folder = %q/%q", reading.Path, reading.Folder)
+	}
+	if reading.Frontmatter.Extra["source"] != "obsidian" {
+		t.Errorf("unknown field not exported: %+v", reading.Frontmatter.Extra)
+	}
+	if !reading.ModTime.Equal(mtime) {
+		t.Errorf("mtime = %v, want %v", reading.ModTime, mtime)
+	}
+	wantLinks := []LinkRecord{{Target: "Golang Tips", Path: "Resources/golang-tips.md"}, {Target: "Missing"}}
+	if len(reading.Links) != 2 || reading.Links[0] != wantLinks[0] || reading.Links[1] != wantLinks[1] {
+		t.Errorf("links = %+v, want %+v", reading.Links, wantLinks)
+	}
+
+	dst := setupListDir(t)
+	var log bytes.Buffer
+	if err := ImportJSON(&log, dst, bytes.NewReader(out.Bytes()), ImportOptions{}); err != nil {
+		t.Fatalf("ImportJSON() error: %v", err)
+	}
+	if !strings.Contains(log.String(), "Imported 4 notes, 0 conflicts.") {
+		t.Errorf("unexpected import summary: %s", log.String())
+	}
+
+	data, err := os.ReadFile(filepath.Join(dst, "Inbox", "2026-01-05-reading-list.md"))
+	if err != nil {
+		t.Fatalf("imported note missing: %v", err)
+	}
+	// Notes without created and updated are stamped with their mtime.
+	want := strings.Replace(extra, "aliases: []\n", "aliases: []\ncreated: 2026-01-06T12:00:00Z\nupdated: 2026-01-06T12:00:00Z\n", 1)
+	if string(data) != want {
+		t.Errorf("imported note =\n%s\nwant:\n%s", data, want)
+	}
+	info, _ := os.Stat(filepath.Join(dst, "Inbox", "2026-01-05-reading-list.md"))
+	if !info.ModTime().Equal(mtime) {
+		t.Errorf("imported mtime = %v, want %v", info.ModTime(), mtime)
+	}
+
+	// Importing again must not overwrite anything.
+	log.Reset()
+	if err := ImportJSON(&log, dst, bytes.NewReader(out.Bytes()), ImportOptions{}); err != nil {
+		t.Fatalf("second ImportJSON() error: %v", err)
+	}
+	if !strings.Contains(log.String(), "Imported 0 notes, 4 conflicts.") {
+		t.Errorf("expected every note skipped, got: %s", log.String())
+	}
+
+	log.Reset()
+	if err := ImportJSON(&log, dst, bytes.NewReader(out.Bytes()), ImportOptions{OnCollision: CollisionSuffix}); err != nil {
+		t.Fatalf("suffix ImportJSON() error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "Resources", "golang-tips-2.md")); err != nil {
+		t.Errorf("expected suffixed copy: %v", err)
+	}
+}
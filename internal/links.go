@@ -0,0 +1,126 @@
+package internal
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var wikilinkPattern = regexp.MustCompile(`\[\[([^\[\]\n]+)\]\]`)
+
+// Link is a [[wikilink]] found in a note body.
+type Link struct {
+	Target string
+	Label  string
+	// Line is the 1-based line of the link within the text it was found in.
+	Line int
+}
+
+// ExtractLinks returns the wikilinks in text in the order they appear.
+func ExtractLinks(text string) []Link {
+	var links []Link
+	for i, line := range strings.Split(text, "\n") {
+		for _, m := range wikilinkPattern.FindAllStringSubmatch(line, -1) {
+			target, label := splitWikilink(m[1])
+			if target == "" {
+				continue
+			}
+			links = append(links, Link{Target: target, Label: label, Line: i + 1})
+		}
+	}
+	return links
+}
+
+// splitWikilink splits the inside of a wikilink, "target#heading|label",
+// into the target note and the text to display.
+func splitWikilink(inner string) (target, label string) {
+	target = inner
+	label = inner
+	if idx := strings.Index(inner, "|"); idx >= 0 {
+		target = inner[:idx]
+		label = inner[idx+1:]
+	}
+	if idx := strings.Index(target, "#"); idx >= 0 {
+		target = target[:idx]
+	}
+	return strings.TrimSpace(target), strings.TrimSpace(label)
+}
+
+// LinkIndex resolves wikilink targets to notes and tracks which notes link
+// to each other.
+type LinkIndex struct {
+	notes     []Note
+	byKey     map[string]int
+	backlinks map[string][]int
+}
+
+// NewLinkIndex builds an index over notes. Targets are matched against
+// titles first, then aliases, then filenames and slugs, ignoring case.
+func NewLinkIndex(notes []Note) *LinkIndex {
+	ix := &LinkIndex{
+		notes:     notes,
+		byKey:     make(map[string]int),
+		backlinks: make(map[string][]int),
+	}
+
+	add := func(key string, i int) {
+		key = linkKey(key)
+		if _, taken := ix.byKey[key]; key != "" && !taken {
+			ix.byKey[key] = i
+		}
+	}
+	for i, n := range notes {
+		add(n.Frontmatter.Title, i)
+	}
+	for i, n := range notes {
+		for _, a := range n.Frontmatter.Aliases {
+			add(a, i)
+		}
+	}
+	for i, n := range notes {
+		add(filepath.Base(n.FilePath), i)
+		add(NoteSlug(n.FilePath), i)
+	}
+
+	for i, n := range notes {
+		seen := make(map[string]bool)
+		for _, l := range ExtractLinks(n.Body) {
+			target, ok := ix.Resolve(l.Target)
+			if !ok || target.FilePath == n.FilePath || seen[target.FilePath] {
+				continue
+			}
+			seen[target.FilePath] = true
+			ix.backlinks[target.FilePath] = append(ix.backlinks[target.FilePath], i)
+		}
+	}
+	return ix
+}
+
+// Resolve returns the note a wikilink target refers to.
+func (ix *LinkIndex) Resolve(target string) (Note, bool) {
+	i, ok := ix.byKey[linkKey(target)]
+	if !ok {
+		return Note{}, false
+	}
+	return ix.notes[i], true
+}
+
+// Backlinks returns the notes that link to note, in index order.
+func (ix *LinkIndex) Backlinks(note Note) []Note {
+	var result []Note
+	for _, i := range ix.backlinks[note.FilePath] {
+		result = append(result, ix.notes[i])
+	}
+	return result
+}
+
+// Notes returns the notes the index was built from.
+func (ix *LinkIndex) Notes() []Note {
+	return ix.notes
+}
+
+// linkKey normalizes a wikilink target or note name for lookup.
+func linkKey(s string) string {
+	s = strings.TrimSpace(strings.ToLower(s))
+	return strings.TrimSuffix(s, ".md")
+}
@@ -0,0 +1,55 @@
+package internal
+
+import (
+	"testing"
+)
+
+func TestExtractLinks(t *testing.T) {
+	body := "See [[Go Tips]] and\n[[python-basics#setup|Python]] plus [[ ]]."
+	got := ExtractLinks(body)
+	want := []Link{
+		{Target: "Go Tips", Label: "Go Tips", Line: 1},
+		{Target: "python-basics", Label: "Python", Line: 2},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("ExtractLinks() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("ExtractLinks()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestLinkIndex(t *testing.T) {
+	notes := []Note{
+		{Frontmatter: Frontmatter{Title: "Go Tips", Aliases: []string{"golang"}}, FilePath: "/v/Resources/go-tips.md", Body: "Links to [[Python Basics]]."},
+		{Frontmatter: Frontmatter{Title: "Python Basics"}, FilePath: "/v/Inbox/2026-02-12-python-basics.md", Body: "Back to [[golang]] and [[go-tips]]."},
+		{Frontmatter: Frontmatter{Title: "Lonely"}, FilePath: "/v/Areas/lonely.md", Body: "[[Nowhere]]"},
+	}
+	ix := NewLinkIndex(notes)
+
+	tests := map[string]string{
+		"go tips":                     "/v/Resources/go-tips.md",
+		"GOLANG":                      "/v/Resources/go-tips.md",
+		"python-basics":               "/v/Inbox/2026-02-12-python-basics.md",
+		"2026-02-12-python-basics.md": "/v/Inbox/2026-02-12-python-basics.md",
+	}
+	for target, want := range tests {
+		got, ok := ix.Resolve(target)
+		if !ok || got.FilePath != want {
+			t.Errorf("Resolve(%q) = %q, %v; want %q", target, got.FilePath, ok, want)
+		}
+	}
+	if _, ok := ix.Resolve("Nowhere"); ok {
+		t.Error("Resolve(\"Nowhere\") should not resolve")
+	}
+
+	back := ix.Backlinks(notes[0])
+	if len(back) != 1 || back[0].Frontmatter.Title != "Python Basics" {
+		t.Errorf("Backlinks(Go Tips) = %+v, want only Python Basics (once)", back)
+	}
+	if back := ix.Backlinks(notes[2]); len(back) != 0 {
+		t.Errorf("Backlinks(Lonely) = %+v, want none", back)
+	}
+}
@@ -0,0 +1,429 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SchemaFile is where a vault declares its frontmatter rules, relative to
+// the vault.
+const SchemaFile = ".qn/schema.toml"
+
+// FolderSchema is the frontmatter every note in a folder must have.
+type FolderSchema struct {
+	// Required lists the keys that must be present and non-empty.
+	Required []string
+	// Status lists the allowed status values. Empty allows any.
+	Status []string
+	// DateFormat is the Go time layout the date field must use.
+	DateFormat string
+	// Tags lists tags every note must carry.
+	Tags []string
+}
+
+// Schema is a FolderSchema for each note folder.
+type Schema map[string]FolderSchema
+
+// DefaultSchema is used when the vault has no schema file. It asks for
+// what Create writes: a title and date on every note, and a status and
+// the project tag on projects.
+func DefaultSchema() Schema {
+	base := FolderSchema{Required: []string{"title", "date"}, DateFormat: "2006-01-02"}
+	s := Schema{}
+	for _, folder := range NoteFolders {
+		s[folder] = base
+	}
+	s["Projects"] = FolderSchema{Required: []string{"title", "date", "status"}, DateFormat: "2006-01-02", Tags: []string{"project"}}
+	return s
+}
+
+// LoadSchema reads the schema of the vault at baseDir, or returns
+// DefaultSchema when it has none. The file has a [default] section
+// applying to every folder, and a section per folder whose keys replace
+// the default ones:
+//
+//	[default]
+//	required = ["title", "date"]
+//	date_format = "2006-01-02"
+//
+//	[Projects]
+//	required = ["title", "date", "status"]
+//	status = ["active", "paused", "done"]
+//	tags = ["project"]
+func LoadSchema(baseDir string) (Schema, error) {
+	path := filepath.Join(baseDir, SchemaFile)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return DefaultSchema(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	s, err := parseSchema(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", SchemaFile, err)
+	}
+	return s, nil
+}
+
+func parseSchema(data []byte) (Schema, error) {
+	doc, err := parseTOML(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(doc[""]) > 0 {
+		return nil, fmt.Errorf("keys must be in a [default] or [<folder>] section")
+	}
+
+	base := FolderSchema{DateFormat: "2006-01-02"}
+	if err := applySchemaTable(&base, doc["default"]); err != nil {
+		return nil, fmt.Errorf("[default]: %w", err)
+	}
+	s := Schema{}
+	for _, folder := range NoteFolders {
+		fs := base
+		if err := applySchemaTable(&fs, doc[folder]); err != nil {
+			return nil, fmt.Errorf("[%s]: %w", folder, err)
+		}
+		s[folder] = fs
+	}
+	for name := range doc {
+		if name != "" && name != "default" && !slices.Contains(NoteFolders, name) {
+			return nil, fmt.Errorf("unknown section [%s]; want default or one of %s", name, strings.Join(NoteFolders, ", "))
+		}
+	}
+	return s, nil
+}
+
+// applySchemaTable overrides the fields of fs that t sets.
+func applySchemaTable(fs *FolderSchema, t tomlTable) error {
+	for key, value := range t {
+		if key == "date_format" {
+			layout, ok := value.(string)
+			if !ok || layout == "" {
+				return fmt.Errorf("date_format must be a Go time layout such as \"2006-01-02\"")
+			}
+			fs.DateFormat = layout
+			continue
+		}
+		list, ok := value.([]string)
+		if !ok {
+			return fmt.Errorf("%s must be an array of strings", key)
+		}
+		switch key {
+		case "required":
+			fs.Required = list
+		case "status":
+			fs.Status = list
+		case "tags":
+			fs.Tags = list
+		default:
+			return fmt.Errorf("unknown key %q; want required, status, date_format or tags", key)
+		}
+	}
+	return nil
+}
+
+// LintOptions configures Lint.
+type LintOptions struct {
+	// Fix rewrites notes to add missing fields that can be derived: the
+	// title from the first heading or filename, the date from the filename
+	// or modification time, the status and the required tags.
+	Fix bool
+}
+
+// Lint checks every note against the vault's schema and prints each
+// violation as path:line: message. It returns an error when violations
+// remain, after fixing what it can if opts.Fix is set.
+func Lint(w io.Writer, baseDir string, opts LintOptions) error {
+	schema, err := LoadSchema(baseDir)
+	if err != nil {
+		return err
+	}
+	notes, err := ScanNotes(baseDir)
+	if err != nil {
+		return err
+	}
+
+	var problems []Problem
+	var fixed []string
+	for _, note := range notes {
+		fs := schema[note.Folder]
+		if opts.Fix {
+			ok, err := fixNote(note, fs)
+			if errors.Is(err, errNoFrontmatterBlock) {
+				problems = append(problems, Problem{Path: relPaths(baseDir, []string{note.FilePath})[0], Line: 1, Message: err.Error()})
+			} else if err != nil {
+				return err
+			}
+			if ok {
+				fixed = append(fixed, note.FilePath)
+				_, _ = fmt.Fprintf(w, "fixed %s\n", relPaths(baseDir, []string{note.FilePath})[0])
+				data, err := os.ReadFile(note.FilePath)
+				if err != nil {
+					return err
+				}
+				note.Frontmatter, note.Body, _ = ParseFrontmatterFromBytes(data)
+			}
+		}
+		problems = append(problems, lintNote(baseDir, note, fs)...)
+	}
+	if len(fixed) > 0 {
+		autoCommit(w, baseDir, fmt.Sprintf("qn: lint --fix %d notes", len(fixed)), fixed...)
+	}
+
+	files := make(map[string]bool)
+	for _, p := range problems {
+		_, _ = fmt.Fprintln(w, p)
+		files[p.Path] = true
+	}
+	switch {
+	case len(problems) > 0:
+		_, _ = fmt.Fprintf(w, "\n%d problems in %d notes.\n", len(problems), len(files))
+		return fmt.Errorf("%d problems found", len(problems))
+	case len(fixed) > 0:
+		_, _ = fmt.Fprintf(w, "\nFixed %d notes; %d notes follow the schema.\n", len(fixed), len(notes))
+	default:
+		_, _ = fmt.Fprintf(w, "%d notes follow the schema.\n", len(notes))
+	}
+	return nil
+}
+
+// lintNote returns the ways note breaks fs. Missing fields are reported on
+// the first line, bad values on the line of their key.
+func lintNote(baseDir string, note Note, fs FolderSchema) []Problem {
+	rel := relPaths(baseDir, []string{note.FilePath})[0]
+	var lines map[string]int
+	line := func(key string) int {
+		if lines == nil {
+			lines = frontmatterKeyLines(note.FilePath)
+		}
+		if n, ok := lines[key]; ok {
+			return n
+		}
+		return 1
+	}
+
+	var problems []Problem
+	fm := note.Frontmatter
+	for _, key := range fs.Required {
+		if frontmatterValue(fm, key) == "" {
+			problems = append(problems, Problem{Path: rel, Line: line(key), Message: fmt.Sprintf("missing required field %q", key)})
+		}
+	}
+	if fm.Date != "" {
+		if _, err := time.Parse(fs.DateFormat, fm.Date); err != nil {
+			problems = append(problems, Problem{Path: rel, Line: line("date"), Message: fmt.Sprintf("date %q does not match format %q", fm.Date, fs.DateFormat)})
+		}
+	}
+	if fm.Status != "" && len(fs.Status) > 0 && !slices.Contains(fs.Status, fm.Status) {
+		problems = append(problems, Problem{Path: rel, Line: line("status"), Message: fmt.Sprintf("status %q is not one of %s", fm.Status, strings.Join(fs.Status, ", "))})
+	}
+	for _, tag := range fs.Tags {
+		if !containsTag(fm.Tags, tag) {
+			problems = append(problems, Problem{Path: rel, Line: line("tags"), Message: fmt.Sprintf("missing required tag %q", tag)})
+		}
+	}
+	sort.SliceStable(problems, func(i, j int) bool { return problems[i].Line < problems[j].Line })
+	return problems
+}
+
+// fixNote adds the missing fields of note that fs requires and can be
+// derived, and reports whether it rewrote the file. Only the lines of the
+// added fields change, so comments and list styles are kept. Notes whose
+// frontmatter is never closed are left alone, since rewriting them would
+// turn the broken block into body text. When the file starts with "---"
+// but that line does not open a frontmatter block, as with "----", it
+// returns errNoFrontmatterBlock and leaves the file alone.
+func fixNote(note Note, fs FolderSchema) (bool, error) {
+	data, err := os.ReadFile(note.FilePath)
+	if err != nil {
+		return false, err
+	}
+	if unclosedFrontmatter(data) {
+		return false, nil
+	}
+
+	content := string(data)
+	if !strings.HasPrefix(content, "---") {
+		content = "---\n---\n" + content
+	}
+	fm := note.Frontmatter
+	changed := false
+	for _, key := range fs.Required {
+		if frontmatterValue(fm, key) != "" {
+			continue
+		}
+		var value string
+		switch key {
+		case "title":
+			value = strconv.Quote(derivedTitle(note))
+		case "date":
+			value = derivedDate(note, fs.DateFormat)
+		case "status":
+			value = "draft"
+			if len(fs.Status) > 0 {
+				value = fs.Status[0]
+			} else if note.Folder == "Projects" {
+				value = "active"
+			}
+		case "created", "updated":
+			value = timestamp(note.ModTime)
+		default:
+			continue
+		}
+		var ok bool
+		if content, ok = setFrontmatterField(content, key, value); !ok {
+			return false, errNoFrontmatterBlock
+		}
+		changed = true
+	}
+	var tags []string
+	for _, tag := range fs.Tags {
+		if !containsTag(fm.Tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) > 0 {
+		added := addFrontmatterListItems(content, "tags", tags)
+		if added == content {
+			return false, errNoFrontmatterBlock
+		}
+		content = added
+		changed = true
+	}
+	if !changed {
+		return false, nil
+	}
+	// A derived updated already says when the note last changed; bumping
+	// it to now would make every backfilled note look freshly edited.
+	if fm.Updated != "" || !slices.Contains(fs.Required, "updated") {
+		content, _ = setFrontmatterField(content, "updated", timestamp(time.Now()))
+	}
+	return true, RewriteFile(note.FilePath, []byte(content))
+}
+
+// errNoFrontmatterBlock is returned by fixNote for a note it cannot add
+// fields to.
+var errNoFrontmatterBlock = errors.New("cannot fix: the first line starts with --- but does not open a frontmatter block")
+
+// addFrontmatterListItems appends items to the list key in the frontmatter
+// of a note file, keeping the list's style: a block list gets "- item"
+// lines with the same indent, an inline list gets the items added inside
+// its brackets, and a missing key is added as an inline list. content must
+// have a closed frontmatter block.
+func addFrontmatterListItems(content, key string, items []string) string {
+	lines := strings.Split(content, "\n")
+	end, ok := frontmatterEnd(lines)
+	if !ok {
+		return content
+	}
+	for i := 1; i < end; i++ {
+		k, v, ok := parseYAMLLine(lines[i])
+		if !ok || k != key || isYAMLContinuation(lines[i]) {
+			continue
+		}
+		last := i
+		for last+1 < end && isYAMLContinuation(lines[last+1]) {
+			last++
+		}
+		if last > i && v == "" {
+			item := lines[last]
+			indent := item[:len(item)-len(strings.TrimLeft(item, " \t"))]
+			added := make([]string, len(items))
+			for j, it := range items {
+				added[j] = indent + "- " + it
+			}
+			return strings.Join(slices.Insert(lines, last+1, added...), "\n")
+		}
+		merged := append(parseYAMLList(v), items...)
+		lines[i] = key + ": [" + strings.Join(merged, ", ") + "]"
+		return strings.Join(lines, "\n")
+	}
+	line := key + ": [" + strings.Join(items, ", ") + "]"
+	return strings.Join(slices.Insert(lines, end, line), "\n")
+}
+
+// derivedTitle is the title --fix gives a note without one: its first
+// heading, or else its filename.
+func derivedTitle(note Note) string {
+	if m := firstHeading.FindStringSubmatch(note.Body); m != nil {
+		return strings.TrimSpace(m[1])
+	}
+	return NoteSlug(note.FilePath)
+}
+
+// derivedDate is the date --fix gives a note without one: the date prefix
+// of its filename, or else its modification time.
+func derivedDate(note Note, layout string) string {
+	name := filepath.Base(note.FilePath)
+	if len(name) >= 10 {
+		if t, err := time.Parse("2006-01-02", name[:10]); err == nil {
+			return t.Format(layout)
+		}
+	}
+	return note.ModTime.Format(layout)
+}
+
+// frontmatterValue returns the value of key in fm as text, or "" when it
+// is missing or empty.
+func frontmatterValue(fm Frontmatter, key string) string {
+	switch key {
+	case "title":
+		return fm.Title
+	case "date":
+		return fm.Date
+	case "status":
+		return fm.Status
+	case "created":
+		return fm.Created
+	case "updated":
+		return fm.Updated
+	case "tags":
+		return strings.Join(fm.Tags, ", ")
+	case "aliases":
+		return strings.Join(fm.Aliases, ", ")
+	}
+	for _, f := range fm.Extra {
+		if f.Key == key {
+			return strings.TrimSpace(f.Value)
+		}
+	}
+	return ""
+}
+
+// frontmatterKeyLines maps each frontmatter key of the file at path to its
+// 1-based line number.
+func frontmatterKeyLines(path string) map[string]int {
+	lines := make(map[string]int)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return lines
+	}
+	all := strings.Split(string(data), "\n")
+	if strings.TrimSpace(all[0]) != "---" {
+		return lines
+	}
+	for i, line := range all[1:] {
+		if strings.TrimSpace(line) == "---" {
+			break
+		}
+		if isYAMLContinuation(line) {
+			continue
+		}
+		if key, _, ok := parseYAMLLine(line); ok {
+			if _, seen := lines[key]; !seen {
+				lines[key] = i + 2
+			}
+		}
+	}
+	return lines
+}
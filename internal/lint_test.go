@@ -0,0 +1,213 @@
+package internal
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadSchema(t *testing.T) {
+	dir := t.TempDir()
+	s, err := LoadSchema(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s["Projects"].Tags; len(got) != 1 || got[0] != "project" {
+		t.Errorf("default Projects tags = %v", got)
+	}
+
+	writeSchema(t, dir, "[default]\nrequired = [\"title\"]\ndate_format = \"2006/01/02\"\n\n[Projects]\nstatus = [\"active\", \"done\"]\n")
+	s, err = LoadSchema(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := s["Projects"]
+	if len(p.Required) != 1 || p.DateFormat != "2006/01/02" || len(p.Status) != 2 || len(p.Tags) != 0 {
+		t.Errorf("Projects schema = %+v, want default keys with its own status", p)
+	}
+
+	for data, want := range map[string]string{
+		"[Nowhere]\nrequired = []":       "unknown section [Nowhere]",
+		"[default]\nrequire = [\"a\"]":   `unknown key "require"`,
+		"[default]\nstatus = \"active\"": "status must be an array of strings",
+		"required = [\"title\"]":         "must be in a [default] or [<folder>] section",
+	} {
+		writeSchema(t, dir, data)
+		if _, err := LoadSchema(dir); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("LoadSchema(%q) error = %v, want %q", data, err, want)
+		}
+	}
+}
+
+func TestLint(t *testing.T) {
+	dir := setupListDir(t)
+	writeSchema(t, dir, "[default]\nrequired = [\"title\", \"date\", \"status\"]\n\n[Projects]\nstatus = [\"active\", \"done\"]\ntags = [\"project\"]\n")
+	writeNote := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeNote("Inbox/ok.md", "---\ntitle: Fine\ndate: 2026-02-13\nstatus: draft\n---\n")
+	writeNote("Projects/2026-01-20-launch.md", "---\ntitle: Launch\ntags: [work]\nstatus: someday\n---\n")
+	writeNote("Resources/bad-date.md", "---\ntitle: Bad Date\ndate: Feb 13\nstatus: draft\n---\n")
+
+	var buf bytes.Buffer
+	if err := Lint(&buf, dir, LintOptions{}); err == nil {
+		t.Fatal("Lint() should fail when notes break the schema")
+	}
+	want := `Projects/2026-01-20-launch.md:1: missing required field "date"
+Projects/2026-01-20-launch.md:3: missing required tag "project"
+Projects/2026-01-20-launch.md:4: status "someday" is not one of active, done
+Resources/bad-date.md:3: date "Feb 13" does not match format "2006-01-02"
+
+4 problems in 2 notes.
+`
+	if buf.String() != want {
+		t.Errorf("Lint() output:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestLintFix(t *testing.T) {
+	dir := setupListDir(t)
+	dated := filepath.Join(dir, "Projects", "2026-01-20-launch.md")
+	undated := filepath.Join(dir, "Inbox", "idea.md")
+	if err := os.WriteFile(dated, []byte("---\ntags: [work]\n---\n# Launch Plan\n\nShip it.\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(undated, []byte("Just a thought.\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2025, 11, 3, 9, 0, 0, 0, time.Local)
+	if err := os.Chtimes(undated, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := Lint(&buf, dir, LintOptions{Fix: true}); err != nil {
+		t.Fatalf("Lint(--fix) error: %v\n%s", err, buf.String())
+	}
+	if !strings.Contains(buf.String(), "fixed Projects/2026-01-20-launch.md\n") || !strings.Contains(buf.String(), "Fixed 2 notes") {
+		t.Errorf("Lint(--fix) output:\n%s", buf.String())
+	}
+
+	fm, body, _ := ParseFrontmatter(dated)
+	if fm.Title != "Launch Plan" || fm.Date != "2026-01-20" || fm.Status != "active" || strings.Join(fm.Tags, ",") != "work,project" {
+		t.Errorf("fixed project frontmatter = %+v", fm)
+	}
+	if body != "# Launch Plan\n\nShip it.\n" {
+		t.Errorf("fixed project body = %q", body)
+	}
+	fm, body, _ = ParseFrontmatter(undated)
+	if fm.Title != "idea" || fm.Date != "2025-11-03" || body != "Just a thought.\n" {
+		t.Errorf("fixed inbox note = %+v, body %q", fm, body)
+	}
+
+	buf.Reset()
+	if err := Lint(&buf, dir, LintOptions{}); err != nil || buf.String() != "2 notes follow the schema.\n" {
+		t.Errorf("Lint() after fix = %v, %q", err, buf.String())
+	}
+}
+
+func TestLintFixNoFrontmatterBlock(t *testing.T) {
+	dir := setupListDir(t)
+	path := filepath.Join(dir, "Projects", "rule.md")
+	content := "----\ntitle: Rule\n----\nBody.\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := Lint(&buf, dir, LintOptions{Fix: true}); err == nil {
+		t.Fatalf("Lint(--fix) should fail, output:\n%s", buf.String())
+	}
+	if strings.Contains(buf.String(), "fixed") || !strings.Contains(buf.String(), "Projects/rule.md:1: cannot fix: ") {
+		t.Errorf("Lint(--fix) output:\n%s", buf.String())
+	}
+	if data, _ := os.ReadFile(path); string(data) != content {
+		t.Errorf("note was rewritten: %q", data)
+	}
+}
+
+func TestLintFixKeepsFormatting(t *testing.T) {
+	dir := setupListDir(t)
+	block := filepath.Join(dir, "Projects", "block.md")
+	inline := filepath.Join(dir, "Projects", "inline.md")
+	if err := os.WriteFile(block, []byte("---\n# reviewed weekly\ntitle: 'Block: Tags'\ntags:\n  - work\n  - q3\nstatus: active\n---\nBody.\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(inline, []byte("---\ntitle: Inline\ntags: [work]  \nstatus: active\nowner: \"me\" # for now\n---\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := Lint(&buf, dir, LintOptions{Fix: true}); err != nil {
+		t.Fatalf("Lint(--fix) error: %v\n%s", err, buf.String())
+	}
+	data, err := os.ReadFile(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "---\n# reviewed weekly\ntitle: 'Block: Tags'\ntags:\n  - work\n  - q3\n  - project\nstatus: active\ndate: "; !strings.HasPrefix(string(data), want) || !strings.HasSuffix(string(data), "\n---\nBody.\n") {
+		t.Errorf("fixed block list note = %q", data)
+	}
+	data, err = os.ReadFile(inline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "---\ntitle: Inline\ntags: [work, project]\nstatus: active\nowner: \"me\" # for now\ndate: "; !strings.HasPrefix(string(data), want) {
+		t.Errorf("fixed inline list note = %q", data)
+	}
+}
+
+func TestLintTimestamps(t *testing.T) {
+	dir := setupListDir(t)
+	writeSchema(t, dir, "[default]\nrequired = [\"title\", \"created\", \"updated\"]\n")
+	stamped := filepath.Join(dir, "Areas", "stamped.md")
+	old := filepath.Join(dir, "Areas", "old.md")
+	if err := os.WriteFile(stamped, []byte("---\ntitle: Stamped\ncreated: 2026-01-02T10:00:00Z\nupdated: 2026-01-03T10:00:00Z\n---\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(old, []byte("---\ntitle: Old\n---\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2025, 11, 3, 9, 0, 0, 0, time.Local)
+	if err := os.Chtimes(old, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := Lint(&buf, dir, LintOptions{}); err == nil {
+		t.Fatal("Lint() should fail when timestamps are missing")
+	}
+	want := `Areas/old.md:1: missing required field "created"
+Areas/old.md:1: missing required field "updated"
+
+2 problems in 1 notes.
+`
+	if buf.String() != want {
+		t.Errorf("Lint() output:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := Lint(&buf, dir, LintOptions{Fix: true}); err != nil {
+		t.Fatalf("Lint(--fix) error: %v\n%s", err, buf.String())
+	}
+	fm, _, _ := ParseFrontmatter(old)
+	if fm.Created != timestamp(mtime) || fm.Updated != timestamp(mtime) {
+		t.Errorf("fixed timestamps = %q, %q; want both %q", fm.Created, fm.Updated, timestamp(mtime))
+	}
+}
+
+func writeSchema(t *testing.T, dir, data string) {
+	t.Helper()
+	path := filepath.Join(dir, SchemaFile)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,42 +1,149 @@
 package internal
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
-// List displays recent notes sorted by modification time.
-func List(w io.Writer, baseDir string, showAll bool) error {
-	notes, err := ScanNotes(baseDir)
+// DefaultListLimit is how many notes List shows without All or Limit.
+const DefaultListLimit = 10
+
+// ListOptions configures List.
+type ListOptions struct {
+	// All lists every matching note instead of the first Limit.
+	All bool
+	// Limit is how many notes to show. Zero means DefaultListLimit.
+	Limit int
+	// Folder, when set, only lists notes in that folder.
+	Folder string
+	// Tags only lists notes carrying every one of these tags.
+	Tags []string
+	// Status, when set, only lists notes with that status.
+	Status string
+	// Since and Until, when set, only list notes whose DateField falls on
+	// or after Since and on or before the day of Until.
+	Since, Until time.Time
+	// DateField is what Since and Until compare, one of ListDateFields.
+	// "updated" (the default) and "created" are the frontmatter fields,
+	// "date" the frontmatter date; each falls back to the modification
+	// time when the note lacks it. "mtime" is the modification time only.
+	DateField string
+	// Sort orders the notes by a date field (newest first), "title" or
+	// "folder". The default is "updated".
+	Sort string
+	// Reverse reverses the sort order.
+	Reverse bool
+	// Strict fails with a *ProblemError when any note has a problem
+	// Doctor would report, instead of skipping or misreading it.
+	Strict bool
+	// DateFormat is the Go time layout of the dates shown. The default is
+	// DefaultDateFormat.
+	DateFormat string
+	// Format is OutputText (the default) or OutputJSON, which writes the
+	// listed notes as a JSON array of NoteSummary.
+	Format string
+}
+
+// ListDateFields are the fields List accepts for ListOptions.DateField.
+var ListDateFields = []string{"updated", "created", "date", "mtime"}
+
+// ListSorts are the orders List accepts for ListOptions.Sort.
+var ListSorts = append(slices.Clone(ListDateFields), "title", "folder")
+
+// List displays notes, by default the most recently updated ones. Each
+// line shows the day of the date field the list is sorted by, or the
+// note's date when sorted by title or folder.
+func List(w io.Writer, baseDir string, opts ListOptions) error {
+	if opts.Sort == "" {
+		opts.Sort = "updated"
+	}
+	if !slices.Contains(ListSorts, opts.Sort) {
+		return fmt.Errorf("unknown sort %q; want one of %s", opts.Sort, strings.Join(ListSorts, ", "))
+	}
+	if opts.DateField == "" {
+		opts.DateField = "updated"
+	}
+	if !slices.Contains(ListDateFields, opts.DateField) {
+		return fmt.Errorf("unknown date field %q; want one of %s", opts.DateField, strings.Join(ListDateFields, ", "))
+	}
+	if opts.Folder != "" {
+		folder, ok := noteFolder(opts.Folder)
+		if !ok {
+			return fmt.Errorf("unknown folder %q; want one of %s", opts.Folder, strings.Join(NoteFolders, ", "))
+		}
+		opts.Folder = folder
+	}
+	if opts.Limit < 0 {
+		return fmt.Errorf("limit must not be negative")
+	}
+	if opts.DateFormat == "" {
+		opts.DateFormat = DefaultDateFormat
+	}
+	format, err := outputFormat(opts.Format)
 	if err != nil {
 		return err
 	}
 
-	if len(notes) == 0 {
+	scan := ScanNotes
+	if opts.Strict {
+		scan = scanStrict
+	}
+	notes, err := scan(baseDir)
+	if err != nil {
+		return err
+	}
+
+	if len(notes) == 0 && format == OutputText {
 		_, _ = fmt.Fprintln(w, "No notes found.")
 		return nil
 	}
 
-	// Sort by modification time, newest first
-	sort.Slice(notes, func(i, j int) bool {
-		return notes[i].ModTime.After(notes[j].ModTime)
-	})
+	notes = filterNotes(notes, opts)
+	if len(notes) == 0 && format == OutputText {
+		_, _ = fmt.Fprintln(w, "No notes match.")
+		return nil
+	}
+	sortNotes(notes, opts.Sort)
+	if opts.Reverse {
+		slices.Reverse(notes)
+	}
 
-	limit := 10
-	if showAll || len(notes) <= limit {
+	limit := opts.Limit
+	if limit == 0 {
+		limit = DefaultListLimit
+	}
+	if opts.All || len(notes) <= limit {
 		limit = len(notes)
 	}
 
+	if format == OutputJSON {
+		summaries := make([]NoteSummary, 0, limit)
+		for _, note := range notes[:limit] {
+			summaries = append(summaries, newNoteSummary(baseDir, note))
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(summaries)
+	}
+
 	for _, note := range notes[:limit] {
 		title := note.Frontmatter.Title
 		if title == "" {
 			title = "(untitled)"
 		}
 		date := note.Frontmatter.Date
-		if date == "" {
-			date = note.ModTime.Format("2006-01-02")
+		if slices.Contains(ListDateFields, opts.Sort) {
+			date = listTime(note, opts.Sort).Format(opts.DateFormat)
+		} else if t, err := time.ParseInLocation("2006-01-02", date, time.Local); err == nil {
+			date = t.Format(opts.DateFormat)
+		} else if date == "" {
+			date = note.ModTime.Format(opts.DateFormat)
 		}
 		tags := ""
 		if len(note.Frontmatter.Tags) > 0 {
@@ -45,9 +152,131 @@ func List(w io.Writer, baseDir string, showAll bool) error {
 		_, _ = fmt.Fprintf(w, "%s  %s  (%s)%s\n", date, title, note.Folder, tags)
 	}
 
-	if !showAll && len(notes) > 10 {
-		_, _ = fmt.Fprintf(w, "\nShowing 10 of %d notes. Use --all to show all.\n", len(notes))
+	if limit < len(notes) {
+		_, _ = fmt.Fprintf(w, "\nShowing %d of %d notes. Use --all or -n to show more.\n", limit, len(notes))
 	}
 
 	return nil
 }
+
+// filterNotes returns the notes matching the filters of opts.
+func filterNotes(notes []Note, opts ListOptions) []Note {
+	var matched []Note
+	for _, n := range notes {
+		if opts.Folder != "" && n.Folder != opts.Folder {
+			continue
+		}
+		if !hasAllTags(n.Frontmatter.Tags, opts.Tags) {
+			continue
+		}
+		if opts.Status != "" && !strings.EqualFold(n.Frontmatter.Status, opts.Status) {
+			continue
+		}
+		t := listTime(n, opts.DateField)
+		if !opts.Since.IsZero() && t.Before(opts.Since) {
+			continue
+		}
+		if !opts.Until.IsZero() && !t.Before(opts.Until.AddDate(0, 0, 1)) {
+			continue
+		}
+		matched = append(matched, n)
+	}
+	return matched
+}
+
+// sortNotes sorts notes by one of ListSorts.
+func sortNotes(notes []Note, by string) {
+	switch by {
+	case "updated", "created", "date", "mtime":
+		// Parse each note's time once rather than on every comparison.
+		timed := make([]struct {
+			t time.Time
+			n Note
+		}, len(notes))
+		for i, n := range notes {
+			timed[i].t, timed[i].n = listTime(n, by), n
+		}
+		sort.SliceStable(timed, func(i, j int) bool { return timed[i].t.After(timed[j].t) })
+		for i := range timed {
+			notes[i] = timed[i].n
+		}
+	case "title":
+		sortByTitle(notes)
+	case "folder":
+		sortByTitle(notes)
+		sort.SliceStable(notes, func(i, j int) bool {
+			return slices.Index(NoteFolders, notes[i].Folder) < slices.Index(NoteFolders, notes[j].Folder)
+		})
+	}
+}
+
+// listTime returns the time of n for one of ListDateFields.
+func listTime(n Note, field string) time.Time {
+	switch field {
+	case "updated":
+		return noteUpdated(n)
+	case "created":
+		return noteCreated(n)
+	case "date":
+		return noteTime(n)
+	}
+	return n.ModTime
+}
+
+// noteTime returns the note's frontmatter date, or its modification time
+// when the date is missing or not YYYY-MM-DD.
+func noteTime(n Note) time.Time {
+	if t, err := time.ParseInLocation("2006-01-02", n.Frontmatter.Date, time.Local); err == nil {
+		return t
+	}
+	return n.ModTime
+}
+
+// noteFolder returns the note folder named name, ignoring case.
+func noteFolder(name string) (string, bool) {
+	for _, f := range NoteFolders {
+		if strings.EqualFold(f, name) {
+			return f, true
+		}
+	}
+	return "", false
+}
+
+// ParseListDate parses a --since or --until value relative to now: a
+// YYYY-MM-DD date, "today", "yesterday", or a number of days or weeks ago
+// such as "7d" or "2w". The result is the start of that day.
+func ParseListDate(s string, now time.Time) (time.Time, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch s {
+	case "today":
+		return today, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	}
+	if n := len(s); n > 1 && (s[n-1] == 'd' || s[n-1] == 'w') {
+		count, err := strconv.Atoi(s[:n-1])
+		if err == nil && count >= 0 {
+			if s[n-1] == 'w' {
+				count *= 7
+			}
+			return today.AddDate(0, 0, -count), nil
+		}
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, now.Location())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q; want YYYY-MM-DD, today, yesterday, or a number of days or weeks ago like 7d or 2w", s)
+	}
+	return t, nil
+}
+
+// sortByUpdated sorts notes by when they were last updated, newest first.
+func sortByUpdated(notes []Note) {
+	sortNotes(notes, "updated")
+}
+
+// sortByTitle sorts notes by title, ignoring case.
+func sortByTitle(notes []Note) {
+	sort.SliceStable(notes, func(i, j int) bool {
+		return strings.ToLower(noteTitle(notes[i])) < strings.ToLower(noteTitle(notes[j]))
+	})
+}
@@ -2,6 +2,7 @@ package internal
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -25,7 +26,7 @@ func TestListEmpty(t *testing.T) {
 	dir := setupListDir(t)
 
 	var buf bytes.Buffer
-	err := List(&buf, dir, false)
+	err := List(&buf, dir, ListOptions{})
 	if err != nil {
 		t.Fatalf("List() error: %v", err)
 	}
@@ -60,7 +61,7 @@ Content.
 	}
 
 	var buf bytes.Buffer
-	err := List(&buf, dir, false)
+	err := List(&buf, dir, ListOptions{})
 	if err != nil {
 		t.Fatalf("List() error: %v", err)
 	}
@@ -96,7 +97,7 @@ aliases: []
 
 	// Without --all, should show 10 + footer
 	var buf bytes.Buffer
-	err := List(&buf, dir, false)
+	err := List(&buf, dir, ListOptions{})
 	if err != nil {
 		t.Fatalf("List() error: %v", err)
 	}
@@ -106,7 +107,7 @@ aliases: []
 
 	// With --all, should show all 15
 	buf.Reset()
-	err = List(&buf, dir, true)
+	err = List(&buf, dir, ListOptions{All: true})
 	if err != nil {
 		t.Fatalf("List(--all) error: %v", err)
 	}
@@ -115,6 +116,55 @@ aliases: []
 	}
 }
 
+func TestListFormats(t *testing.T) {
+	dir := setupListDir(t)
+
+	var buf bytes.Buffer
+	if err := List(&buf, dir, ListOptions{Format: OutputJSON}); err != nil {
+		t.Fatalf("List(json) error: %v", err)
+	}
+	if strings.TrimSpace(buf.String()) != "[]" {
+		t.Errorf("expected an empty JSON array, got: %s", buf.String())
+	}
+
+	for i := range 3 {
+		note := fmt.Sprintf(`---
+title: "Note %d"
+date: 2026-02-1%d
+tags: [test]
+---
+`, i, i)
+		path := filepath.Join(dir, "Areas", fmt.Sprintf("note-%d.md", i))
+		if err := os.WriteFile(path, []byte(note), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	buf.Reset()
+	if err := List(&buf, dir, ListOptions{Format: "JSON", Sort: "title", Limit: 2}); err != nil {
+		t.Fatalf("List(json) error: %v", err)
+	}
+	var notes []NoteSummary
+	if err := json.Unmarshal(buf.Bytes(), &notes); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, buf.String())
+	}
+	if len(notes) != 2 || notes[0].Title != "Note 0" || notes[0].Path != "Areas/note-0.md" || notes[0].Date != "2026-02-10" {
+		t.Errorf("unexpected notes: %+v", notes)
+	}
+
+	buf.Reset()
+	if err := List(&buf, dir, ListOptions{Sort: "title", DateFormat: "Jan 2"}); err != nil {
+		t.Fatalf("List(--date-format) error: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "Feb 10  Note 0") {
+		t.Errorf("expected the date in the given layout, got: %s", buf.String())
+	}
+
+	if err := List(&buf, dir, ListOptions{Format: "yaml"}); err == nil || !strings.Contains(err.Error(), "unknown output format") {
+		t.Errorf("expected an unknown format error, got %v", err)
+	}
+}
+
 func nonEmptyLines(s string) []string {
 	var result []string
 	for _, line := range strings.Split(s, "\n") {
@@ -124,3 +174,97 @@ func nonEmptyLines(s string) []string {
 	}
 	return result
 }
+
+func TestListFilters(t *testing.T) {
+	dir := setupListDir(t)
+	now := time.Now()
+	notes := []struct {
+		path, title, date, status, tags string
+		age                             time.Duration
+	}{
+		{"Projects/2026-01-05-launch.md", "Launch", "2026-01-05", "active", "project, work", 2 * 24 * time.Hour},
+		{"Projects/2025-06-01-old.md", "Old Project", "2025-06-01", "active", "project", 30 * 24 * time.Hour},
+		{"Projects/2026-02-01-paused.md", "Paused", "2026-02-01", "paused", "project", time.Hour},
+		{"Areas/health.md", "Health", "2026-01-10", "draft", "work", 3 * time.Hour},
+	}
+	for _, n := range notes {
+		path := filepath.Join(dir, n.path)
+		content := fmt.Sprintf("---\ntitle: %q\ndate: %s\ntags: [%s]\nstatus: %s\n---\n", n.title, n.date, n.tags, n.status)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		mtime := now.Add(-n.age)
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	weekAgo, err := ParseListDate("7d", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jan, _ := ParseListDate("2026-01-01", now)
+	janEnd, _ := ParseListDate("2026-01-31", now)
+
+	tests := []struct {
+		name string
+		opts ListOptions
+		want []string
+	}{
+		{"active projects this week", ListOptions{Folder: "projects", Status: "Active", Since: weekAgo}, []string{"Launch"}},
+		{"tags", ListOptions{Tags: []string{"work", "project"}}, []string{"Launch"}},
+		{"date range", ListOptions{Since: jan, Until: janEnd, DateField: "date", Sort: "date"}, []string{"Health", "Launch"}},
+		{"sort title reversed", ListOptions{Sort: "title", Reverse: true}, []string{"Paused", "Old Project", "Launch", "Health"}},
+		{"sort folder", ListOptions{Sort: "folder"}, []string{"Launch", "Old Project", "Paused", "Health"}},
+		{"limit", ListOptions{Limit: 2}, []string{"Paused", "Health"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := List(&buf, dir, tt.opts); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, line := range nonEmptyLines(buf.String()) {
+				if fields := strings.SplitN(line, "  ", 3); len(fields) == 3 {
+					got = append(got, fields[1])
+				}
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("titles = %q, want %q\n%s", got, tt.want, buf.String())
+			}
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := List(&buf, dir, ListOptions{Status: "done"}); err != nil || buf.String() != "No notes match.\n" {
+		t.Errorf("no matches = %v, %q", err, buf.String())
+	}
+	if err := List(&buf, dir, ListOptions{Sort: "size"}); err == nil {
+		t.Error("List() should reject an unknown sort")
+	}
+	if err := List(&buf, dir, ListOptions{Folder: "Nowhere"}); err == nil {
+		t.Error("List() should reject an unknown folder")
+	}
+}
+
+func TestParseListDate(t *testing.T) {
+	now := time.Date(2026, 2, 13, 15, 30, 0, 0, time.Local)
+	tests := map[string]string{
+		"today":      "2026-02-13",
+		"yesterday":  "2026-02-12",
+		"7d":         "2026-02-06",
+		"2w":         "2026-01-30",
+		"2025-12-31": "2025-12-31",
+	}
+	for in, want := range tests {
+		got, err := ParseListDate(in, now)
+		if err != nil || got.Format("2006-01-02") != want || got.Hour() != 0 {
+			t.Errorf("ParseListDate(%q) = %v, %v; want %s", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"", "last week", "d", "-3d", "2026-13-01"} {
+		if _, err := ParseListDate(bad, now); err == nil {
+			t.Errorf("ParseListDate(%q) should fail", bad)
+		}
+	}
+}
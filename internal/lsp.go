@@ -0,0 +1,625 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxLSPMessage bounds the size of a single message from the client.
+const maxLSPMessage = 64 << 20
+
+// JSON-RPC and LSP constants used by the language server.
+const (
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+
+	lspSyncFull          = 1
+	lspSeverityWarning   = 2
+	lspCompletionFile    = 17
+	lspCompletionKeyword = 14
+)
+
+type rpcMessage struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  any              `json:"result,omitempty"`
+	Error   *rpcError        `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspLocation struct {
+	URI   string   `json:"uri"`
+	Range lspRange `json:"range"`
+}
+
+type lspTextDocumentPosition struct {
+	TextDocument struct {
+		URI string `json:"uri"`
+	} `json:"textDocument"`
+	Position lspPosition `json:"position"`
+}
+
+type lspCompletionItem struct {
+	Label    string      `json:"label"`
+	Kind     int         `json:"kind"`
+	Detail   string      `json:"detail,omitempty"`
+	TextEdit lspTextEdit `json:"textEdit"`
+}
+
+type lspTextEdit struct {
+	Range   lspRange `json:"range"`
+	NewText string   `json:"newText"`
+}
+
+type lspDiagnostic struct {
+	Range    lspRange `json:"range"`
+	Severity int      `json:"severity"`
+	Source   string   `json:"source"`
+	Message  string   `json:"message"`
+}
+
+// LanguageServer answers LSP requests for the notes in a vault over a
+// single stdio connection. It completes [[wikilinks]] and frontmatter
+// tags, jumps to linked notes, previews them on hover, lists backlinks as
+// references and reports links that resolve to no note.
+type LanguageServer struct {
+	baseDir string
+	in      *bufio.Reader
+	out     io.Writer
+
+	watcher *Watcher
+	notes   []Note
+	index   *LinkIndex
+	// docs holds the text of the documents the client has open, by URI.
+	docs     map[string]string
+	shutdown bool
+}
+
+// ServeLSP runs a language server for baseDir, reading requests from r and
+// writing responses to w until the client sends exit or r is closed.
+func ServeLSP(r io.Reader, w io.Writer, baseDir string) error {
+	// Note paths become file:// URIs, which must be absolute.
+	baseDir, err := filepath.Abs(baseDir)
+	if err != nil {
+		return err
+	}
+	s := &LanguageServer{
+		baseDir: baseDir,
+		in:      bufio.NewReader(r),
+		out:     w,
+		docs:    make(map[string]string),
+	}
+	return s.serve()
+}
+
+func (s *LanguageServer) serve() error {
+	for {
+		data, err := readLSPMessage(s.in)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var msg rpcMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return fmt.Errorf("decoding message: %w", err)
+		}
+
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("exit before shutdown")
+			}
+			return nil
+		}
+		result, rerr := s.handle(msg.Method, msg.Params)
+		if msg.ID == nil {
+			continue
+		}
+		resp := rpcMessage{JSONRPC: "2.0", ID: msg.ID}
+		if rerr != nil {
+			resp.Error = rerr
+		} else {
+			resp.Result = result
+			if result == nil {
+				resp.Result = json.RawMessage("null")
+			}
+		}
+		if err := s.send(resp); err != nil {
+			return err
+		}
+	}
+}
+
+// handle dispatches one request or notification and returns its result.
+func (s *LanguageServer) handle(method string, params json.RawMessage) (any, *rpcError) {
+	switch method {
+	case "initialize":
+		s.rescan()
+		return map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync": map[string]any{
+					"openClose": true,
+					"change":    lspSyncFull,
+					"save":      true,
+				},
+				"completionProvider": map[string]any{"triggerCharacters": []string{"[", " ", ","}},
+				"definitionProvider": true,
+				"hoverProvider":      true,
+				"referencesProvider": true,
+			},
+			"serverInfo": map[string]string{"name": "qn"},
+		}, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var p struct {
+			TextDocument struct {
+				URI  string `json:"uri"`
+				Text string `json:"text"`
+			} `json:"textDocument"`
+		}
+		if json.Unmarshal(params, &p) == nil {
+			s.docs[p.TextDocument.URI] = p.TextDocument.Text
+			s.publishDiagnostics(p.TextDocument.URI)
+		}
+		return nil, nil
+	case "textDocument/didChange":
+		var p struct {
+			TextDocument struct {
+				URI string `json:"uri"`
+			} `json:"textDocument"`
+			ContentChanges []struct {
+				Text string `json:"text"`
+			} `json:"contentChanges"`
+		}
+		if json.Unmarshal(params, &p) == nil && len(p.ContentChanges) > 0 {
+			s.docs[p.TextDocument.URI] = p.ContentChanges[len(p.ContentChanges)-1].Text
+			s.publishDiagnostics(p.TextDocument.URI)
+		}
+		return nil, nil
+	case "textDocument/didSave":
+		// A save can add titles, aliases and links other documents
+		// depend on, so rescan and recheck everything that is open.
+		s.rescan()
+		for uri := range s.docs {
+			s.publishDiagnostics(uri)
+		}
+		return nil, nil
+	case "textDocument/didClose":
+		var p lspTextDocumentPosition
+		if json.Unmarshal(params, &p) == nil {
+			delete(s.docs, p.TextDocument.URI)
+			_ = s.notify("textDocument/publishDiagnostics", map[string]any{
+				"uri":         p.TextDocument.URI,
+				"diagnostics": []lspDiagnostic{},
+			})
+		}
+		return nil, nil
+	case "textDocument/completion", "textDocument/definition", "textDocument/hover", "textDocument/references":
+		var p lspTextDocumentPosition
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+		}
+		switch method {
+		case "textDocument/completion":
+			return s.completion(p), nil
+		case "textDocument/definition":
+			return s.definition(p), nil
+		case "textDocument/hover":
+			return s.hover(p), nil
+		default:
+			return s.references(p), nil
+		}
+	case "initialized", "$/cancelRequest", "$/setTrace", "workspace/didChangeConfiguration":
+		return nil, nil
+	}
+	return nil, &rpcError{Code: rpcMethodNotFound, Message: "method not supported: " + method}
+}
+
+// rescan brings the notes up to date, rereading only files that changed
+// since the last rescan. On failure the previous notes are kept.
+func (s *LanguageServer) rescan() {
+	if s.watcher == nil {
+		w, err := NewWatcher(s.baseDir)
+		if err != nil {
+			return
+		}
+		s.watcher = w
+	} else if _, err := s.watcher.Poll(); err != nil {
+		return
+	}
+	s.notes = s.watcher.Notes()
+	s.index = NewLinkIndex(s.notes)
+}
+
+// text returns the current text of the document at uri, reading it from
+// disk if the client has not opened it.
+func (s *LanguageServer) text(uri string) string {
+	if text, ok := s.docs[uri]; ok {
+		return text
+	}
+	data, err := os.ReadFile(uriToPath(uri))
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func (s *LanguageServer) completion(p lspTextDocumentPosition) []lspCompletionItem {
+	lines := strings.Split(s.text(p.TextDocument.URI), "\n")
+	if p.Position.Line < 0 || p.Position.Line >= len(lines) || p.Position.Character < 0 {
+		return []lspCompletionItem{}
+	}
+	line := lines[p.Position.Line]
+	col := byteColumn(line, p.Position.Character)
+	before := line[:col]
+
+	// Inside an unclosed [[ on this line: complete note titles and aliases.
+	if open := strings.LastIndex(before, "[["); open >= 0 && !strings.Contains(before[open:], "]]") {
+		start := lspPosition{Line: p.Position.Line, Character: utf16Column(line, open+2)}
+		edit := lspRange{Start: start, End: p.Position}
+		closing := "]]"
+		if strings.HasPrefix(line[col:], "]]") {
+			closing = ""
+		}
+		items := []lspCompletionItem{}
+		for _, n := range s.notes {
+			title := noteTitle(n)
+			rel, _ := vaultRelPath(s.baseDir, n.FilePath)
+			items = append(items, lspCompletionItem{
+				Label:    title,
+				Kind:     lspCompletionFile,
+				Detail:   rel,
+				TextEdit: lspTextEdit{Range: edit, NewText: title + closing},
+			})
+			for _, a := range n.Frontmatter.Aliases {
+				items = append(items, lspCompletionItem{
+					Label:    a,
+					Kind:     lspCompletionFile,
+					Detail:   "alias of " + title,
+					TextEdit: lspTextEdit{Range: edit, NewText: a + closing},
+				})
+			}
+		}
+		return items
+	}
+
+	if start, ok := tagCompletionStart(lines, p.Position.Line, col); ok {
+		edit := lspRange{Start: lspPosition{Line: p.Position.Line, Character: utf16Column(line, start)}, End: p.Position}
+		items := []lspCompletionItem{}
+		for _, tc := range countTags(s.notes) {
+			items = append(items, lspCompletionItem{
+				Label:    tc.Tag,
+				Kind:     lspCompletionKeyword,
+				Detail:   fmt.Sprintf("%d notes", tc.Count),
+				TextEdit: lspTextEdit{Range: edit, NewText: tc.Tag},
+			})
+		}
+		return items
+	}
+	return []lspCompletionItem{}
+}
+
+// tagCompletionStart reports whether the cursor at byte col of line is in
+// the frontmatter tags list, either inline (tags: [a, b|) or as a block
+// list item under tags:, and returns where the tag being typed starts.
+func tagCompletionStart(lines []string, line, col int) (int, bool) {
+	end, ok := frontmatterEnd(lines)
+	if !ok || line == 0 || line >= end {
+		return 0, false
+	}
+	before := lines[line][:col]
+	trimmed := strings.TrimSpace(before)
+
+	if rest, ok := strings.CutPrefix(trimmed, "tags:"); ok {
+		if strings.Contains(rest, "]") {
+			return 0, false
+		}
+		start := strings.LastIndexAny(before, "[,:") + 1
+		for start < len(before) && before[start] == ' ' {
+			start++
+		}
+		return start, true
+	}
+
+	if strings.HasPrefix(trimmed, "-") {
+		for i := line - 1; i > 0; i-- {
+			prev := strings.TrimSpace(lines[i])
+			if strings.HasPrefix(prev, "-") {
+				continue
+			}
+			if prev != "tags:" {
+				return 0, false
+			}
+			start := strings.Index(before, "-") + 1
+			for start < len(before) && before[start] == ' ' {
+				start++
+			}
+			return start, true
+		}
+	}
+	return 0, false
+}
+
+// frontmatterEnd returns the line of the closing --- of the frontmatter
+// block that opens lines.
+func frontmatterEnd(lines []string) (int, bool) {
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != "---" {
+		return 0, false
+	}
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == "---" {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func (s *LanguageServer) definition(p lspTextDocumentPosition) []lspLocation {
+	span, ok := s.linkAt(p)
+	if !ok {
+		return []lspLocation{}
+	}
+	target, ok := s.resolve(span.Target)
+	if !ok {
+		return []lspLocation{}
+	}
+	return []lspLocation{{URI: pathToURI(target.FilePath)}}
+}
+
+func (s *LanguageServer) hover(p lspTextDocumentPosition) any {
+	span, ok := s.linkAt(p)
+	if !ok {
+		return nil
+	}
+	target, ok := s.resolve(span.Target)
+	if !ok {
+		return map[string]any{
+			"contents": map[string]string{"kind": "markdown", "value": fmt.Sprintf("No note matches `%s`.", span.Target)},
+			"range":    span.Range,
+		}
+	}
+	return map[string]any{
+		"contents": map[string]string{"kind": "markdown", "value": notePreview(target)},
+		"range":    span.Range,
+	}
+}
+
+// notePreview renders a short markdown summary of n for hovers.
+func notePreview(n Note) string {
+	const maxLines = 15
+	title := noteTitle(n)
+	var b strings.Builder
+	b.WriteString("**" + title + "**")
+	if n.Folder != "" {
+		b.WriteString(" · " + n.Folder)
+	}
+	for _, t := range n.Frontmatter.Tags {
+		b.WriteString(" #" + t)
+	}
+	b.WriteString("\n\n")
+
+	lines := strings.Split(strings.TrimSpace(stripTitleHeading(n.Body, title)), "\n")
+	if len(lines) > maxLines {
+		lines = append(lines[:maxLines], "…")
+	}
+	b.WriteString(strings.Join(lines, "\n"))
+	return b.String()
+}
+
+// references lists every wikilink in other notes that points at the
+// document's note.
+func (s *LanguageServer) references(p lspTextDocumentPosition) []lspLocation {
+	path := uriToPath(p.TextDocument.URI)
+	var self Note
+	found := false
+	for _, n := range s.notes {
+		if samePath(n.FilePath, path) {
+			self, found = n, true
+			break
+		}
+	}
+	// On a wikilink, list references to its target instead.
+	if span, ok := s.linkAt(p); ok {
+		self, found = s.resolve(span.Target)
+	}
+	if !found || s.index == nil {
+		return []lspLocation{}
+	}
+
+	locations := []lspLocation{}
+	for _, b := range s.index.Backlinks(self) {
+		uri := pathToURI(b.FilePath)
+		for _, span := range findWikilinks(s.text(uri)) {
+			if target, ok := s.resolve(span.Target); ok && target.FilePath == self.FilePath {
+				locations = append(locations, lspLocation{URI: uri, Range: span.Range})
+			}
+		}
+	}
+	return locations
+}
+
+func (s *LanguageServer) publishDiagnostics(uri string) {
+	diags := []lspDiagnostic{}
+	for _, span := range findWikilinks(s.docs[uri]) {
+		if _, ok := s.resolve(span.Target); !ok {
+			diags = append(diags, lspDiagnostic{
+				Range:    span.Range,
+				Severity: lspSeverityWarning,
+				Source:   "qn",
+				Message:  fmt.Sprintf("No note matches [[%s]]", span.Target),
+			})
+		}
+	}
+	_ = s.notify("textDocument/publishDiagnostics", map[string]any{"uri": uri, "diagnostics": diags})
+}
+
+func (s *LanguageServer) resolve(target string) (Note, bool) {
+	if s.index == nil {
+		return Note{}, false
+	}
+	return s.index.Resolve(target)
+}
+
+// linkAt returns the wikilink under the cursor.
+func (s *LanguageServer) linkAt(p lspTextDocumentPosition) (wikilinkSpan, bool) {
+	for _, span := range findWikilinks(s.text(p.TextDocument.URI)) {
+		if span.Range.Start.Line != p.Position.Line {
+			continue
+		}
+		if span.Range.Start.Character <= p.Position.Character && p.Position.Character <= span.Range.End.Character {
+			return span, true
+		}
+	}
+	return wikilinkSpan{}, false
+}
+
+// wikilinkSpan is a wikilink and where it appears in a document.
+type wikilinkSpan struct {
+	Target string
+	Range  lspRange
+}
+
+// findWikilinks returns the wikilinks in text with their LSP ranges.
+func findWikilinks(text string) []wikilinkSpan {
+	var spans []wikilinkSpan
+	for i, line := range strings.Split(text, "\n") {
+		for _, m := range wikilinkPattern.FindAllStringSubmatchIndex(line, -1) {
+			target, _ := splitWikilink(line[m[2]:m[3]])
+			if target == "" {
+				continue
+			}
+			spans = append(spans, wikilinkSpan{
+				Target: target,
+				Range: lspRange{
+					Start: lspPosition{Line: i, Character: utf16Column(line, m[0])},
+					End:   lspPosition{Line: i, Character: utf16Column(line, m[1])},
+				},
+			})
+		}
+	}
+	return spans
+}
+
+func (s *LanguageServer) notify(method string, params any) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return s.send(rpcMessage{JSONRPC: "2.0", Method: method, Params: data})
+}
+
+func (s *LanguageServer) send(msg rpcMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(data), data); err != nil {
+		return fmt.Errorf("writing response: %w", err)
+	}
+	return nil
+}
+
+var contentLengthPattern = regexp.MustCompile(`(?i)^content-length:\s*(\d+)\s*$`)
+
+// readLSPMessage reads one Content-Length framed message.
+func readLSPMessage(r *bufio.Reader) ([]byte, error) {
+	length := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			if errors.Is(err, io.EOF) && line == "" && length < 0 {
+				return nil, io.EOF
+			}
+			return nil, fmt.Errorf("reading header: %w", err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		if m := contentLengthPattern.FindStringSubmatch(line); m != nil {
+			length, _ = strconv.Atoi(m[1])
+		}
+	}
+	if length < 0 {
+		return nil, errors.New("message without Content-Length")
+	}
+	if length > maxLSPMessage {
+		return nil, fmt.Errorf("message of %d bytes is too large", length)
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, fmt.Errorf("reading message: %w", err)
+	}
+	return data, nil
+}
+
+// utf16Column converts a byte offset in line to the UTF-16 column LSP
+// positions use.
+func utf16Column(line string, offset int) int {
+	col := 0
+	for _, r := range line[:offset] {
+		if r >= 0x10000 {
+			col += 2
+		} else {
+			col++
+		}
+	}
+	return col
+}
+
+// byteColumn converts an LSP UTF-16 column to a byte offset in line,
+// clamped to the line's length.
+func byteColumn(line string, col int) int {
+	units := 0
+	for i, r := range line {
+		if units >= col {
+			return i
+		}
+		if r >= 0x10000 {
+			units += 2
+		} else {
+			units++
+		}
+	}
+	return len(line)
+}
+
+func pathToURI(path string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(u.Path)
+}
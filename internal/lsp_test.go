@@ -0,0 +1,190 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// lspSession runs ServeLSP over the given requests, followed by shutdown
+// and exit, and returns the responses by id and the notifications.
+func lspSession(t *testing.T, dir string, requests ...map[string]any) (map[int]json.RawMessage, []rpcMessage) {
+	t.Helper()
+	var in bytes.Buffer
+	write := func(msg map[string]any) {
+		msg["jsonrpc"] = "2.0"
+		data, err := json.Marshal(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(data), data)
+	}
+	write(map[string]any{"id": 0, "method": "initialize", "params": map[string]any{}})
+	write(map[string]any{"method": "initialized", "params": map[string]any{}})
+	for _, r := range requests {
+		write(r)
+	}
+	write(map[string]any{"id": 999, "method": "shutdown"})
+	write(map[string]any{"method": "exit"})
+
+	var out bytes.Buffer
+	if err := ServeLSP(&in, &out, dir); err != nil {
+		t.Fatalf("ServeLSP() error: %v", err)
+	}
+
+	responses := make(map[int]json.RawMessage)
+	var notes []rpcMessage
+	r := bufio.NewReader(&out)
+	for {
+		data, err := readLSPMessage(r)
+		if err != nil {
+			break
+		}
+		var msg struct {
+			ID     *int            `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+			Result json.RawMessage `json:"result"`
+			Error  *rpcError       `json:"error"`
+		}
+		if err := json.Unmarshal(data, &msg); err != nil {
+			t.Fatalf("bad response %s: %v", data, err)
+		}
+		switch {
+		case msg.ID != nil && msg.Error != nil:
+			responses[*msg.ID] = json.RawMessage(fmt.Sprintf(`{"error": %d}`, msg.Error.Code))
+		case msg.ID != nil:
+			responses[*msg.ID] = msg.Result
+		default:
+			notes = append(notes, rpcMessage{Method: msg.Method, Params: msg.Params})
+		}
+	}
+	return responses, notes
+}
+
+func position(uri string, line, char int) map[string]any {
+	return map[string]any{
+		"textDocument": map[string]any{"uri": uri},
+		"position":     map[string]any{"line": line, "character": char},
+	}
+}
+
+func TestLanguageServerBadPosition(t *testing.T) {
+	dir := setupServerVault(t)
+	dir, _ = filepath.Abs(dir)
+	uri := pathToURI(filepath.Join(dir, "Inbox", "draft.md"))
+	responses, _ := lspSession(t, dir,
+		map[string]any{"method": "textDocument/didOpen", "params": map[string]any{
+			"textDocument": map[string]any{"uri": uri, "languageId": "markdown", "version": 1, "text": "tags: [fit\n[[Go"},
+		}},
+		map[string]any{"id": 1, "method": "textDocument/completion", "params": position(uri, -1, 2)},
+		map[string]any{"id": 2, "method": "textDocument/completion", "params": position(uri, 1, -3)},
+		map[string]any{"id": 3, "method": "textDocument/completion", "params": position(uri, 9, 0)},
+	)
+	for id := 1; id <= 3; id++ {
+		if string(responses[id]) != "[]" {
+			t.Errorf("completion %d = %s, want []", id, responses[id])
+		}
+	}
+}
+
+func TestLanguageServer(t *testing.T) {
+	dir := setupServerVault(t)
+	dir, _ = filepath.Abs(dir)
+	healthURI := pathToURI(filepath.Join(dir, "Areas", "health.md"))
+	runsURI := pathToURI(filepath.Join(dir, "Inbox", "2026-01-05-runs.md"))
+
+	draft := "---\ntitle: Draft\ntags: [fit\n---\nSee [[Running Log]] and [[Nowhere]].\nNext: [[Go C"
+	draftURI := pathToURI(filepath.Join(dir, "Inbox", "draft.md"))
+
+	responses, notifications := lspSession(t, dir,
+		map[string]any{"method": "textDocument/didOpen", "params": map[string]any{
+			"textDocument": map[string]any{"uri": draftURI, "languageId": "markdown", "version": 1, "text": draft},
+		}},
+		map[string]any{"id": 1, "method": "textDocument/completion", "params": position(draftURI, 5, 12)},
+		map[string]any{"id": 2, "method": "textDocument/completion", "params": position(draftURI, 2, 10)},
+		map[string]any{"id": 3, "method": "textDocument/definition", "params": position(draftURI, 4, 8)},
+		map[string]any{"id": 4, "method": "textDocument/hover", "params": position(draftURI, 4, 8)},
+		map[string]any{"id": 5, "method": "textDocument/references", "params": position(runsURI, 0, 0)},
+		map[string]any{"id": 6, "method": "workspace/symbol", "params": map[string]any{}},
+	)
+
+	var caps struct {
+		Capabilities map[string]any `json:"capabilities"`
+	}
+	if err := json.Unmarshal(responses[0], &caps); err != nil || caps.Capabilities["definitionProvider"] != true {
+		t.Errorf("initialize result = %s", responses[0])
+	}
+
+	var items []lspCompletionItem
+	_ = json.Unmarshal(responses[1], &items)
+	var labels []string
+	for _, it := range items {
+		labels = append(labels, it.Label)
+	}
+	if !containsTag(labels, "Go Concurrency") || !containsTag(labels, "Running Log") {
+		t.Errorf("wikilink completions = %v", labels)
+	}
+	for _, it := range items {
+		if it.Label == "Go Concurrency" {
+			if it.TextEdit.NewText != "Go Concurrency]]" || it.TextEdit.Range.Start.Character != 8 {
+				t.Errorf("wikilink edit = %+v", it.TextEdit)
+			}
+		}
+	}
+
+	items = nil
+	_ = json.Unmarshal(responses[2], &items)
+	if len(items) != 3 || items[0].Label != "fitness" || items[0].TextEdit.Range.Start.Character != 7 {
+		t.Errorf("tag completions = %+v", items)
+	}
+
+	var locs []lspLocation
+	_ = json.Unmarshal(responses[3], &locs)
+	if len(locs) != 1 || locs[0].URI != runsURI {
+		t.Errorf("definition = %s, want %s", responses[3], runsURI)
+	}
+
+	if !strings.Contains(string(responses[4]), "**Running Log**") || !strings.Contains(string(responses[4]), "Five kilometres today.") {
+		t.Errorf("hover = %s", responses[4])
+	}
+
+	locs = nil
+	_ = json.Unmarshal(responses[5], &locs)
+	if len(locs) != 1 || locs[0].URI != healthURI || locs[0].Range.Start.Line != 6 || locs[0].Range.Start.Character != 4 {
+		t.Errorf("references = %s", responses[5])
+	}
+
+	if string(responses[6]) != fmt.Sprintf(`{"error": %d}`, rpcMethodNotFound) {
+		t.Errorf("unsupported method = %s", responses[6])
+	}
+
+	if len(notifications) != 1 || notifications[0].Method != "textDocument/publishDiagnostics" {
+		t.Fatalf("notifications = %+v", notifications)
+	}
+	var diags struct {
+		Diagnostics []lspDiagnostic `json:"diagnostics"`
+	}
+	_ = json.Unmarshal(notifications[0].Params, &diags)
+	if len(diags.Diagnostics) != 1 || !strings.Contains(diags.Diagnostics[0].Message, "[[Nowhere]]") {
+		t.Errorf("diagnostics = %+v", diags.Diagnostics)
+	}
+}
+
+func TestUTF16Columns(t *testing.T) {
+	line := "é 😀 [[x]]"
+	off := strings.Index(line, "[[")
+	if got := utf16Column(line, off); got != 5 {
+		t.Errorf("utf16Column() = %d, want 5", got)
+	}
+	if got := byteColumn(line, 5); got != off {
+		t.Errorf("byteColumn() = %d, want %d", got, off)
+	}
+	if got := byteColumn(line, 100); got != len(line) {
+		t.Errorf("byteColumn(past end) = %d, want %d", got, len(line))
+	}
+}
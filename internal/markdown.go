@@ -0,0 +1,470 @@
+package internal
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// LinkResolver maps a wikilink target to an href. It returns false when the
+// target does not name a note that can be linked to.
+type LinkResolver func(target string) (href string, ok bool)
+
+var (
+	headingLine   = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	ruleLine      = regexp.MustCompile(`^\s*([-*_])(\s*[-*_]){2,}\s*$`)
+	listItemLine  = regexp.MustCompile(`^(\s*)([-*+]|\d+[.)])\s+(.*)$`)
+	tableSepLine  = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+	taskItemStart = regexp.MustCompile(`^\[([ xX])\]\s+`)
+)
+
+// RenderMarkdown converts a note body to HTML. It supports the subset of
+// markdown used in notes: headings, paragraphs, block quotes, ordered,
+// unordered and task lists, fenced code, pipe tables, rules, emphasis,
+// inline code, links, images and [[wikilinks]]. Wikilinks are turned into
+// links with resolve; unresolved ones are rendered as plain text.
+func RenderMarkdown(src string, resolve LinkResolver) string {
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	var b strings.Builder
+	renderBlocks(&b, lines, resolve)
+	return b.String()
+}
+
+func renderBlocks(b *strings.Builder, lines []string, resolve LinkResolver) {
+	var para []string
+	flush := func() {
+		if len(para) > 0 {
+			b.WriteString("<p>" + renderInline(strings.Join(para, "\n"), resolve) + "</p>\n")
+			para = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			flush()
+
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			flush()
+			fence := trimmed[:3]
+			lang := strings.TrimSpace(trimmed[3:])
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence); i++ {
+				code = append(code, lines[i])
+			}
+			if lang != "" {
+				fmt.Fprintf(b, "<pre><code class=\"language-%s\">", html.EscapeString(lang))
+			} else {
+				b.WriteString("<pre><code>")
+			}
+			b.WriteString(html.EscapeString(strings.Join(code, "\n")))
+			if len(code) > 0 {
+				b.WriteString("\n")
+			}
+			b.WriteString("</code></pre>\n")
+
+		case headingLine.MatchString(trimmed):
+			flush()
+			m := headingLine.FindStringSubmatch(trimmed)
+			level := len(m[1])
+			fmt.Fprintf(b, "<h%d id=\"%s\">%s</h%d>\n", level, Slugify(m[2]), renderInline(m[2], resolve), level)
+
+		case ruleLine.MatchString(line):
+			flush()
+			b.WriteString("<hr>\n")
+
+		case strings.HasPrefix(trimmed, ">"):
+			flush()
+			var quoted []string
+			for ; i < len(lines); i++ {
+				t := strings.TrimSpace(lines[i])
+				if !strings.HasPrefix(t, ">") {
+					break
+				}
+				t = strings.TrimPrefix(t, ">")
+				quoted = append(quoted, strings.TrimPrefix(t, " "))
+			}
+			i--
+			b.WriteString("<blockquote>\n")
+			renderBlocks(b, quoted, resolve)
+			b.WriteString("</blockquote>\n")
+
+		case strings.Contains(line, "|") && i+1 < len(lines) && tableSepLine.MatchString(lines[i+1]) && strings.Contains(lines[i+1], "-"):
+			flush()
+			end := i + 2
+			for end < len(lines) && strings.Contains(lines[end], "|") && strings.TrimSpace(lines[end]) != "" {
+				end++
+			}
+			renderTable(b, lines[i], lines[i+1], lines[i+2:end], resolve)
+			i = end - 1
+
+		case listItemLine.MatchString(line):
+			flush()
+			end := listEnd(lines, i)
+			renderList(b, lines[i:end], resolve)
+			i = end - 1
+
+		default:
+			para = append(para, trimmed)
+		}
+	}
+	flush()
+}
+
+// listEnd returns the index just past the list starting at lines[start].
+// Indented lines and blank lines followed by more list content belong to
+// the list.
+func listEnd(lines []string, start int) int {
+	i := start + 1
+	for i < len(lines) {
+		line := lines[i]
+		if strings.TrimSpace(line) == "" {
+			next := i + 1
+			for next < len(lines) && strings.TrimSpace(lines[next]) == "" {
+				next++
+			}
+			if next < len(lines) && (listItemLine.MatchString(lines[next]) || indentOf(lines[next]) >= 2) {
+				i = next
+				continue
+			}
+			return i
+		}
+		if !listItemLine.MatchString(line) && indentOf(line) < 2 {
+			return i
+		}
+		i++
+	}
+	return i
+}
+
+func renderList(b *strings.Builder, lines []string, resolve LinkResolver) {
+	first := listItemLine.FindStringSubmatch(lines[0])
+	baseIndent := len(first[1])
+	ordered := first[2] != "-" && first[2] != "*" && first[2] != "+"
+
+	tag := "ul"
+	if ordered {
+		tag = "ol"
+	}
+	b.WriteString("<" + tag + ">\n")
+
+	type item struct {
+		text string
+		rest []string
+	}
+	var items []item
+	for _, line := range lines {
+		if m := listItemLine.FindStringSubmatch(line); m != nil && len(m[1]) <= baseIndent {
+			items = append(items, item{text: m[3]})
+			continue
+		}
+		if len(items) == 0 {
+			continue
+		}
+		last := &items[len(items)-1]
+		last.rest = append(last.rest, dedent(line, baseIndent+2))
+	}
+
+	for _, it := range items {
+		text := it.text
+		if m := taskItemStart.FindStringSubmatch(text); m != nil {
+			checked := ""
+			if m[1] != " " {
+				checked = " checked"
+			}
+			b.WriteString("<li class=\"task\"><input type=\"checkbox\" disabled" + checked + "> ")
+			text = text[len(m[0]):]
+		} else if strings.TrimSpace(text) == "[ ]" || strings.EqualFold(strings.TrimSpace(text), "[x]") {
+			checked := ""
+			if strings.TrimSpace(text) != "[ ]" {
+				checked = " checked"
+			}
+			b.WriteString("<li class=\"task\"><input type=\"checkbox\" disabled" + checked + ">")
+			text = ""
+		} else {
+			b.WriteString("<li>")
+		}
+		b.WriteString(renderInline(text, resolve))
+
+		if strings.TrimSpace(strings.Join(it.rest, "")) != "" {
+			b.WriteString("\n")
+			renderBlocks(b, it.rest, resolve)
+		}
+		b.WriteString("</li>\n")
+	}
+	b.WriteString("</" + tag + ">\n")
+}
+
+func renderTable(b *strings.Builder, header, sep string, rows []string, resolve LinkResolver) {
+	var aligns []string
+	for _, cell := range splitTableRow(sep) {
+		left := strings.HasPrefix(cell, ":")
+		right := strings.HasSuffix(cell, ":")
+		switch {
+		case left && right:
+			aligns = append(aligns, "center")
+		case right:
+			aligns = append(aligns, "right")
+		case left:
+			aligns = append(aligns, "left")
+		default:
+			aligns = append(aligns, "")
+		}
+	}
+
+	writeRow := func(cells []string, cellTag string) {
+		b.WriteString("<tr>")
+		for i := range aligns {
+			cell := ""
+			if i < len(cells) {
+				cell = cells[i]
+			}
+			if aligns[i] != "" {
+				fmt.Fprintf(b, "<%s style=\"text-align: %s\">", cellTag, aligns[i])
+			} else {
+				b.WriteString("<" + cellTag + ">")
+			}
+			b.WriteString(renderInline(cell, resolve) + "</" + cellTag + ">")
+		}
+		b.WriteString("</tr>\n")
+	}
+
+	b.WriteString("<table>\n<thead>\n")
+	writeRow(splitTableRow(header), "th")
+	b.WriteString("</thead>\n")
+	if len(rows) > 0 {
+		b.WriteString("<tbody>\n")
+		for _, row := range rows {
+			writeRow(splitTableRow(row), "td")
+		}
+		b.WriteString("</tbody>\n")
+	}
+	b.WriteString("</table>\n")
+}
+
+// splitTableRow splits a pipe table row into trimmed cells.
+func splitTableRow(row string) []string {
+	row = strings.TrimSpace(row)
+	row = strings.TrimPrefix(row, "|")
+	row = strings.TrimSuffix(row, "|")
+	cells := strings.Split(row, "|")
+	for i, c := range cells {
+		cells[i] = strings.TrimSpace(c)
+	}
+	return cells
+}
+
+// renderInline renders emphasis, code spans, links, images, wikilinks and
+// bare URLs within a block of text, escaping everything else.
+func renderInline(s string, resolve LinkResolver) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		rest := s[i:]
+		switch {
+		case rest[0] == '\\' && len(rest) > 1 && strings.ContainsRune("\\`*_[]()#!|<>", rune(rest[1])):
+			b.WriteString(html.EscapeString(rest[1:2]))
+			i += 2
+			continue
+
+		case rest[0] == '`':
+			if end := strings.Index(rest[1:], "`"); end >= 0 {
+				b.WriteString("<code>" + html.EscapeString(rest[1:1+end]) + "</code>")
+				i += end + 2
+				continue
+			}
+
+		case strings.HasPrefix(rest, "[["):
+			if end := strings.Index(rest, "]]"); end > 2 {
+				target, label := splitWikilink(rest[2:end])
+				if href, ok := resolveLink(resolve, target); ok {
+					b.WriteString("<a class=\"wikilink\" href=\"" + html.EscapeString(href) + "\">" + html.EscapeString(label) + "</a>")
+				} else {
+					b.WriteString("<span class=\"wikilink missing\">" + html.EscapeString(label) + "</span>")
+				}
+				i += end + 2
+				continue
+			}
+
+		case strings.HasPrefix(rest, "!["):
+			if text, url, n, ok := parseLink(rest[1:]); ok {
+				b.WriteString("<img src=\"" + html.EscapeString(safeURL(url)) + "\" alt=\"" + html.EscapeString(text) + "\">")
+				i += n + 1
+				continue
+			}
+
+		case rest[0] == '[':
+			if text, url, n, ok := parseLink(rest); ok {
+				b.WriteString("<a href=\"" + html.EscapeString(safeURL(url)) + "\">" + renderInline(text, resolve) + "</a>")
+				i += n
+				continue
+			}
+
+		case rest[0] == '<' && (strings.HasPrefix(rest, "<http://") || strings.HasPrefix(rest, "<https://")):
+			if end := strings.Index(rest, ">"); end > 0 {
+				url := rest[1:end]
+				b.WriteString("<a href=\"" + html.EscapeString(url) + "\">" + html.EscapeString(url) + "</a>")
+				i += end + 1
+				continue
+			}
+
+		case (strings.HasPrefix(rest, "http://") || strings.HasPrefix(rest, "https://")) && (i == 0 || !isWordByte(s[i-1])):
+			url := bareURL(rest)
+			b.WriteString("<a href=\"" + html.EscapeString(url) + "\">" + html.EscapeString(url) + "</a>")
+			i += len(url)
+			continue
+
+		case strings.HasPrefix(rest, "**") || strings.HasPrefix(rest, "__"):
+			delim := rest[:2]
+			if end := strings.Index(rest[2:], delim); end > 0 {
+				b.WriteString("<strong>" + renderInline(rest[2:2+end], resolve) + "</strong>")
+				i += end + 4
+				continue
+			}
+
+		case strings.HasPrefix(rest, "~~"):
+			if end := strings.Index(rest[2:], "~~"); end > 0 {
+				b.WriteString("<del>" + renderInline(rest[2:2+end], resolve) + "</del>")
+				i += end + 4
+				continue
+			}
+
+		case rest[0] == '*' || (rest[0] == '_' && (i == 0 || !isWordByte(s[i-1]))):
+			delim := rest[:1]
+			if end := strings.Index(rest[1:], delim); end > 0 && rest[1] != ' ' {
+				after := 1 + end + 1
+				if delim == "*" || after >= len(rest) || !isWordByte(rest[after]) {
+					b.WriteString("<em>" + renderInline(rest[1:1+end], resolve) + "</em>")
+					i += after
+					continue
+				}
+			}
+
+		case rest[0] == '\n':
+			b.WriteString("\n")
+			i++
+			continue
+		}
+
+		b.WriteString(html.EscapeString(rest[:1]))
+		i++
+	}
+	return b.String()
+}
+
+// parseLink parses "[text](url)" at the start of s and returns the text,
+// the URL and the number of bytes consumed.
+func parseLink(s string) (text, url string, n int, ok bool) {
+	depth := 0
+	closeText := -1
+	for i := 0; i < len(s); i++ {
+		if s[i] == '[' {
+			depth++
+		} else if s[i] == ']' {
+			depth--
+			if depth == 0 {
+				closeText = i
+				break
+			}
+		}
+	}
+	if closeText < 0 || closeText+1 >= len(s) || s[closeText+1] != '(' {
+		return "", "", 0, false
+	}
+	end := strings.Index(s[closeText+2:], ")")
+	if end < 0 {
+		return "", "", 0, false
+	}
+	url = strings.TrimSpace(s[closeText+2 : closeText+2+end])
+	if sp := strings.IndexAny(url, " \t"); sp >= 0 {
+		// Drop an optional "title" after the URL.
+		url = url[:sp]
+	}
+	return s[1:closeText], url, closeText + 2 + end + 1, true
+}
+
+// bareURL returns the URL at the start of s, without trailing punctuation.
+func bareURL(s string) string {
+	end := strings.IndexAny(s, " \t\n<>\"")
+	if end < 0 {
+		end = len(s)
+	}
+	url := s[:end]
+	for len(url) > 0 && strings.ContainsRune(".,;:!?)]'", rune(url[len(url)-1])) {
+		if url[len(url)-1] == ')' && strings.Count(url, "(") >= strings.Count(url, ")") {
+			break
+		}
+		url = url[:len(url)-1]
+	}
+	return url
+}
+
+// safeSchemes are the URL schemes links and images may use. Relative and
+// fragment links have no scheme and are always allowed.
+var safeSchemes = map[string]bool{"http": true, "https": true, "mailto": true}
+
+// safeURL replaces url with "#" unless it is relative or uses one of
+// safeSchemes. The scheme is read the way a browser would, after decoding
+// entities and dropping the whitespace and control characters browsers
+// ignore, so "&#106;avascript:" and "java\tscript:" are caught too.
+func safeURL(url string) string {
+	decoded := strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, html.UnescapeString(url))
+	end := strings.IndexAny(decoded, ":/?#")
+	if end < 0 || decoded[end] != ':' {
+		return url
+	}
+	if !safeSchemes[strings.ToLower(decoded[:end])] {
+		return "#"
+	}
+	return url
+}
+
+func resolveLink(resolve LinkResolver, target string) (string, bool) {
+	if resolve == nil {
+		return "", false
+	}
+	return resolve(target)
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func indentOf(line string) int {
+	n := 0
+	for _, r := range line {
+		switch r {
+		case ' ':
+			n++
+		case '\t':
+			n += 4
+		default:
+			return n
+		}
+	}
+	return n
+}
+
+// dedent removes up to n columns of leading whitespace from line.
+func dedent(line string, n int) string {
+	for n > 0 && len(line) > 0 {
+		switch line[0] {
+		case ' ':
+			n--
+		case '\t':
+			n -= 4
+		default:
+			return line
+		}
+		line = line[1:]
+	}
+	return line
+}
@@ -0,0 +1,62 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	resolve := func(target string) (string, bool) {
+		if target == "Go Tips" {
+			return "go-tips.html", true
+		}
+		return "", false
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"heading", "## Tasks & Notes", []string{`<h2 id="tasks-notes">Tasks &amp; Notes</h2>`}},
+		{"paragraph escapes html", "a <b> c", []string{"<p>a &lt;b&gt; c</p>"}},
+		{"emphasis", "**bold** and *em* and `x<y`", []string{"<strong>bold</strong>", "<em>em</em>", "<code>x&lt;y</code>"}},
+		{"snake case is not emphasis", "a_b_c", []string{"<p>a_b_c</p>"}},
+		{"link", "[docs](https://example.com/a)", []string{`<a href="https://example.com/a">docs</a>`}},
+		{"script link is neutralized", "[x](javascript:alert(1))", []string{`<a href="#">x</a>`}},
+		{"encoded script link is neutralized", "[x](&#106;avascript:alert(1))", []string{`<a href="#">x</a>`}},
+		{"script link with a tab is neutralized", "[x](java&#9;script:alert(1))", []string{`<a href="#">x</a>`}},
+		{"other schemes are neutralized", "[x](file:///etc/passwd) ![y](data:image/svg+xml,x)", []string{`<a href="#">x</a>`, `<img src="#" alt="y">`}},
+		{"safe links are kept", "[a](https://go.dev) [b](mailto:me@example.com) [c](../Areas/x.html#top) [d](#intro)", []string{`href="https://go.dev"`, `href="mailto:me@example.com"`, `href="../Areas/x.html#top"`, `href="#intro"`}},
+		{"bare url", "see https://example.com/x.", []string{`<a href="https://example.com/x">https://example.com/x</a>.`}},
+		{"wikilink", "read [[Go Tips|tips]]", []string{`<a class="wikilink" href="go-tips.html">tips</a>`}},
+		{"missing wikilink", "read [[Secret]]", []string{`<span class="wikilink missing">Secret</span>`}},
+		{"unordered list", "- one\n- two", []string{"<ul>\n<li>one</li>\n<li>two</li>\n</ul>"}},
+		{"ordered list", "1. one\n2. two", []string{"<ol>\n<li>one</li>\n<li>two</li>\n</ol>"}},
+		{"nested list", "- one\n  - inner\n- two", []string{"<li>one\n<ul>\n<li>inner</li>\n</ul>\n</li>"}},
+		{"task list", "- [ ] todo\n- [x] done\n- [ ]", []string{
+			`<li class="task"><input type="checkbox" disabled> todo</li>`,
+			`<li class="task"><input type="checkbox" disabled checked> done</li>`,
+			`<li class="task"><input type="checkbox" disabled></li>`,
+		}},
+		{"list interrupts paragraph", "Tasks:\n- a", []string{"<p>Tasks:</p>\n<ul>"}},
+		{"code fence", "```go\nif a < b {}\n```", []string{`<pre><code class="language-go">if a &lt; b {}` + "\n</code></pre>"}},
+		{"table", "| A | B |\n|:--|--:|\n| 1 | 2 |", []string{
+			`<th style="text-align: left">A</th><th style="text-align: right">B</th>`,
+			`<td style="text-align: left">1</td><td style="text-align: right">2</td>`,
+		}},
+		{"blockquote", "> quoted **text**", []string{"<blockquote>\n<p>quoted <strong>text</strong></p>\n</blockquote>"}},
+		{"rule", "---", []string{"<hr>"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RenderMarkdown(tt.input, resolve)
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("RenderMarkdown(%q) =\n%s\nwant it to contain:\n%s", tt.input, got, want)
+				}
+			}
+		})
+	}
+}
@@ -2,10 +2,15 @@ package internal
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -16,6 +21,20 @@ type Frontmatter struct {
 	Tags    []string
 	Status  string
 	Aliases []string
+	// Created and Updated are RFC 3339 timestamps of when the note was
+	// first written and last changed, maintained by qn's write commands.
+	Created string
+	Updated string
+	// Extra holds any other fields in the order they appeared, so they
+	// survive a parse and format round trip.
+	Extra []Field
+}
+
+// Field is a frontmatter field qn does not interpret. Value is the raw
+// text after "key:", including any indented continuation lines.
+type Field struct {
+	Key   string
+	Value string
 }
 
 // Note represents a parsed note file.
@@ -58,11 +77,26 @@ func ParseFrontmatterFromBytes(data []byte) (Frontmatter, string, error) {
 	}
 
 	fm := Frontmatter{}
+	lastKey := ""
 	for _, line := range lines[1:endIdx] {
+		if isYAMLContinuation(line) {
+			item, isItem := strings.CutPrefix(strings.TrimSpace(line), "- ")
+			switch {
+			case lastKey == "tags" && isItem:
+				fm.Tags = append(fm.Tags, unquote(strings.TrimSpace(item)))
+			case lastKey == "aliases" && isItem:
+				fm.Aliases = append(fm.Aliases, unquote(strings.TrimSpace(item)))
+			case len(fm.Extra) > 0 && fm.Extra[len(fm.Extra)-1].Key == lastKey:
+				f := &fm.Extra[len(fm.Extra)-1]
+				f.Value += "\n" + line
+			}
+			continue
+		}
 		key, value, ok := parseYAMLLine(line)
 		if !ok {
 			continue
 		}
+		lastKey = key
 		switch key {
 		case "title":
 			fm.Title = unquote(value)
@@ -74,6 +108,12 @@ func ParseFrontmatterFromBytes(data []byte) (Frontmatter, string, error) {
 			fm.Status = value
 		case "aliases":
 			fm.Aliases = parseYAMLList(value)
+		case "created":
+			fm.Created = unquote(value)
+		case "updated":
+			fm.Updated = unquote(value)
+		default:
+			fm.Extra = append(fm.Extra, Field{Key: key, Value: value})
 		}
 	}
 
@@ -94,16 +134,71 @@ func FormatFrontmatter(fm Frontmatter) string {
 	b.WriteString(fmt.Sprintf("tags: [%s]\n", strings.Join(fm.Tags, ", ")))
 	b.WriteString(fmt.Sprintf("status: %s\n", fm.Status))
 	b.WriteString(fmt.Sprintf("aliases: [%s]\n", strings.Join(fm.Aliases, ", ")))
+	if fm.Created != "" {
+		b.WriteString("created: " + fm.Created + "\n")
+	}
+	if fm.Updated != "" {
+		b.WriteString("updated: " + fm.Updated + "\n")
+	}
+	for _, f := range fm.Extra {
+		if f.Value == "" || strings.HasPrefix(f.Value, "\n") {
+			b.WriteString(f.Key + ":" + f.Value + "\n")
+		} else {
+			b.WriteString(f.Key + ": " + f.Value + "\n")
+		}
+	}
 	b.WriteString("---\n")
 	return b.String()
 }
 
-// ScanNotes reads all notes from the standard PARA folders under baseDir.
+// NoteFolders lists every folder ScanNotes reads: the PARA folders offered
+// by Create plus Archive.
+var NoteFolders = []string{"Inbox", "Projects", "Areas", "Resources", "Archive"}
+
+// ScanNotes reads all notes from the standard PARA folders under baseDir,
+// parsing files in parallel on GOMAXPROCS workers.
 func ScanNotes(baseDir string) ([]Note, error) {
-	folders := []string{"Inbox", "Projects", "Areas", "Resources", "Archive"}
-	var notes []Note
+	return ScanNotesContext(context.Background(), baseDir, ScanOptions{})
+}
+
+// ScanOptions configures ScanNotesContext.
+type ScanOptions struct {
+	// Workers is how many files are read and parsed at once. Zero means
+	// GOMAXPROCS.
+	Workers int
+}
+
+// scanJob is a note file waiting to be read, and its result.
+type scanJob struct {
+	entry  os.DirEntry
+	path   string
+	folder string
+	note   Note
+	// err is why the file could not be read; the note is skipped.
+	err error
+	// problems and titleLine are only filled in by checked scans.
+	problems  []Problem
+	titleLine int
+}
+
+// ScanNotesContext is ScanNotes with the reading and parsing spread over a
+// bounded pool of workers. Notes are returned in the same order as a
+// sequential scan: by folder, then by filename. It stops early and returns
+// ctx.Err() when ctx is cancelled.
+func ScanNotesContext(ctx context.Context, baseDir string, opts ScanOptions) ([]Note, error) {
+	jobs, err := scanJobs(ctx, baseDir, opts, nil)
+	if err != nil {
+		return nil, err
+	}
+	return jobNotes(jobs), nil
+}
 
-	for _, folder := range folders {
+// scanJobs lists the note files under baseDir and reads them on a pool of
+// workers. When schema is not nil each file is also run through
+// checkNoteData with the date format of its folder.
+func scanJobs(ctx context.Context, baseDir string, opts ScanOptions, schema Schema) ([]scanJob, error) {
+	var jobs []scanJob
+	for _, folder := range NoteFolders {
 		dir := filepath.Join(baseDir, folder)
 		entries, err := os.ReadDir(dir)
 		if err != nil {
@@ -116,26 +211,193 @@ func ScanNotes(baseDir string) ([]Note, error) {
 			if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".md") {
 				continue
 			}
-			path := filepath.Join(dir, entry.Name())
-			fm, body, err := ParseFrontmatter(path)
-			if err != nil {
-				continue
+			jobs = append(jobs, scanJob{entry: entry, path: filepath.Join(dir, entry.Name()), folder: folder})
+		}
+	}
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	workers = min(workers, len(jobs))
+
+	// Each worker claims the next unread job, so results land in their
+	// own slots and keep the scan order without any sorting.
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				i := int(next.Add(1)) - 1
+				if i >= len(jobs) {
+					return
+				}
+				readNote(&jobs[i], schema)
 			}
-			info, err := entry.Info()
-			if err != nil {
-				continue
+		}()
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// jobNotes returns the notes of the jobs that could be read.
+func jobNotes(jobs []scanJob) []Note {
+	notes := make([]Note, 0, len(jobs))
+	for _, j := range jobs {
+		if j.err == nil {
+			notes = append(notes, j.note)
+		}
+	}
+	return notes
+}
+
+// readNote reads and parses the file of j, recording in j.err why it
+// could not be read.
+func readNote(j *scanJob, schema Schema) {
+	info, err := j.entry.Info()
+	if err != nil {
+		j.err = err
+		return
+	}
+	data, err := os.ReadFile(j.path)
+	if err != nil {
+		j.err = err
+		return
+	}
+	fm, body, err := ParseFrontmatterFromBytes(data)
+	if err != nil {
+		j.err = err
+		return
+	}
+	if schema != nil {
+		j.problems, j.titleLine = checkNoteData(data, schema[j.folder].DateFormat)
+	}
+	j.note = Note{
+		Frontmatter: fm,
+		Body:        body,
+		FilePath:    j.path,
+		Folder:      j.folder,
+		ModTime:     info.ModTime(),
+	}
+}
+
+// LookupNote finds the single note identified by query, which may be a
+// path (absolute or relative to baseDir), a filename, a slug without its
+// date prefix, a title or an alias. It is an error if nothing or more than
+// one note matches.
+func LookupNote(baseDir, query string) (Note, error) {
+	notes, err := ScanNotes(baseDir)
+	if err != nil {
+		return Note{}, err
+	}
+
+	return pickNote(notes, baseDir, query)
+}
+
+// pickNote is LookupNote over notes that have already been scanned.
+func pickNote(notes []Note, baseDir, query string) (Note, error) {
+	matches := matchNotes(notes, baseDir, query)
+	switch len(matches) {
+	case 0:
+		return Note{}, fmt.Errorf("%w %q", ErrNoteNotFound, query)
+	case 1:
+		return matches[0], nil
+	}
+	return Note{}, &AmbiguousNoteError{Query: query, Matches: matches}
+}
+
+// ErrNoteNotFound is returned by LookupNote when nothing matches the query.
+var ErrNoteNotFound = errors.New("no note matches")
+
+// AmbiguousNoteError is returned by LookupNote when the query matches more
+// than one note.
+type AmbiguousNoteError struct {
+	Query   string
+	Matches []Note
+}
+
+func (e *AmbiguousNoteError) Error() string {
+	paths := make([]string, 0, len(e.Matches))
+	for _, n := range e.Matches {
+		paths = append(paths, n.FilePath)
+	}
+	return fmt.Sprintf("%q matches %d notes:\n  %s", e.Query, len(e.Matches), strings.Join(paths, "\n  "))
+}
+
+// matchNotes returns the notes matching query, trying paths, filenames,
+// slugs, titles and aliases in that order and stopping at the first kind that
+// matches anything.
+func matchNotes(notes []Note, baseDir, query string) []Note {
+	q := strings.TrimSpace(query)
+	if q == "" {
+		return nil
+	}
+	withExt := q
+	if !strings.HasSuffix(withExt, ".md") {
+		withExt += ".md"
+	}
+
+	tiers := []func(Note) bool{
+		func(n Note) bool {
+			return samePath(n.FilePath, withExt) || samePath(n.FilePath, filepath.Join(baseDir, withExt))
+		},
+		func(n Note) bool { return filepath.Base(n.FilePath) == withExt },
+		func(n Note) bool { return NoteSlug(n.FilePath) == strings.ToLower(strings.TrimSuffix(q, ".md")) },
+		func(n Note) bool { return strings.EqualFold(n.Frontmatter.Title, q) },
+		func(n Note) bool {
+			for _, a := range n.Frontmatter.Aliases {
+				if strings.EqualFold(a, q) {
+					return true
+				}
+			}
+			return false
+		},
+	}
+	for _, match := range tiers {
+		var found []Note
+		for _, n := range notes {
+			if match(n) {
+				found = append(found, n)
 			}
-			notes = append(notes, Note{
-				Frontmatter: fm,
-				Body:        body,
-				FilePath:    path,
-				Folder:      folder,
-				ModTime:     info.ModTime(),
-			})
+		}
+		if len(found) > 0 {
+			return found
+		}
+	}
+	return nil
+}
+
+// NoteSlug returns the slug part of a note's filename, without the ".md"
+// extension or a leading YYYY-MM-DD- date prefix.
+func NoteSlug(path string) string {
+	name := strings.TrimSuffix(filepath.Base(path), ".md")
+	if len(name) > len("2006-01-02-") {
+		if _, err := time.Parse("2006-01-02", name[:10]); err == nil && name[10] == '-' {
+			return name[11:]
 		}
 	}
+	return name
+}
+
+// samePath reports whether a and b refer to the same file path.
+func samePath(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	return absA == absB
+}
 
-	return notes, nil
+// isYAMLContinuation reports whether line continues the previous field,
+// as the items of a block list or the keys of a nested map do.
+func isYAMLContinuation(line string) bool {
+	return strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") || strings.HasPrefix(line, "- ")
 }
 
 // parseYAMLLine splits a simple "key: value" YAML line.
@@ -160,7 +422,7 @@ func parseYAMLList(s string) []string {
 	parts := strings.Split(s, ",")
 	var result []string
 	for _, p := range parts {
-		p = strings.TrimSpace(p)
+		p = unquote(strings.TrimSpace(p))
 		if p != "" {
 			result = append(result, p)
 		}
@@ -1,6 +1,9 @@
 package internal
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
@@ -57,6 +60,23 @@ Body.
 			},
 			wantBody: "\nBody.\n",
 		},
+		{
+			name: "block lists and quoted items",
+			input: `---
+title: "Lists"
+tags:
+  - go
+  - "cli"
+aliases: ["qn", quick note]
+---
+`,
+			wantFM: Frontmatter{
+				Title:   "Lists",
+				Tags:    []string{"go", "cli"},
+				Aliases: []string{"qn", "quick note"},
+			},
+			wantBody: "",
+		},
 		{
 			name:     "no frontmatter",
 			input:    "Just some text without frontmatter.\n",
@@ -221,3 +241,181 @@ func sliceEqual(a, b []string) bool {
 	}
 	return true
 }
+
+func TestLookupNote(t *testing.T) {
+	dir := setupFindTestDir(t)
+	dup := "---\ntitle: \"Golang Tips\"\n---\n"
+	if err := os.WriteFile(filepath.Join(dir, "Archive", "old-golang-tips.md"), []byte(dup), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		wantFile string
+		wantErr  bool
+	}{
+		{"relative path", "Inbox/2026-02-12-python-basics.md", "2026-02-12-python-basics.md", false},
+		{"filename without extension", "cooking-recipes", "cooking-recipes.md", false},
+		{"slug without date prefix", "python-basics", "2026-02-12-python-basics.md", false},
+		{"title", "cooking recipes", "cooking-recipes.md", false},
+		{"slug wins over duplicate title", "golang-tips", "golang-tips.md", false},
+		{"ambiguous title", "Golang Tips", "", true},
+		{"no match", "nothing-here", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			note, err := LookupNote(dir, tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LookupNote(%q) error = %v, wantErr %v", tt.query, err, tt.wantErr)
+			}
+			if !tt.wantErr && filepath.Base(note.FilePath) != tt.wantFile {
+				t.Errorf("LookupNote(%q) = %s, want %s", tt.query, note.FilePath, tt.wantFile)
+			}
+		})
+	}
+}
+
+func TestNoteSlug(t *testing.T) {
+	tests := map[string]string{
+		"Inbox/2026-02-13-topic.md": "topic",
+		"Resources/topic.md":        "topic",
+		"Areas/2026-notes.md":       "2026-notes",
+		"Inbox/2026-02-13.md":       "2026-02-13",
+	}
+	for path, want := range tests {
+		if got := NoteSlug(path); got != want {
+			t.Errorf("NoteSlug(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestFrontmatterExtraRoundTrip(t *testing.T) {
+	input := `---
+title: "Reading List"
+date: 2026-02-13
+source: obsidian
+tags: [books]
+cover:
+  url: https://example.com/cover.png
+  width: 200
+status: draft
+aliases: []
+related:
+- one
+- two
+---
+Body.
+`
+	fm, body, err := ParseFrontmatterFromBytes([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Field{
+		{Key: "source", Value: "obsidian"},
+		{Key: "cover", Value: "\n  url: https://example.com/cover.png\n  width: 200"},
+		{Key: "related", Value: "\n- one\n- two"},
+	}
+	if len(fm.Extra) != len(want) {
+		t.Fatalf("Extra = %+v, want %+v", fm.Extra, want)
+	}
+	for i := range want {
+		if fm.Extra[i] != want[i] {
+			t.Errorf("Extra[%d] = %+v, want %+v", i, fm.Extra[i], want[i])
+		}
+	}
+
+	again, againBody, err := ParseFrontmatterFromBytes([]byte(FormatFrontmatter(fm) + body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if againBody != body {
+		t.Errorf("body after round trip = %q, want %q", againBody, body)
+	}
+	if len(again.Extra) != len(want) {
+		t.Fatalf("Extra after round trip = %+v, want %+v", again.Extra, want)
+	}
+	for i := range want {
+		if again.Extra[i] != want[i] {
+			t.Errorf("Extra[%d] after round trip = %+v, want %+v", i, again.Extra[i], want[i])
+		}
+	}
+}
+
+// writeNoteFixture writes n generated notes spread over the PARA folders.
+func writeNoteFixture(tb testing.TB, dir string, n int) {
+	tb.Helper()
+	for _, folder := range NoteFolders {
+		if err := os.MkdirAll(filepath.Join(dir, folder), 0o755); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	for i := range n {
+		folder := NoteFolders[i%len(NoteFolders)]
+		content := fmt.Sprintf("---\ntitle: \"Note %d\"\ndate: 2026-01-%02d\ntags: [t%d, bench]\nstatus: draft\n---\n\n# Note %d\n\nSee [[Note %d]] for more.\n\n- one\n- two\n", i, i%28+1, i%50, i, (i+1)%n)
+		if err := os.WriteFile(filepath.Join(dir, folder, fmt.Sprintf("note-%05d.md", i)), []byte(content), 0o644); err != nil {
+			tb.Fatal(err)
+		}
+	}
+}
+
+func TestScanNotesContextMatchesSequential(t *testing.T) {
+	dir := t.TempDir()
+	writeNoteFixture(t, dir, 500)
+
+	sequential, err := ScanNotesContext(context.Background(), dir, ScanOptions{Workers: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sequential) != 500 {
+		t.Fatalf("got %d notes, want 500", len(sequential))
+	}
+	for _, workers := range []int{0, 3, 64} {
+		notes, err := ScanNotesContext(context.Background(), dir, ScanOptions{Workers: workers})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(notes) != len(sequential) {
+			t.Fatalf("workers=%d: got %d notes, want %d", workers, len(notes), len(sequential))
+		}
+		for i := range notes {
+			if notes[i].FilePath != sequential[i].FilePath || notes[i].Frontmatter.Title != sequential[i].Frontmatter.Title {
+				t.Fatalf("workers=%d: note %d = %s, want %s", workers, i, notes[i].FilePath, sequential[i].FilePath)
+			}
+		}
+	}
+}
+
+func TestScanNotesContextCancelled(t *testing.T) {
+	dir := t.TempDir()
+	writeNoteFixture(t, dir, 50)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := ScanNotesContext(ctx, dir, ScanOptions{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("ScanNotesContext() error = %v, want context.Canceled", err)
+	}
+}
+
+// BenchmarkScanNotes compares a single worker with pools of four and
+// GOMAXPROCS workers over 50,000 notes. Generating the fixture takes a few
+// seconds.
+func BenchmarkScanNotes(b *testing.B) {
+	dir := b.TempDir()
+	writeNoteFixture(b, dir, 50000)
+
+	for _, workers := range []int{1, 4, 0} {
+		name := fmt.Sprintf("workers=%d", workers)
+		if workers == 0 {
+			name = "workers=GOMAXPROCS"
+		}
+		b.Run(name, func(b *testing.B) {
+			for b.Loop() {
+				if _, err := ScanNotesContext(context.Background(), dir, ScanOptions{Workers: workers}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxOpenChoices is how many notes Open offers when a query is ambiguous.
+const maxOpenChoices = 9
+
+// Open opens the note matching query in the user's editor. The query is
+// matched like LookupNote: a path, filename, slug, title or alias. When
+// none of those match, the best Find results are used instead and the
+// note opens at the first line containing the query. If several notes
+// match, p asks which one to open.
+func Open(p *Prompter, baseDir, query string) error {
+	note, line, err := resolveNote(p, baseDir, query, false, false)
+	if err != nil {
+		return err
+	}
+	return runEditor(note.FilePath, line)
+}
+
+// OpenFound opens one of the notes Find lists for query with opts, asking
+// p which when there are several, at the first line containing the query.
+// opts.Format is ignored.
+func OpenFound(p *Prompter, baseDir, query string, opts FindOptions) error {
+	note, line, err := resolveNote(p, baseDir, query, true, opts.Strict)
+	if err != nil {
+		return err
+	}
+	return runEditor(note.FilePath, line)
+}
+
+// resolveNote finds the note for query and the line to open it at. Unless
+// searchOnly is set, exact matches are tried before Find's scoring. p is
+// asked to choose between several matches. strict fails like Find's
+// FindOptions.Strict when any note has a problem.
+func resolveNote(p *Prompter, baseDir, query string, searchOnly, strict bool) (Note, int, error) {
+	scan := ScanNotes
+	if strict {
+		scan = scanStrict
+	}
+	notes, err := scan(baseDir)
+	if err != nil {
+		return Note{}, 0, err
+	}
+
+	var matches []Note
+	if !searchOnly {
+		matches = matchNotes(notes, baseDir, query)
+	}
+	searched := len(matches) == 0
+	q := strings.ToLower(strings.TrimSpace(query))
+	if searched {
+		sortByUpdated(notes)
+		for _, r := range searchNotes(notes, q) {
+			matches = append(matches, r.Note)
+		}
+	}
+
+	var note Note
+	switch len(matches) {
+	case 0:
+		return Note{}, 0, fmt.Errorf("%w %q", ErrNoteNotFound, query)
+	case 1:
+		note = matches[0]
+	default:
+		if len(matches) > maxOpenChoices {
+			_, _ = fmt.Fprintf(p.Writer, "%d notes match %q; showing the best %d.\n", len(matches), query, maxOpenChoices)
+			matches = matches[:maxOpenChoices]
+		}
+		options := make([]string, len(matches))
+		for i, n := range matches {
+			options[i] = fmt.Sprintf("%s (%s)", noteTitle(n), relPaths(baseDir, []string{n.FilePath})[0])
+		}
+		note = matches[p.AskMenu("Open:", options, 0)]
+	}
+
+	if !searched {
+		return note, 0, nil
+	}
+	return note, matchLine(note.FilePath, q), nil
+}
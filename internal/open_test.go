@@ -0,0 +1,105 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeEditor installs a shell script as $VISUAL (with an extra argument)
+// that records the arguments it is run with, and returns the file they are
+// written to.
+func fakeEditor(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake editor is a shell script")
+	}
+	dir := t.TempDir()
+	out := filepath.Join(dir, "args")
+	script := filepath.Join(dir, "editor")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\nprintf '%s\\n' \"$@\" > \""+out+"\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("VISUAL", script+" --wait")
+	t.Setenv("EDITOR", "false")
+	return out
+}
+
+func setupOpenVault(t *testing.T) string {
+	t.Helper()
+	dir := setupListDir(t)
+	for name, content := range map[string]string{
+		"Inbox/2026-01-05-runs.md":    "---\ntitle: Running Log\naliases: [jogging]\n---\nFive kilometres today.\n",
+		"Areas/health.md":             "---\ntitle: Health\n---\nSleep and running.\n",
+		"Resources/go.md":             "---\ntitle: Go Notes\n---\nKilometres of code.\n",
+		"Resources/go-concurrency.md": "---\ntitle: Go Concurrency\n---\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Equal search scores are offered most recently updated first.
+	newer := time.Now()
+	if err := os.Chtimes(filepath.Join(dir, "Inbox", "2026-01-05-runs.md"), newer, newer); err != nil {
+		t.Fatal(err)
+	}
+	older := newer.Add(-time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "Resources", "go.md"), older, older); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestOpen(t *testing.T) {
+	dir := setupOpenVault(t)
+	args := fakeEditor(t)
+
+	tests := []struct {
+		query, input, want string
+	}{
+		{"runs", "", "Inbox/2026-01-05-runs.md"},
+		{"Running Log", "", "Inbox/2026-01-05-runs.md"},
+		{"JOGGING", "", "Inbox/2026-01-05-runs.md"},
+		{"sleep", "", "Areas/health.md"},
+		{"kilometres", "2\n", "Resources/go.md"},
+	}
+	for _, tt := range tests {
+		var prompts bytes.Buffer
+		p := NewPrompter(strings.NewReader(tt.input), &prompts)
+		if err := Open(p, dir, tt.query); err != nil {
+			t.Fatalf("Open(%q) error: %v", tt.query, err)
+		}
+		data, err := os.ReadFile(args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := "--wait\n" + filepath.Join(dir, tt.want) + "\n"
+		if string(data) != want {
+			t.Errorf("Open(%q) ran editor with %q, want %q", tt.query, data, want)
+		}
+		if tt.input != "" && !strings.Contains(prompts.String(), "1) Running Log (Inbox/2026-01-05-runs.md)") {
+			t.Errorf("Open(%q) menu = %q", tt.query, prompts.String())
+		}
+	}
+
+	p := NewPrompter(strings.NewReader(""), &bytes.Buffer{})
+	if err := Open(p, dir, "nowhere"); !errors.Is(err, ErrNoteNotFound) {
+		t.Errorf("Open(nowhere) error = %v", err)
+	}
+}
+
+func TestOpenWithoutEditor(t *testing.T) {
+	dir := setupOpenVault(t)
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "  ")
+
+	p := NewPrompter(strings.NewReader(""), &bytes.Buffer{})
+	if err := Open(p, dir, "health"); !errors.Is(err, ErrNoEditor) {
+		t.Errorf("Open() error = %v, want ErrNoEditor", err)
+	}
+}
@@ -0,0 +1,312 @@
+package internal
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ErrPickCancelled is returned when the picker is closed without choosing
+// a note.
+var ErrPickCancelled = errors.New("no note picked")
+
+// PickOptions configures Pick.
+type PickOptions struct {
+	// Print writes the picked note's path to w instead of opening it in
+	// the editor. The path is also printed when no editor is set.
+	Print bool
+}
+
+// Pick lets the user choose a note in a full-screen picker on the
+// terminal, filtering as they type with the same scoring as Find and
+// previewing the selected note. The picker draws on /dev/tty, so w can be
+// a pipe.
+func Pick(w io.Writer, baseDir string, opts PickOptions) error {
+	notes, err := ScanNotes(baseDir)
+	if err != nil {
+		return err
+	}
+	if len(notes) == 0 {
+		return errors.New("no notes found")
+	}
+	sortByUpdated(notes)
+
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("qn pick needs a terminal: %w", err)
+	}
+	defer func() { _ = tty.Close() }()
+	restore, err := rawTerminal(tty)
+	if err != nil {
+		return err
+	}
+	rows, cols := terminalSize(tty)
+
+	// Draw on the alternate screen so the shell's scrollback is left as
+	// it was.
+	_, _ = io.WriteString(tty, "\x1b[?1049h")
+	note, err := runPicker(tty, tty, notes, rows, cols)
+	_, _ = io.WriteString(tty, "\x1b[?1049l")
+	restore()
+	if err != nil {
+		return err
+	}
+
+	if opts.Print || !hasEditor() {
+		_, _ = fmt.Fprintln(w, note.FilePath)
+		return nil
+	}
+	openInEditor(w, note.FilePath, 0)
+	return nil
+}
+
+// rawTerminal switches the terminal f to raw mode with stty and returns a
+// function that restores its previous settings.
+func rawTerminal(f *os.File) (func(), error) {
+	if runtime.GOOS == "windows" {
+		return nil, errors.New("qn pick is not supported on Windows")
+	}
+	saved, err := stty(f, "-g")
+	if err != nil {
+		return nil, fmt.Errorf("reading terminal settings: %w", err)
+	}
+	if _, err := stty(f, "raw", "-echo"); err != nil {
+		return nil, fmt.Errorf("switching terminal to raw mode: %w", err)
+	}
+	return func() { _, _ = stty(f, strings.TrimSpace(saved)) }, nil
+}
+
+// terminalSize returns the rows and columns of the terminal f, or 24x80
+// when stty cannot tell.
+func terminalSize(f *os.File) (int, int) {
+	out, err := stty(f, "size")
+	if err == nil {
+		if fields := strings.Fields(out); len(fields) == 2 {
+			rows, errR := strconv.Atoi(fields[0])
+			cols, errC := strconv.Atoi(fields[1])
+			if errR == nil && errC == nil && rows > 0 && cols > 0 {
+				return rows, cols
+			}
+		}
+	}
+	return 24, 80
+}
+
+func stty(f *os.File, args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = f
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// pickKey is a decoded keypress.
+type pickKey int
+
+const (
+	keyNone pickKey = iota
+	keyRune
+	keyEnter
+	keyBackspace
+	keyUp
+	keyDown
+	keyClear
+	keyCancel
+)
+
+// readKey reads one keypress from a raw terminal. An escape byte followed
+// by nothing else already read is the Escape key itself.
+func readKey(r *bufio.Reader) (pickKey, rune, error) {
+	c, _, err := r.ReadRune()
+	if err != nil {
+		return keyNone, 0, err
+	}
+	switch c {
+	case '\r', '\n':
+		return keyEnter, 0, nil
+	case 0x7f, 0x08:
+		return keyBackspace, 0, nil
+	case 0x03, 0x04, 0x07:
+		return keyCancel, 0, nil
+	case 0x15:
+		return keyClear, 0, nil
+	case 0x10, 0x0b:
+		return keyUp, 0, nil
+	case 0x0e:
+		return keyDown, 0, nil
+	case 0x1b:
+		if r.Buffered() == 0 {
+			return keyCancel, 0, nil
+		}
+		if next, _ := r.ReadByte(); next != '[' && next != 'O' {
+			return keyCancel, 0, nil
+		}
+		// Read the rest of the CSI sequence up to its final byte.
+		for {
+			b, err := r.ReadByte()
+			if err != nil {
+				return keyNone, 0, err
+			}
+			if b >= 0x40 && b <= 0x7e {
+				switch b {
+				case 'A':
+					return keyUp, 0, nil
+				case 'B':
+					return keyDown, 0, nil
+				}
+				return keyNone, 0, nil
+			}
+		}
+	}
+	if unicode.IsPrint(c) {
+		return keyRune, c, nil
+	}
+	return keyNone, 0, nil
+}
+
+// picker is the state of the picker: the query, the notes matching it and
+// which of them is selected.
+type picker struct {
+	notes    []Note
+	query    []rune
+	matches  []Note
+	selected int
+	offset   int
+}
+
+// filter recomputes the matches for the current query. An empty query
+// matches every note in its original order.
+func (p *picker) filter() {
+	q := strings.ToLower(strings.TrimSpace(string(p.query)))
+	if q == "" {
+		p.matches = p.notes
+	} else {
+		p.matches = p.matches[:0:0]
+		for _, r := range searchNotes(p.notes, q) {
+			p.matches = append(p.matches, r.Note)
+		}
+	}
+	p.selected, p.offset = 0, 0
+}
+
+// runPicker reads keys from in and draws the picker on out until a note
+// is chosen with Enter or the picker is cancelled.
+func runPicker(in io.Reader, out io.Writer, notes []Note, rows, cols int) (Note, error) {
+	r := bufio.NewReader(in)
+	p := &picker{notes: notes}
+	p.filter()
+	for {
+		p.render(out, rows, cols)
+		key, c, err := readKey(r)
+		if err == io.EOF {
+			return Note{}, ErrPickCancelled
+		}
+		if err != nil {
+			return Note{}, err
+		}
+		switch key {
+		case keyRune:
+			p.query = append(p.query, c)
+			p.filter()
+		case keyBackspace:
+			if len(p.query) > 0 {
+				p.query = p.query[:len(p.query)-1]
+				p.filter()
+			}
+		case keyClear:
+			p.query = nil
+			p.filter()
+		case keyUp:
+			if p.selected > 0 {
+				p.selected--
+			}
+		case keyDown:
+			if p.selected < len(p.matches)-1 {
+				p.selected++
+			}
+		case keyEnter:
+			if len(p.matches) > 0 {
+				return p.matches[p.selected], nil
+			}
+		case keyCancel:
+			return Note{}, ErrPickCancelled
+		}
+	}
+}
+
+// render draws the query line, the matches around the selection and a
+// preview of the selected note, filling a rows by cols screen.
+func (p *picker) render(out io.Writer, rows, cols int) {
+	listRows := max(1, (rows-3)/2)
+	previewRows := max(0, rows-3-listRows)
+	if p.selected < p.offset {
+		p.offset = p.selected
+	}
+	if p.selected >= p.offset+listRows {
+		p.offset = p.selected - listRows + 1
+	}
+
+	var lines []string
+	lines = append(lines, truncateRunes("> "+string(p.query), cols))
+	lines = append(lines, fmt.Sprintf("  %d/%d", len(p.matches), len(p.notes)))
+	for i := p.offset; i < p.offset+listRows; i++ {
+		if i >= len(p.matches) {
+			lines = append(lines, "")
+			continue
+		}
+		n := p.matches[i]
+		line := truncateRunes("  "+noteTitle(n)+"  ("+n.Folder+")", cols)
+		if i == p.selected {
+			line = "\x1b[7m>" + line[1:] + "\x1b[0m"
+		}
+		lines = append(lines, line)
+	}
+	lines = append(lines, strings.Repeat("─", cols))
+	if len(p.matches) > 0 {
+		for _, l := range p.preview(p.matches[p.selected], cols) {
+			if previewRows == 0 {
+				break
+			}
+			lines = append(lines, l)
+			previewRows--
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("\x1b[H\x1b[2J")
+	b.WriteString(strings.Join(lines, "\r\n"))
+	fmt.Fprintf(&b, "\x1b[1;%dH", min(len(p.query)+3, cols))
+	_, _ = io.WriteString(out, b.String())
+}
+
+// preview returns the lines shown for n: its path, the excerpt matching
+// the query, and the start of its body.
+func (p *picker) preview(n Note, cols int) []string {
+	lines := []string{truncateRunes(filepath.ToSlash(filepath.Join(n.Folder, filepath.Base(n.FilePath))), cols)}
+	if q := strings.ToLower(strings.TrimSpace(string(p.query))); q != "" {
+		if excerpt := findExcerpt(n.Body, q); excerpt != "" {
+			lines = append(lines, "", truncateRunes(excerpt, cols))
+		}
+	}
+	lines = append(lines, "")
+	for _, l := range strings.Split(strings.TrimSpace(n.Body), "\n") {
+		lines = append(lines, truncateRunes(strings.TrimRight(l, " \t\r"), cols))
+	}
+	return lines
+}
+
+// truncateRunes shortens s to at most n runes.
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
@@ -0,0 +1,97 @@
+package internal
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func pickerNotes() []Note {
+	return []Note{
+		{Frontmatter: Frontmatter{Title: "Running Log"}, Folder: "Inbox", FilePath: "/v/Inbox/runs.md", Body: "Five kilometres today."},
+		{Frontmatter: Frontmatter{Title: "Go Concurrency", Tags: []string{"golang"}}, Folder: "Resources", FilePath: "/v/Resources/go.md", Body: "Channels and goroutines."},
+		{Frontmatter: Frontmatter{Title: "Go Modules", Tags: []string{"golang"}}, Folder: "Resources", FilePath: "/v/Resources/mod.md", Body: "Versioning."},
+	}
+}
+
+func TestReadKey(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("aé\r\x7f\x1b[A\x1b[B\x1bOB\x1b[3~\x10\x0e\x15\x03"))
+	want := []pickKey{keyRune, keyRune, keyEnter, keyBackspace, keyUp, keyDown, keyDown, keyNone, keyUp, keyDown, keyClear, keyCancel}
+	for i, w := range want {
+		got, _, err := readKey(r)
+		if err != nil || got != w {
+			t.Fatalf("key %d = %v, %v; want %v", i, got, err, w)
+		}
+	}
+
+	// A lone escape with nothing after it is the Escape key.
+	if got, _, _ := readKey(bufio.NewReader(strings.NewReader("\x1b"))); got != keyCancel {
+		t.Errorf("Escape = %v, want keyCancel", got)
+	}
+}
+
+func TestRunPicker(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"first note", "\r", "Running Log"},
+		{"filter", "golang\r", "Go Concurrency"},
+		{"move down", "go\x1b[B\r", "Go Modules"},
+		{"move past the end", "mod\x1b[B\x1b[B\r", "Go Modules"},
+		{"backspace", "runx\x7f\r", "Running Log"},
+		{"clear", "zzz\x15\x0e\r", "Go Concurrency"},
+		{"body match", "kilometres\r", "Running Log"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			note, err := runPicker(strings.NewReader(tt.input), &out, pickerNotes(), 12, 60)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if note.Frontmatter.Title != tt.want {
+				t.Errorf("picked %q, want %q", note.Frontmatter.Title, tt.want)
+			}
+		})
+	}
+
+	// Enter with nothing matching does nothing; Ctrl-C then cancels.
+	if _, err := runPicker(strings.NewReader("nothing\r\x03"), &strings.Builder{}, pickerNotes(), 12, 60); !errors.Is(err, ErrPickCancelled) {
+		t.Errorf("cancel error = %v", err)
+	}
+	if _, err := runPicker(strings.NewReader("go"), &strings.Builder{}, pickerNotes(), 12, 60); !errors.Is(err, ErrPickCancelled) {
+		t.Errorf("end of input error = %v", err)
+	}
+}
+
+func TestPickerRender(t *testing.T) {
+	p := &picker{notes: pickerNotes(), query: []rune("chan")}
+	p.filter()
+	var out strings.Builder
+	p.render(&out, 12, 40)
+	screen := out.String()
+
+	for _, want := range []string{
+		"> chan\r\n",
+		"  1/3\r\n",
+		"\x1b[7m> Go Concurrency  (Resources)\x1b[0m",
+		"Resources/go.md",
+		"Channels and goroutines.",
+	} {
+		if !strings.Contains(screen, want) {
+			t.Errorf("screen missing %q:\n%q", want, screen)
+		}
+	}
+	if strings.Contains(screen, "Running Log") {
+		t.Errorf("screen lists a note that does not match:\n%q", screen)
+	}
+	plain := strings.NewReplacer("\x1b[H\x1b[2J", "", "\x1b[7m", "", "\x1b[0m", "", "\x1b[1;7H", "").Replace(screen)
+	for _, line := range strings.Split(plain, "\r\n") {
+		if len([]rune(line)) > 40 {
+			t.Errorf("line wider than the screen: %q", line)
+		}
+	}
+}
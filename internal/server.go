@@ -0,0 +1,485 @@
+package internal
+
+import (
+	"cmp"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxRequestBytes bounds the size of request bodies the API accepts.
+const maxRequestBytes = 1 << 20
+
+// Server serves a notes directory over a local JSON API:
+//
+//	GET  /api/notes             recent notes (?all=true or ?limit=N)
+//	POST /api/notes             create a note
+//	GET  /api/notes/{note...}   one note, by path, filename, slug or title
+//	POST /api/append/{note...}  append text to a note
+//	GET  /api/find?q=topic      search notes
+//	GET  /api/tags              tags with their note counts
+//
+// API errors are returned as {"error": "message"}. Every other path is the
+// browser UI in webui.go.
+type Server struct {
+	baseDir string
+	token   string
+	log     io.Writer
+	mux     *http.ServeMux
+	handler http.Handler
+	// writeMu serializes requests that change notes, so their git commits
+	// don't interleave.
+	writeMu sync.Mutex
+	// hosts are the hosts besides localhost and loopback IPs that requests
+	// may be addressed to when there is no token; see AllowHost.
+	hosts []string
+}
+
+// NewServer returns a Server for baseDir. When token is not empty, every
+// request must carry it as "Authorization: Bearer <token>". Warnings from
+// git auto-commits are written to log.
+func NewServer(baseDir, token string, log io.Writer) *Server {
+	if log == nil {
+		log = io.Discard
+	}
+	s := &Server{baseDir: baseDir, token: token, log: log, mux: http.NewServeMux()}
+	s.mux.HandleFunc("GET /api/notes", s.handleList)
+	s.mux.HandleFunc("POST /api/notes", s.handleCreate)
+	s.mux.HandleFunc("GET /api/notes/{note...}", s.handleGet)
+	s.mux.HandleFunc("POST /api/append/{note...}", s.handleAppend)
+	s.mux.HandleFunc("GET /api/find", s.handleFind)
+	s.mux.HandleFunc("GET /api/tags", s.handleTags)
+	s.registerUI()
+	s.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if isAPIPath(r.URL.Path) {
+			writeError(w, http.StatusNotFound, errors.New("not found"))
+			return
+		}
+		s.renderUIError(w, http.StatusNotFound, "No page at "+r.URL.Path+".")
+	})
+	// Browsers attach no token to cross-site form posts, but without one
+	// any web page could post to a local server, so reject them outright.
+	s.handler = http.NewCrossOriginProtection().Handler(s.mux)
+	return s
+}
+
+// tokenCookie remembers the token in browsers that opened the UI with
+// ?token=.
+const tokenCookie = "qn_token"
+
+// AllowHost accepts requests addressed to host, the host the server
+// listens on, when there is no token. An empty or unspecified host such
+// as 0.0.0.0 accepts any IP address.
+func (s *Server) AllowHost(host string) {
+	s.hosts = append(s.hosts, host)
+}
+
+// allowedHost reports whether r is addressed to localhost, a loopback IP
+// or a host passed to AllowHost. Without a token this is what stops a
+// DNS-rebinding page, whose own hostname resolves to 127.0.0.1, from
+// reading the vault: its requests carry that hostname in Host.
+func (s *Server) allowedHost(r *http.Request) bool {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.Trim(host, "[]"), ".")
+	ip := net.ParseIP(host)
+	if strings.EqualFold(host, "localhost") || ip != nil && ip.IsLoopback() {
+		return true
+	}
+	for _, h := range s.hosts {
+		if allowed := net.ParseIP(h); (h == "" || allowed != nil && allowed.IsUnspecified()) && ip != nil {
+			return true
+		}
+		if strings.EqualFold(strings.Trim(h, "[]"), host) {
+			return true
+		}
+	}
+	return false
+}
+
+// ServeHTTP checks the Host header and token and dispatches the request.
+// API clients send the token as a bearer token; browsers open any page
+// with ?token= once and keep it in a cookie.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.token == "" && !s.allowedHost(r) {
+		msg := fmt.Sprintf("requests must be addressed to localhost, not %q", r.Host)
+		if isAPIPath(r.URL.Path) {
+			writeError(w, http.StatusForbidden, errors.New(msg))
+		} else {
+			s.renderUIError(w, http.StatusForbidden, "Open qn at http://localhost; "+msg+".")
+		}
+		return
+	}
+	if s.token != "" && !s.authorized(r) {
+		if t := r.URL.Query().Get("token"); t != "" && s.validToken(t) && !isAPIPath(r.URL.Path) {
+			http.SetCookie(w, &http.Cookie{
+				Name:     tokenCookie,
+				Value:    t,
+				Path:     "/",
+				HttpOnly: true,
+				SameSite: http.SameSiteStrictMode,
+			})
+			u := *r.URL
+			q := u.Query()
+			q.Del("token")
+			u.RawQuery = q.Encode()
+			http.Redirect(w, r, u.RequestURI(), http.StatusSeeOther)
+			return
+		}
+		if isAPIPath(r.URL.Path) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="qn"`)
+			writeError(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+		} else {
+			s.renderUIError(w, http.StatusUnauthorized, "Open this page with ?token=<token> to sign in.")
+		}
+		return
+	}
+	s.handler.ServeHTTP(w, r)
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	if got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return s.validToken(got)
+	}
+	if c, err := r.Cookie(tokenCookie); err == nil {
+		return s.validToken(c.Value)
+	}
+	return false
+}
+
+func (s *Server) validToken(t string) bool {
+	return subtle.ConstantTimeCompare([]byte(t), []byte(s.token)) == 1
+}
+
+func isAPIPath(p string) bool {
+	return p == "/api" || strings.HasPrefix(p, "/api/")
+}
+
+// NoteSummary is the JSON form of a note in list and search results.
+type NoteSummary struct {
+	Path    string    `json:"path"`
+	Folder  string    `json:"folder"`
+	Title   string    `json:"title"`
+	Date    string    `json:"date,omitempty"`
+	Tags    []string  `json:"tags,omitempty"`
+	ModTime time.Time `json:"mtime"`
+}
+
+// SearchHit is a note found by /api/find or qn find --format json.
+type SearchHit struct {
+	NoteSummary
+	Score   int    `json:"score"`
+	Excerpt string `json:"excerpt,omitempty"`
+}
+
+// TagCount is a tag and the number of notes carrying it.
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// CreateRequest is the body of POST /api/notes.
+type CreateRequest struct {
+	Title  string   `json:"title"`
+	Folder string   `json:"folder"`
+	Tags   []string `json:"tags"`
+	Body   string   `json:"body"`
+	URLs   []string `json:"urls"`
+	// OnConflict is suffix or abort; the default is abort.
+	OnConflict string `json:"on_conflict"`
+	// OnDuplicateURL says what to do when other notes already reference
+	// one of URLs: link (the default) adds links to them, ignore creates
+	// the note as it is and abort fails with 409.
+	OnDuplicateURL string `json:"on_duplicate_url"`
+}
+
+// CreateResponse is the reply to POST /api/notes: the new note, and the
+// notes that already referenced its URLs.
+type CreateResponse struct {
+	NoteRecord
+	Duplicates []URLMatch `json:"duplicates,omitempty"`
+}
+
+// AppendRequest is the body of POST /api/append/{note}.
+type AppendRequest struct {
+	Text string `json:"text"`
+}
+
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	limit := 10
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid limit %q", v))
+			return
+		}
+		limit = n
+	}
+	if all, _ := strconv.ParseBool(r.URL.Query().Get("all")); all {
+		limit = 0
+	}
+
+	notes, err := ScanNotes(s.baseDir)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	sortByUpdated(notes)
+	total := len(notes)
+	if limit > 0 && limit < len(notes) {
+		notes = notes[:limit]
+	}
+
+	summaries := make([]NoteSummary, 0, len(notes))
+	for _, n := range notes {
+		summaries = append(summaries, s.summary(n))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"notes": summaries, "total": total})
+}
+
+func (s *Server) handleFind(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		writeError(w, http.StatusBadRequest, errors.New("search query is required"))
+		return
+	}
+	notes, err := ScanNotes(s.baseDir)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	q := strings.ToLower(query)
+	hits := []SearchHit{}
+	for _, res := range searchNotes(notes, q) {
+		hits = append(hits, SearchHit{
+			NoteSummary: s.summary(res.Note),
+			Score:       res.Score,
+			Excerpt:     findExcerpt(res.Note.Body, q),
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"results": hits})
+}
+
+func (s *Server) handleGet(w http.ResponseWriter, r *http.Request) {
+	notes, err := ScanNotes(s.baseDir)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	note, err := pickNote(notes, s.baseDir, r.PathValue("note"))
+	if err != nil {
+		writeError(w, lookupStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, NewNoteRecord(s.baseDir, note, NewLinkIndex(notes)))
+}
+
+func (s *Server) handleCreate(w http.ResponseWriter, r *http.Request) {
+	var req CreateRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if strings.TrimSpace(req.Title) == "" {
+		writeError(w, http.StatusBadRequest, errors.New("title is required"))
+		return
+	}
+	if req.Folder == "" {
+		req.Folder = DefaultFolder()
+	}
+	if !isNoteFolder(req.Folder) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unknown folder %q", req.Folder))
+		return
+	}
+	policy := CollisionAbort
+	if req.OnConflict != "" {
+		p, err := ParseCollisionPolicy(req.OnConflict)
+		if err != nil || (p != CollisionSuffix && p != CollisionAbort) {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid on_conflict %q (want suffix or abort)", req.OnConflict))
+			return
+		}
+		policy = p
+	}
+	onDuplicate := cmp.Or(req.OnDuplicateURL, DuplicateLink)
+	if onDuplicate != DuplicateLink && onDuplicate != DuplicateIgnore && onDuplicate != DuplicateAbort {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid on_duplicate_url %q (want link, ignore or abort)", req.OnDuplicateURL))
+		return
+	}
+
+	note := NewNote{
+		Title:  strings.TrimSpace(req.Title),
+		Folder: req.Folder,
+		Tags:   NormalizeTags(strings.Join(req.Tags, ",")),
+		Body:   req.Body,
+		URLs:   req.URLs,
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	dups, links, err := resolveDuplicateURLs(s.baseDir, note.URLs, onDuplicate)
+	if errors.Is(err, ErrDuplicateURL) {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	note.URLs = append(note.URLs, links...)
+	path, err := CreateNote(s.baseDir, note, policy)
+	if errors.Is(err, ErrNoteExists) {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	autoCommit(s.log, s.baseDir, fmt.Sprintf("qn: create %q in %s", note.Title, note.Folder), path)
+	rec, err := s.noteRecord(path)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Location", "/api/notes/"+rec.Path)
+	writeJSON(w, http.StatusCreated, CreateResponse{NoteRecord: rec, Duplicates: dups})
+}
+
+func (s *Server) handleAppend(w http.ResponseWriter, r *http.Request) {
+	var req AppendRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if strings.TrimSpace(req.Text) == "" {
+		writeError(w, http.StatusBadRequest, errors.New("text is required"))
+		return
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	path, err := AppendToNote(s.baseDir, r.PathValue("note"), req.Text)
+	if err != nil {
+		writeError(w, lookupStatus(err), err)
+		return
+	}
+	rel, _ := vaultRelPath(s.baseDir, path)
+	autoCommit(s.log, s.baseDir, fmt.Sprintf("qn: append to %s", rel), path)
+	s.writeNote(w, http.StatusOK, path)
+}
+
+func (s *Server) handleTags(w http.ResponseWriter, r *http.Request) {
+	notes, err := ScanNotes(s.baseDir)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"tags": countTags(notes)})
+}
+
+// writeNote responds with the full record of the note at path.
+func (s *Server) writeNote(w http.ResponseWriter, status int, path string) {
+	rec, err := s.noteRecord(path)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Location", "/api/notes/"+rec.Path)
+	writeJSON(w, status, rec)
+}
+
+// noteRecord returns the JSON form of the note just written at path.
+func (s *Server) noteRecord(path string) (NoteRecord, error) {
+	notes, err := ScanNotes(s.baseDir)
+	if err != nil {
+		return NoteRecord{}, err
+	}
+	for _, n := range notes {
+		if samePath(n.FilePath, path) {
+			return NewNoteRecord(s.baseDir, n, NewLinkIndex(notes)), nil
+		}
+	}
+	return NoteRecord{}, fmt.Errorf("note %s vanished after writing", path)
+}
+
+func (s *Server) summary(n Note) NoteSummary {
+	return newNoteSummary(s.baseDir, n)
+}
+
+// newNoteSummary returns the JSON form of n, with its path relative to
+// baseDir.
+func newNoteSummary(baseDir string, n Note) NoteSummary {
+	rel, err := vaultRelPath(baseDir, n.FilePath)
+	if err != nil {
+		rel = n.FilePath
+	}
+	return NoteSummary{
+		Path:    rel,
+		Folder:  n.Folder,
+		Title:   noteTitle(n),
+		Date:    n.Frontmatter.Date,
+		Tags:    n.Frontmatter.Tags,
+		ModTime: n.ModTime.UTC(),
+	}
+}
+
+// countTags returns every tag in notes with its note count, sorted by tag.
+func countTags(notes []Note) []TagCount {
+	counts := make(map[string]int)
+	for _, n := range notes {
+		for _, t := range n.Frontmatter.Tags {
+			counts[t]++
+		}
+	}
+	tags := make([]TagCount, 0, len(counts))
+	for t, c := range counts {
+		tags = append(tags, TagCount{Tag: t, Count: c})
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Tag < tags[j].Tag })
+	return tags
+}
+
+// lookupStatus maps errors from LookupNote and AppendToNote to HTTP
+// statuses.
+func lookupStatus(err error) int {
+	var ambiguous *AmbiguousNoteError
+	switch {
+	case errors.Is(err, ErrNoteNotFound):
+		return http.StatusNotFound
+	case errors.As(err, &ambiguous):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// readJSON decodes the request body into v, responding with 400 and
+// returning false if it is not valid JSON.
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
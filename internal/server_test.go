@@ -0,0 +1,299 @@
+package internal
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func setupServerVault(t *testing.T) string {
+	t.Helper()
+	dir := setupTestNotesDir(t)
+	writeFiles(t, dir, map[string]string{
+		"Areas/health.md":             "---\ntitle: Health\ntags: [fitness, habits]\n---\n# Health\n\nSee [[Running Log]].\n",
+		"Inbox/2026-01-05-runs.md":    "---\ntitle: Running Log\ndate: 2026-01-05\ntags: [fitness]\n---\nFive kilometres today.\n",
+		"Resources/go-concurrency.md": "---\ntitle: Go Concurrency\ntags: [go]\n---\nChannels and goroutines.\n",
+	})
+	return dir
+}
+
+// do sends a request to srv and decodes the JSON response into out.
+func do(t *testing.T, srv http.Handler, method, target, body string, out any) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Host = "127.0.0.1:7777"
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+		t.Errorf("%s %s: Content-Type = %q, want JSON", method, target, ct)
+	}
+	if out != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s: decoding %q: %v", method, target, rec.Body.String(), err)
+		}
+	}
+	return rec
+}
+
+func TestServerList(t *testing.T) {
+	srv := NewServer(setupServerVault(t), "", nil)
+
+	var resp struct {
+		Notes []NoteSummary `json:"notes"`
+		Total int           `json:"total"`
+	}
+	rec := do(t, srv, "GET", "/api/notes?limit=2", "", &resp)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	if resp.Total != 3 || len(resp.Notes) != 2 {
+		t.Errorf("got %d of %d notes, want 2 of 3", len(resp.Notes), resp.Total)
+	}
+
+	rec = do(t, srv, "GET", "/api/notes?limit=x", "", nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("bad limit: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestServerFind(t *testing.T) {
+	srv := NewServer(setupServerVault(t), "", nil)
+
+	var resp struct {
+		Results []SearchHit `json:"results"`
+	}
+	do(t, srv, "GET", "/api/find?q=fitness", "", &resp)
+	if len(resp.Results) != 2 {
+		t.Fatalf("got %d results, want 2: %+v", len(resp.Results), resp.Results)
+	}
+	for _, hit := range resp.Results {
+		if hit.Score == 0 || hit.Path == "" {
+			t.Errorf("incomplete hit: %+v", hit)
+		}
+	}
+
+	rec := do(t, srv, "GET", "/api/find", "", nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("missing query: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestServerGet(t *testing.T) {
+	srv := NewServer(setupServerVault(t), "", nil)
+
+	var note NoteRecord
+	rec := do(t, srv, "GET", "/api/notes/Areas/health.md", "", &note)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	if note.Frontmatter.Title != "Health" {
+		t.Errorf("title = %q, want Health", note.Frontmatter.Title)
+	}
+	if len(note.Links) != 1 || note.Links[0].Path != "Inbox/2026-01-05-runs.md" {
+		t.Errorf("links = %+v, want resolved link to the running log", note.Links)
+	}
+
+	do(t, srv, "GET", "/api/notes/runs", "", &note)
+	if note.Frontmatter.Title != "Running Log" {
+		t.Errorf("lookup by slug: title = %q", note.Frontmatter.Title)
+	}
+
+	var errResp struct {
+		Error string `json:"error"`
+	}
+	rec = do(t, srv, "GET", "/api/notes/nothing-here", "", &errResp)
+	if rec.Code != http.StatusNotFound || errResp.Error == "" {
+		t.Errorf("missing note: status = %d, error = %q", rec.Code, errResp.Error)
+	}
+}
+
+func TestServerCreate(t *testing.T) {
+	dir := setupServerVault(t)
+	srv := NewServer(dir, "", nil)
+
+	var note NoteRecord
+	body := `{"title": "Sprint Plan", "folder": "Projects", "tags": ["Work"], "body": "Ship it"}`
+	rec := do(t, srv, "POST", "/api/notes", body, &note)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	if !strings.HasPrefix(note.Path, "Projects/") || !strings.HasSuffix(note.Path, "-sprint-plan.md") {
+		t.Errorf("path = %q", note.Path)
+	}
+	if !sliceEqual(note.Frontmatter.Tags, []string{"work", "project"}) {
+		t.Errorf("tags = %v, want [work project]", note.Frontmatter.Tags)
+	}
+	if rec.Header().Get("Location") != "/api/notes/"+note.Path {
+		t.Errorf("Location = %q", rec.Header().Get("Location"))
+	}
+
+	rec = do(t, srv, "POST", "/api/notes", body, nil)
+	if rec.Code != http.StatusConflict {
+		t.Errorf("duplicate: status = %d, want 409", rec.Code)
+	}
+	rec = do(t, srv, "POST", "/api/notes", `{"title": "Sprint Plan", "folder": "Projects", "on_conflict": "suffix"}`, &note)
+	if rec.Code != http.StatusCreated || !strings.HasSuffix(note.Path, "-sprint-plan-2.md") {
+		t.Errorf("suffix: status = %d, path = %q", rec.Code, note.Path)
+	}
+
+	for _, bad := range []string{`{"folder": "Inbox"}`, `{"title": "x", "folder": "Nowhere"}`, `{"title": "x", "colour": "red"}`, `not json`} {
+		if rec := do(t, srv, "POST", "/api/notes", bad, nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", bad, rec.Code)
+		}
+	}
+}
+
+func TestServerCreateDefaultFolder(t *testing.T) {
+	dir := setupServerVault(t)
+	srv := NewServer(dir, "", nil)
+	t.Setenv("MDNOTES_DEFAULT_FOLDER", "areas")
+
+	var note NoteRecord
+	rec := do(t, srv, "POST", "/api/notes", `{"title": "Gardening"}`, &note)
+	if rec.Code != http.StatusCreated || note.Path != "Areas/gardening.md" {
+		t.Errorf("status = %d, path = %q; want Areas/gardening.md", rec.Code, note.Path)
+	}
+}
+
+func TestServerCreateDuplicateURL(t *testing.T) {
+	dir := setupServerVault(t)
+	srv := NewServer(dir, "", nil)
+	if rec := do(t, srv, "POST", "/api/notes", `{"title": "Go Blog", "folder": "Resources", "urls": ["https://go.dev/blog"]}`, nil); rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+
+	var errResp struct{ Error string }
+	rec := do(t, srv, "POST", "/api/notes", `{"title": "Blog", "folder": "Resources", "urls": ["http://www.go.dev/blog/"], "on_duplicate_url": "abort"}`, &errResp)
+	if rec.Code != http.StatusConflict || !strings.Contains(errResp.Error, "http://www.go.dev/blog/ is referenced in Resources/go-blog.md") {
+		t.Errorf("abort: status = %d, error %q", rec.Code, errResp.Error)
+	}
+
+	var resp CreateResponse
+	rec = do(t, srv, "POST", "/api/notes", `{"title": "Blog", "folder": "Resources", "urls": ["https://go.dev/blog"]}`, &resp)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("link: status = %d, body %s", rec.Code, rec.Body)
+	}
+	if len(resp.Duplicates) != 1 || !sliceEqual(resp.Duplicates[0].Notes, []string{"Resources/go-blog.md"}) {
+		t.Errorf("duplicates = %+v", resp.Duplicates)
+	}
+	if !strings.Contains(resp.Body, "- See [[Go Blog]]") {
+		t.Errorf("linked note body = %q", resp.Body)
+	}
+
+	if rec := do(t, srv, "POST", "/api/notes", `{"title": "x", "urls": ["https://go.dev/blog"], "on_duplicate_url": "merge"}`, nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("bad on_duplicate_url: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestServerAppend(t *testing.T) {
+	dir := setupServerVault(t)
+	srv := NewServer(dir, "", nil)
+
+	rec := do(t, srv, "POST", "/api/append/Running%20Log", `{"text": "- 2026-01-06: 8 km"}`, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "Inbox", "2026-01-05-runs.md"))
+	if !strings.HasSuffix(string(data), "Five kilometres today.\n- 2026-01-06: 8 km\n") {
+		t.Errorf("note after append:\n%s", data)
+	}
+
+	if rec := do(t, srv, "POST", "/api/append/Running%20Log", `{"text": "  "}`, nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("empty text: status = %d, want 400", rec.Code)
+	}
+	if rec := do(t, srv, "POST", "/api/append/missing", `{"text": "x"}`, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("missing note: status = %d, want 404", rec.Code)
+	}
+}
+
+func TestServerTags(t *testing.T) {
+	srv := NewServer(setupServerVault(t), "", nil)
+
+	var resp struct {
+		Tags []TagCount `json:"tags"`
+	}
+	do(t, srv, "GET", "/api/tags", "", &resp)
+	want := []TagCount{{"fitness", 2}, {"go", 1}, {"habits", 1}}
+	if len(resp.Tags) != len(want) {
+		t.Fatalf("tags = %+v, want %+v", resp.Tags, want)
+	}
+	for i := range want {
+		if resp.Tags[i] != want[i] {
+			t.Errorf("tags[%d] = %+v, want %+v", i, resp.Tags[i], want[i])
+		}
+	}
+}
+
+func TestServerToken(t *testing.T) {
+	srv := NewServer(setupServerVault(t), "s3cret", nil)
+
+	rec := do(t, srv, "GET", "/api/tags", "", nil)
+	if rec.Code != http.StatusUnauthorized || rec.Header().Get("WWW-Authenticate") == "" {
+		t.Errorf("no token: status = %d, want 401 with a challenge", rec.Code)
+	}
+
+	for token, want := range map[string]int{"Bearer wrong": http.StatusUnauthorized, "Bearer s3cret": http.StatusOK} {
+		req := httptest.NewRequest("GET", "/api/tags", nil)
+		req.Header.Set("Authorization", token)
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("%q: status = %d, want %d", token, rec.Code, want)
+		}
+	}
+}
+
+func TestServerUnknownRoute(t *testing.T) {
+	srv := NewServer(setupServerVault(t), "", nil)
+	if rec := do(t, srv, "GET", "/api/nope", "", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", rec.Code)
+	}
+}
+
+func TestServerHost(t *testing.T) {
+	srv := NewServer(setupServerVault(t), "", nil)
+	srv.AllowHost("notes.lan")
+	get := func(host string) int {
+		req := httptest.NewRequest("GET", "/api/notes", nil)
+		req.Host = host
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	for host, want := range map[string]int{
+		"localhost:7777":    http.StatusOK,
+		"127.0.0.1:7777":    http.StatusOK,
+		"[::1]:7777":        http.StatusOK,
+		"notes.lan:7777":    http.StatusOK,
+		"evil.example":      http.StatusForbidden,
+		"evil.example:7777": http.StatusForbidden,
+		"192.168.1.5:7777":  http.StatusForbidden,
+	} {
+		if got := get(host); got != want {
+			t.Errorf("Host %s: status = %d, want %d", host, got, want)
+		}
+	}
+
+	srv.AllowHost("0.0.0.0")
+	if got := get("192.168.1.5:7777"); got != http.StatusOK {
+		t.Errorf("listening on every address: status = %d for an IP host, want 200", got)
+	}
+	if got := get("evil.example"); got != http.StatusForbidden {
+		t.Errorf("listening on every address: status = %d for a hostname, want 403", got)
+	}
+
+	// With a token, the token is what protects the vault.
+	srv = NewServer(setupServerVault(t), "s3cret", nil)
+	req := httptest.NewRequest("GET", "/api/notes", nil)
+	req.Host = "notes.example.com"
+	req.Header.Set("Authorization", "Bearer s3cret")
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("with a token: status = %d, want 200", rec.Code)
+	}
+}
@@ -0,0 +1,173 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// tomlTable is one [section] of a TOML file. Values are string, int64,
+// bool or []string.
+type tomlTable map[string]any
+
+// parseTOML parses the subset of TOML used by qn's own files: [section]
+// headers, bare or quoted keys, basic and literal strings, integers,
+// booleans, single-line arrays of strings and # comments. Keys before the
+// first header belong to the section "". Errors name the line.
+func parseTOML(data []byte) (map[string]tomlTable, error) {
+	doc := map[string]tomlTable{"": {}}
+	section := ""
+	for i, line := range strings.Split(string(data), "\n") {
+		n := i + 1
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			end := strings.Index(line, "]")
+			if end < 0 || stripTOMLComment(line[end+1:]) != "" {
+				return nil, fmt.Errorf("line %d: malformed section header %q", n, line)
+			}
+			name, err := parseTOMLKey(strings.TrimSpace(line[1:end]))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+			if _, ok := doc[name]; ok && name != "" {
+				return nil, fmt.Errorf("line %d: section [%s] is defined twice", n, name)
+			}
+			section = name
+			doc[section] = tomlTable{}
+			continue
+		}
+
+		eq := strings.Index(line, "=")
+		if eq < 0 {
+			return nil, fmt.Errorf("line %d: want key = value, got %q", n, line)
+		}
+		key, err := parseTOMLKey(strings.TrimSpace(line[:eq]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		value, rest, err := parseTOMLValue(strings.TrimSpace(line[eq+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %w", n, key, err)
+		}
+		if stripTOMLComment(rest) != "" {
+			return nil, fmt.Errorf("line %d: %s: unexpected %q after value", n, key, strings.TrimSpace(rest))
+		}
+		if _, ok := doc[section][key]; ok {
+			return nil, fmt.Errorf("line %d: %s is set twice", n, key)
+		}
+		doc[section][key] = value
+	}
+	return doc, nil
+}
+
+// parseTOMLKey parses a bare key or a quoted one.
+func parseTOMLKey(s string) (string, error) {
+	if strings.HasPrefix(s, `"`) || strings.HasPrefix(s, "'") {
+		key, rest, err := parseTOMLString(s)
+		if err != nil {
+			return "", err
+		}
+		if rest != "" {
+			return "", fmt.Errorf("malformed key %q", s)
+		}
+		return key, nil
+	}
+	if s == "" {
+		return "", fmt.Errorf("empty key")
+	}
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return "", fmt.Errorf("invalid character %q in key %q; quote keys with other characters", r, s)
+		}
+	}
+	return s, nil
+}
+
+// parseTOMLValue parses the value at the start of s and returns it with
+// the unparsed remainder.
+func parseTOMLValue(s string) (any, string, error) {
+	switch {
+	case s == "":
+		return nil, "", fmt.Errorf("missing value")
+	case s[0] == '"' || s[0] == '\'':
+		return parseTOMLString(s)
+	case s[0] == '[':
+		return parseTOMLArray(s)
+	}
+
+	end := strings.IndexAny(s, " \t#")
+	if end < 0 {
+		end = len(s)
+	}
+	word, rest := s[:end], s[end:]
+	switch word {
+	case "true":
+		return true, rest, nil
+	case "false":
+		return false, rest, nil
+	}
+	n, err := strconv.ParseInt(strings.ReplaceAll(word, "_", ""), 10, 64)
+	if err != nil {
+		return nil, "", fmt.Errorf("unsupported value %q; quote strings", word)
+	}
+	return n, rest, nil
+}
+
+// parseTOMLString parses a "basic" or 'literal' string at the start of s.
+func parseTOMLString(s string) (string, string, error) {
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case quote == '"' && s[i] == '\\':
+			i++
+		case s[i] == quote:
+			if quote == '\'' {
+				return s[1:i], s[i+1:], nil
+			}
+			v, err := strconv.Unquote(s[:i+1])
+			if err != nil {
+				return "", "", fmt.Errorf("invalid string %s", s[:i+1])
+			}
+			return v, s[i+1:], nil
+		}
+	}
+	return "", "", fmt.Errorf("unterminated string %s", s)
+}
+
+// parseTOMLArray parses a single-line array of strings at the start of s.
+func parseTOMLArray(s string) ([]string, string, error) {
+	items := []string{}
+	rest := strings.TrimSpace(s[1:])
+	for {
+		if strings.HasPrefix(rest, "]") {
+			return items, rest[1:], nil
+		}
+		if rest == "" || (rest[0] != '"' && rest[0] != '\'') {
+			return nil, "", fmt.Errorf("arrays may only hold strings and must end on the same line")
+		}
+		item, after, err := parseTOMLString(rest)
+		if err != nil {
+			return nil, "", err
+		}
+		items = append(items, item)
+		rest = strings.TrimSpace(after)
+		if strings.HasPrefix(rest, ",") {
+			rest = strings.TrimSpace(rest[1:])
+		} else if !strings.HasPrefix(rest, "]") {
+			return nil, "", fmt.Errorf("want , or ] after %q", item)
+		}
+	}
+}
+
+// stripTOMLComment returns s without a trailing comment or whitespace.
+func stripTOMLComment(s string) string {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "#") {
+		return ""
+	}
+	return s
+}
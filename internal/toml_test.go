@@ -0,0 +1,59 @@
+package internal
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTOML(t *testing.T) {
+	data := `# top comment
+name = "qn"   # trailing comment
+
+[section]
+list = ["a", 'b\c', "d\"e"]
+empty = []
+flag = true
+count = 1_000
+"quoted key" = 'x'
+`
+	doc, err := parseTOML([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]tomlTable{
+		"": {"name": "qn"},
+		"section": {
+			"list":       []string{"a", `b\c`, `d"e`},
+			"empty":      []string{},
+			"flag":       true,
+			"count":      int64(1000),
+			"quoted key": "x",
+		},
+	}
+	if !reflect.DeepEqual(doc, want) {
+		t.Errorf("parseTOML() = %#v\nwant %#v", doc, want)
+	}
+}
+
+func TestParseTOMLErrors(t *testing.T) {
+	tests := []struct {
+		data string
+		want string
+	}{
+		{"a = 1\na = 2", "line 2: a is set twice"},
+		{"[s]\n[s]", "line 2: section [s] is defined twice"},
+		{"just words", "line 1: want key = value"},
+		{`a = "open`, "unterminated string"},
+		{`a = ["x",`, "must end on the same line"},
+		{"a = bare", "quote strings"},
+		{`a = "x" y`, "unexpected"},
+		{"[oops", "malformed section header"},
+	}
+	for _, tt := range tests {
+		_, err := parseTOML([]byte(tt.data))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("parseTOML(%q) error = %v, want %q", tt.data, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// timestamp formats t for the created and updated frontmatter fields.
+func timestamp(t time.Time) string {
+	return t.Format(time.RFC3339)
+}
+
+// timestampLayouts are the forms of created and updated qn understands:
+// its own RFC 3339 timestamps, and the shorter ones other tools write.
+var timestampLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02T15:04", "2006-01-02 15:04", "2006-01-02"}
+
+// parseTimestamp parses a created, updated or date value.
+func parseTimestamp(s string) (time.Time, bool) {
+	for _, layout := range timestampLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// noteUpdated returns when the note last changed: its updated field, or
+// its modification time when it has none.
+func noteUpdated(n Note) time.Time {
+	if t, ok := parseTimestamp(n.Frontmatter.Updated); ok {
+		return t
+	}
+	return n.ModTime
+}
+
+// noteCreated returns when the note was created: its created field, else
+// its date, else its modification time.
+func noteCreated(n Note) time.Time {
+	if t, ok := parseTimestamp(n.Frontmatter.Created); ok {
+		return t
+	}
+	return noteTime(n)
+}
+
+// setFrontmatterField sets key to value in the frontmatter of a note
+// file, replacing the key's line or adding one before the closing "---",
+// and leaves every other line as it was. It reports false when content
+// has no closed frontmatter block.
+func setFrontmatterField(content, key, value string) (string, bool) {
+	lines := strings.Split(content, "\n")
+	end, ok := frontmatterEnd(lines)
+	if !ok {
+		return content, false
+	}
+	line := key + ": " + value
+	for i := 1; i < end; i++ {
+		if k, _, ok := parseYAMLLine(lines[i]); ok && k == key && !isYAMLContinuation(lines[i]) {
+			lines[i] = line
+			return strings.Join(lines, "\n"), true
+		}
+	}
+	lines = append(lines[:end], append([]string{line}, lines[end:]...)...)
+	return strings.Join(lines, "\n"), true
+}
+
+// Touch sets the updated field of the note matching query to now, without
+// otherwise changing the file.
+func Touch(w io.Writer, baseDir, query string) error {
+	note, err := LookupNote(baseDir, query)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(note.FilePath)
+	if err != nil {
+		return fmt.Errorf("reading note: %w", err)
+	}
+	rel := relPaths(baseDir, []string{note.FilePath})[0]
+	content, ok := setFrontmatterField(string(data), "updated", timestamp(time.Now()))
+	if !ok {
+		return fmt.Errorf("%s has no frontmatter; run qn lint --fix to add it", rel)
+	}
+	if err := RewriteFile(note.FilePath, []byte(content)); err != nil {
+		return fmt.Errorf("writing note: %w", err)
+	}
+	_, _ = fmt.Fprintf(w, "Touched: %s\n", rel)
+	autoCommit(w, baseDir, fmt.Sprintf("qn: touch %s", rel), note.FilePath)
+	return nil
+}
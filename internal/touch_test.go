@@ -0,0 +1,126 @@
+package internal
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetFrontmatterField(t *testing.T) {
+	tests := []struct {
+		in, want string
+		ok       bool
+	}{
+		{"---\ntitle: A\nupdated: old\n---\nbody\n", "---\ntitle: A\nupdated: new\n---\nbody\n", true},
+		{"---\ntitle: A\ntags:\n  - updated: x\n---\n", "---\ntitle: A\ntags:\n  - updated: x\nupdated: new\n---\n", true},
+		{"no frontmatter\n", "no frontmatter\n", false},
+		{"---\ntitle: never closed\n", "---\ntitle: never closed\n", false},
+	}
+	for _, tt := range tests {
+		got, ok := setFrontmatterField(tt.in, "updated", "new")
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("setFrontmatterField(%q) = %q, %v; want %q, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestTouch(t *testing.T) {
+	dir := setupListDir(t)
+	path := filepath.Join(dir, "Areas", "health.md")
+	original := "---\ntitle: \"Health\"\ndate: 2026-01-10\ncreated: 2026-01-10T08:00:00Z\n---\n\nBody.\n"
+	if err := os.WriteFile(path, []byte(original), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	before := time.Now().Add(-time.Second)
+	if err := Touch(&buf, dir, "health"); err != nil {
+		t.Fatalf("Touch() error: %v", err)
+	}
+	if buf.String() != "Touched: Areas/health.md\n" {
+		t.Errorf("Touch() output = %q", buf.String())
+	}
+	data, _ := os.ReadFile(path)
+	fm, body, _ := ParseFrontmatterFromBytes(data)
+	updated, ok := parseTimestamp(fm.Updated)
+	if !ok || updated.Before(before) || fm.Created != "2026-01-10T08:00:00Z" || body != "\nBody.\n" {
+		t.Errorf("touched note:\n%s", data)
+	}
+	if !strings.HasPrefix(string(data), "---\ntitle: \"Health\"\ndate: 2026-01-10\ncreated: 2026-01-10T08:00:00Z\nupdated: ") {
+		t.Errorf("Touch() changed other lines:\n%s", data)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "Inbox", "bare.md"), []byte("Just text.\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Touch(&buf, dir, "bare"); err == nil || !strings.Contains(err.Error(), "no frontmatter") {
+		t.Errorf("Touch() on a note without frontmatter = %v", err)
+	}
+}
+
+func TestWritesMaintainTimestamps(t *testing.T) {
+	dir := setupListDir(t)
+	before := time.Now().Add(-time.Second)
+
+	path, err := CreateNote(dir, NewNote{Title: "Stamped", Folder: "Inbox"}, CollisionAbort)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fm, _, _ := ParseFrontmatter(path)
+	created, ok := parseTimestamp(fm.Created)
+	if !ok || created.Before(before) || fm.Updated != fm.Created {
+		t.Fatalf("created note has created %q, updated %q", fm.Created, fm.Updated)
+	}
+
+	old := "2020-01-01T00:00:00Z"
+	data, _ := os.ReadFile(path)
+	content, _ := setFrontmatterField(string(data), "updated", old)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AppendToNote(dir, "Stamped", "More."); err != nil {
+		t.Fatal(err)
+	}
+	fm, body, _ := ParseFrontmatter(path)
+	if fm.Updated == old || fm.Created != timestamp(created) || !strings.HasSuffix(body, "More.\n") {
+		t.Errorf("after append: created %q, updated %q", fm.Created, fm.Updated)
+	}
+}
+
+func TestListPrefersUpdated(t *testing.T) {
+	dir := setupListDir(t)
+	// A sync touched the old note last, but its updated field says
+	// otherwise.
+	for name, content := range map[string]string{
+		"old.md":   "---\ntitle: Old\ndate: 2026-01-01\nupdated: 2026-01-02T10:00:00Z\n---\n",
+		"fresh.md": "---\ntitle: Fresh\ndate: 2026-02-01\nupdated: 2026-02-03T10:00:00Z\n---\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, "Inbox", name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Now()
+	if err := os.Chtimes(filepath.Join(dir, "Inbox", "old.md"), now, now); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := List(&buf, dir, ListOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	want := "2026-02-03  Fresh  (Inbox)\n2026-01-02  Old  (Inbox)\n"
+	if buf.String() != want {
+		t.Errorf("List() =\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := List(&buf, dir, ListOptions{Sort: "mtime"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), now.Format("2006-01-02")+"  Old") {
+		t.Errorf("List(--sort mtime) =\n%s", buf.String())
+	}
+}
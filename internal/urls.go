@@ -0,0 +1,160 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var urlPattern = regexp.MustCompile(`https?://[^\s<>"'\x60]+`)
+
+// ExtractURLs returns the http and https URLs in text, in order, without
+// trailing punctuation or the closing parenthesis of a markdown link.
+func ExtractURLs(text string) []string {
+	var urls []string
+	for _, m := range urlPattern.FindAllString(text, -1) {
+		if u := bareURL(m); u != "" {
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
+
+// URLIndex records which notes reference each URL.
+type URLIndex struct {
+	notes map[string][]string
+}
+
+// NewURLIndex indexes the URLs in the bodies of notes.
+func NewURLIndex(notes []Note) *URLIndex {
+	ix := &URLIndex{notes: make(map[string][]string)}
+	for _, n := range notes {
+		for _, u := range ExtractURLs(n.Body) {
+			ix.Add(u, n.FilePath)
+		}
+	}
+	return ix
+}
+
+// Add records that the note at path references u.
+func (ix *URLIndex) Add(u, path string) {
+	key := urlKey(u)
+	for _, p := range ix.notes[key] {
+		if p == path {
+			return
+		}
+	}
+	ix.notes[key] = append(ix.notes[key], path)
+}
+
+// Lookup returns the paths of the notes that reference u.
+func (ix *URLIndex) Lookup(u string) []string {
+	return ix.notes[urlKey(u)]
+}
+
+// URLDuplicate is a URL referenced by more than one note.
+type URLDuplicate struct {
+	URL   string
+	Paths []string
+}
+
+// Duplicates returns the URLs referenced by more than one note, sorted by
+// URL.
+func (ix *URLIndex) Duplicates() []URLDuplicate {
+	var dups []URLDuplicate
+	for u, paths := range ix.notes {
+		if len(paths) > 1 {
+			dups = append(dups, URLDuplicate{URL: u, Paths: paths})
+		}
+	}
+	sort.Slice(dups, func(i, j int) bool { return dups[i].URL < dups[j].URL })
+	return dups
+}
+
+// urlKey is the form URLs are compared in.
+func urlKey(u string) string {
+	return NormalizeURL(u)
+}
+
+// trackingParams are query parameters that identify a campaign or click
+// rather than the page itself.
+var trackingParams = map[string]bool{
+	"fbclid": true, "gclid": true, "dclid": true, "msclkid": true, "yclid": true,
+	"mc_cid": true, "mc_eid": true, "igshid": true, "_hsenc": true, "_hsmi": true,
+}
+
+// NormalizeURL returns the form of u used to decide whether two URLs point
+// to the same page. http and https are treated alike, the host is
+// lowercased without a "www." prefix or default port, trailing slashes and
+// fragments are dropped, tracking parameters such as utm_* are removed and
+// the remaining query parameters are sorted. Strings that are not absolute
+// URLs are returned trimmed.
+func NormalizeURL(u string) string {
+	u = strings.TrimSpace(u)
+	parsed, err := url.Parse(u)
+	if err != nil || parsed.Host == "" {
+		return u
+	}
+
+	scheme := strings.ToLower(parsed.Scheme)
+	if scheme == "http" {
+		scheme = "https"
+	}
+	host := strings.ToLower(parsed.Hostname())
+	host = strings.TrimPrefix(host, "www.")
+	if port := parsed.Port(); port != "" && port != "80" && port != "443" {
+		host += ":" + port
+	}
+
+	query := parsed.Query()
+	for key := range query {
+		if strings.HasPrefix(strings.ToLower(key), "utm_") || trackingParams[strings.ToLower(key)] {
+			query.Del(key)
+		}
+	}
+
+	result := scheme + "://" + host + strings.TrimRight(parsed.EscapedPath(), "/")
+	if len(query) > 0 {
+		result += "?" + query.Encode()
+	}
+	return result
+}
+
+// CheckURLs reports every URL referenced by more than one note.
+func CheckURLs(w io.Writer, baseDir string) error {
+	notes, err := ScanNotes(baseDir)
+	if err != nil {
+		return err
+	}
+
+	dups := NewURLIndex(notes).Duplicates()
+	if len(dups) == 0 {
+		_, _ = fmt.Fprintln(w, "No duplicate URLs found.")
+		return nil
+	}
+	for _, d := range dups {
+		_, _ = fmt.Fprintln(w, d.URL)
+		for _, p := range relPaths(baseDir, d.Paths) {
+			_, _ = fmt.Fprintf(w, "  %s\n", p)
+		}
+	}
+	_, _ = fmt.Fprintf(w, "\n%d URLs are referenced by more than one note.\n", len(dups))
+	return nil
+}
+
+// relPaths returns paths relative to baseDir for display.
+func relPaths(baseDir string, paths []string) []string {
+	rel := make([]string, 0, len(paths))
+	for _, p := range paths {
+		r, err := vaultRelPath(baseDir, p)
+		if err != nil {
+			r = filepath.ToSlash(p)
+		}
+		rel = append(rel, r)
+	}
+	return rel
+}
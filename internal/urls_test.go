@@ -0,0 +1,126 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExtractURLs(t *testing.T) {
+	text := "See [docs](https://go.dev/doc/) and https://example.com/a_(b), or <http://x.org/p>.\nNot ftp://nope.example"
+	got := ExtractURLs(text)
+	want := []string{"https://go.dev/doc/", "https://example.com/a_(b)", "http://x.org/p"}
+	if !sliceEqual(got, want) {
+		t.Errorf("ExtractURLs() = %v, want %v", got, want)
+	}
+}
+
+func TestURLIndex(t *testing.T) {
+	ix := NewURLIndex([]Note{
+		{FilePath: "a.md", Body: "https://go.dev/ and https://go.dev/ again"},
+		{FilePath: "b.md", Body: "- https://go.dev/"},
+	})
+	if got := ix.Lookup("https://go.dev/"); !sliceEqual(got, []string{"a.md", "b.md"}) {
+		t.Errorf("Lookup() = %v, want [a.md b.md]", got)
+	}
+	if got := ix.Lookup("https://other.dev/"); len(got) != 0 {
+		t.Errorf("Lookup(unknown) = %v, want none", got)
+	}
+}
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"https://go.dev/doc/", "https://go.dev/doc"},
+		{"HTTP://WWW.Go.dev/doc", "https://go.dev/doc"},
+		{"https://go.dev:443/", "https://go.dev"},
+		{"http://localhost:8080/x", "https://localhost:8080/x"},
+		{"https://go.dev/doc?utm_source=x&UTM_Medium=y&fbclid=z", "https://go.dev/doc"},
+		{"https://go.dev/search?q=go&b=1&utm_campaign=c", "https://go.dev/search?b=1&q=go"},
+		{"https://go.dev/doc#install", "https://go.dev/doc"},
+		{"https://go.dev/Doc", "https://go.dev/Doc"},
+		{"  not a url ", "not a url"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeURL(tt.in); got != tt.want {
+			t.Errorf("NormalizeURL(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestURLIndexNormalizes(t *testing.T) {
+	ix := NewURLIndex([]Note{
+		{FilePath: "a.md", Body: "https://example.com/post/?utm_source=feed"},
+		{FilePath: "b.md", Body: "http://www.example.com/post#comments"},
+		{FilePath: "c.md", Body: "https://example.com/other"},
+	})
+	if got := ix.Lookup("https://example.com/post"); !sliceEqual(got, []string{"a.md", "b.md"}) {
+		t.Errorf("Lookup() = %v, want [a.md b.md]", got)
+	}
+	dups := ix.Duplicates()
+	if len(dups) != 1 || dups[0].URL != "https://example.com/post" {
+		t.Errorf("Duplicates() = %+v, want only https://example.com/post", dups)
+	}
+}
+
+func TestCheckURLs(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"Resources/a.md": "---\ntitle: A\n---\nhttps://go.dev/doc/\n",
+		"Inbox/b.md":     "---\ntitle: B\n---\nRead https://go.dev/doc?utm_source=x\n",
+		"Areas/c.md":     "---\ntitle: C\n---\nhttps://example.com\n",
+	})
+
+	w := &bytes.Buffer{}
+	if err := CheckURLs(w, dir); err != nil {
+		t.Fatalf("CheckURLs() error: %v", err)
+	}
+	out := w.String()
+	for _, want := range []string{"https://go.dev/doc\n", "  Inbox/b.md\n", "  Resources/a.md\n", "1 URLs are referenced"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "example.com") {
+		t.Errorf("unique URL reported as duplicate:\n%s", out)
+	}
+}
+
+func TestCreateWarnsAboutDuplicateURL(t *testing.T) {
+	dir := setupTestNotesDir(t)
+	t.Setenv("EDITOR", "")
+	existing := filepath.Join(dir, "Resources", "go-docs.md")
+	if err := os.WriteFile(existing, []byte("---\ntitle: Go Docs\n---\n- https://go.dev/doc/\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Choose "Create and link to existing notes".
+	w := &bytes.Buffer{}
+	p := NewPrompter(strings.NewReader("Go Reference\n4\n\n\nhttp://go.dev/doc?utm_source=x\n1\n"), w)
+	if err := Create(p, dir, CreateOptions{}); err != nil {
+		t.Fatalf("Create() error: %v", err)
+	}
+	if !strings.Contains(w.String(), "already referenced in Resources/go-docs.md") {
+		t.Errorf("expected duplicate warning, got: %s", w.String())
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "Resources", "go-reference.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "- See [[Go Docs]]") {
+		t.Errorf("expected link to existing note, got:\n%s", data)
+	}
+
+	// Choose "Abort".
+	p = NewPrompter(strings.NewReader("Go Again\n4\n\n\nhttps://go.dev/doc\n3\n"), &bytes.Buffer{})
+	if err := Create(p, dir, CreateOptions{}); !errors.Is(err, ErrDuplicateURL) {
+		t.Fatalf("Create() error = %v, want ErrDuplicateURL", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Resources", "go-again.md")); !os.IsNotExist(err) {
+		t.Errorf("aborted note was written: %v", err)
+	}
+}
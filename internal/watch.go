@@ -0,0 +1,259 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultWatchInterval is how often Watch polls the vault.
+const DefaultWatchInterval = 2 * time.Second
+
+// ChangeKind says what happened to a note between two polls.
+type ChangeKind string
+
+const (
+	NoteCreated  ChangeKind = "created"
+	NoteModified ChangeKind = "modified"
+	NoteDeleted  ChangeKind = "deleted"
+	NoteRenamed  ChangeKind = "renamed"
+)
+
+// Change is a note that was created, modified, deleted or renamed.
+type Change struct {
+	Kind ChangeKind
+	Path string
+	// OldPath is the previous path of a renamed note.
+	OldPath string
+	// Note is the note as parsed after the change, or as last seen for
+	// deletions.
+	Note Note
+}
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+	hash    uint64
+}
+
+// Watcher keeps a parsed copy of every note in a vault and updates it by
+// polling. Only files whose size or modification time changed are read
+// again, so polling a large vault is cheap.
+type Watcher struct {
+	baseDir string
+	files   map[string]fileState
+	notes   map[string]Note
+}
+
+// NewWatcher reads every note in baseDir.
+func NewWatcher(baseDir string) (*Watcher, error) {
+	w := &Watcher{baseDir: baseDir, files: make(map[string]fileState), notes: make(map[string]Note)}
+	if _, err := w.Poll(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Notes returns the cached notes, ordered by path.
+func (w *Watcher) Notes() []Note {
+	notes := make([]Note, 0, len(w.notes))
+	for _, n := range w.notes {
+		notes = append(notes, n)
+	}
+	sort.Slice(notes, func(i, j int) bool { return notes[i].FilePath < notes[j].FilePath })
+	return notes
+}
+
+// Poll compares the vault with the last poll, updates the cache and
+// returns what changed, ordered by path. A note that disappeared and
+// reappeared elsewhere with the same content is reported as renamed.
+// Files whose modification time changed but whose content did not are
+// updated in the cache without being reported.
+func (w *Watcher) Poll() ([]Change, error) {
+	seen := make(map[string]bool, len(w.files))
+	var created, modified []Change
+	for _, folder := range NoteFolders {
+		dir := filepath.Join(w.baseDir, folder)
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("reading %s: %w", dir, err)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".md") {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				// Deleted since ReadDir; the next poll reports it.
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			seen[path] = true
+
+			old, known := w.files[path]
+			if known && old.modTime.Equal(info.ModTime()) && old.size == info.Size() {
+				continue
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				delete(seen, path)
+				continue
+			}
+			state := fileState{modTime: info.ModTime(), size: info.Size(), hash: contentHash(data)}
+			fm, body, _ := ParseFrontmatterFromBytes(data)
+			note := Note{Frontmatter: fm, Body: body, FilePath: path, Folder: folder, ModTime: info.ModTime()}
+			w.files[path] = state
+			w.notes[path] = note
+
+			switch {
+			case !known:
+				created = append(created, Change{Kind: NoteCreated, Path: path, Note: note})
+			case old.hash != state.hash:
+				modified = append(modified, Change{Kind: NoteModified, Path: path, Note: note})
+			}
+		}
+	}
+
+	var deleted []Change
+	for path := range w.files {
+		if !seen[path] {
+			deleted = append(deleted, Change{Kind: NoteDeleted, Path: path, Note: w.notes[path]})
+		}
+	}
+
+	changes := pairRenames(w.files, created, deleted)
+	for _, c := range deleted {
+		delete(w.files, c.Path)
+		delete(w.notes, c.Path)
+	}
+	changes = append(changes, modified...)
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+// pairRenames turns a deletion and a creation with the same content into
+// a single rename. files must still hold the state of the deleted paths.
+func pairRenames(files map[string]fileState, created, deleted []Change) []Change {
+	byHash := make(map[uint64][]int)
+	for i, c := range deleted {
+		h := files[c.Path].hash
+		byHash[h] = append(byHash[h], i)
+	}
+
+	renamedFrom := make(map[int]bool)
+	var changes []Change
+	for _, c := range created {
+		candidates := byHash[files[c.Path].hash]
+		if len(candidates) == 0 {
+			changes = append(changes, c)
+			continue
+		}
+		i := candidates[0]
+		byHash[files[c.Path].hash] = candidates[1:]
+		renamedFrom[i] = true
+		changes = append(changes, Change{Kind: NoteRenamed, Path: c.Path, OldPath: deleted[i].Path, Note: c.Note})
+	}
+	for i, c := range deleted {
+		if !renamedFrom[i] {
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+func contentHash(data []byte) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write(data)
+	return h.Sum64()
+}
+
+// WatchOptions configures Watch.
+type WatchOptions struct {
+	// Interval is the time between polls. Zero means DefaultWatchInterval.
+	Interval time.Duration
+	// Hooks are shell commands run for every change. The change is passed
+	// in the environment as QN_EVENT, QN_PATH, QN_OLD_PATH and QN_TITLE,
+	// with paths relative to the vault.
+	Hooks []string
+}
+
+// Watch polls baseDir until ctx is done, printing each change to w and
+// running the configured hooks for it. Failing hooks are reported and do
+// not stop the watch.
+func Watch(ctx context.Context, w io.Writer, baseDir string, opts WatchOptions) error {
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	watcher, err := NewWatcher(baseDir)
+	if err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(w, "Watching %d notes in %s every %s\n", len(watcher.notes), baseDir, interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		changes, err := watcher.Poll()
+		if err != nil {
+			_, _ = fmt.Fprintf(w, "Warning: %v\n", err)
+			continue
+		}
+		for _, c := range changes {
+			rel := relPaths(baseDir, []string{c.Path})[0]
+			if c.Kind == NoteRenamed {
+				_, _ = fmt.Fprintf(w, "%-9s %s -> %s\n", c.Kind, relPaths(baseDir, []string{c.OldPath})[0], rel)
+			} else {
+				_, _ = fmt.Fprintf(w, "%-9s %s\n", c.Kind, rel)
+			}
+			for _, hook := range opts.Hooks {
+				if err := runHook(ctx, w, baseDir, hook, c); err != nil {
+					_, _ = fmt.Fprintf(w, "Warning: hook %q failed for %s: %v\n", hook, rel, err)
+				}
+			}
+		}
+	}
+}
+
+// runHook runs command through the shell with the change in its
+// environment and its output sent to w.
+func runHook(ctx context.Context, w io.Writer, baseDir, command string, c Change) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Dir = baseDir
+	cmd.Stdout = w
+	cmd.Stderr = w
+
+	oldPath := ""
+	if c.OldPath != "" {
+		oldPath = relPaths(baseDir, []string{c.OldPath})[0]
+	}
+	cmd.Env = append(os.Environ(),
+		"QN_EVENT="+string(c.Kind),
+		"QN_PATH="+relPaths(baseDir, []string{c.Path})[0],
+		"QN_OLD_PATH="+oldPath,
+		"QN_TITLE="+c.Note.Frontmatter.Title,
+	)
+	return cmd.Run()
+}
@@ -0,0 +1,141 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWatcherPoll(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"Inbox/a.md":     "---\ntitle: A\n---\nfirst\n",
+		"Inbox/b.md":     "---\ntitle: B\n---\nmove me\n",
+		"Areas/c.md":     "---\ntitle: C\n---\ndelete me\n",
+		"Areas/touch.md": "---\ntitle: Touch\n---\nsame\n",
+	})
+	w, err := NewWatcher(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(w.Notes()); got != 4 {
+		t.Fatalf("initial notes = %d, want 4", got)
+	}
+	if changes, _ := w.Poll(); len(changes) != 0 {
+		t.Fatalf("changes without edits = %+v", changes)
+	}
+
+	later := time.Now().Add(time.Minute)
+	writeFiles(t, dir, map[string]string{
+		"Inbox/a.md":     "---\ntitle: A2\n---\nsecond\n",
+		"Resources/d.md": "---\ntitle: D\n---\nnew\n",
+	})
+	if err := os.Chtimes(filepath.Join(dir, "Inbox", "a.md"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(filepath.Join(dir, "Areas", "touch.md"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "Archive"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(filepath.Join(dir, "Inbox", "b.md"), filepath.Join(dir, "Archive", "b.md")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, "Areas", "c.md")); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := w.Poll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range changes {
+		s := string(c.Kind) + " " + relPaths(dir, []string{c.Path})[0]
+		if c.OldPath != "" {
+			s += " from " + relPaths(dir, []string{c.OldPath})[0]
+		}
+		got = append(got, s)
+	}
+	want := []string{
+		"renamed Archive/b.md from Inbox/b.md",
+		"deleted Areas/c.md",
+		"modified Inbox/a.md",
+		"created Resources/d.md",
+	}
+	if !sliceEqual(got, want) {
+		t.Errorf("changes =\n  %s\nwant\n  %s", strings.Join(got, "\n  "), strings.Join(want, "\n  "))
+	}
+	if changes[1].Note.Frontmatter.Title != "C" {
+		t.Errorf("deleted note title = %q, want C", changes[1].Note.Frontmatter.Title)
+	}
+
+	var titles []string
+	for _, n := range w.Notes() {
+		titles = append(titles, n.Frontmatter.Title)
+	}
+	if !sliceEqual(titles, []string{"B", "Touch", "A2", "D"}) {
+		t.Errorf("cached titles = %v", titles)
+	}
+}
+
+func TestWatchRunsHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook command uses sh")
+	}
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"Inbox/a.md": "---\ntitle: A\n---\n"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out := &syncBuffer{}
+	done := make(chan error, 1)
+	go func() {
+		done <- Watch(ctx, out, dir, WatchOptions{
+			Interval: 10 * time.Millisecond,
+			Hooks:    []string{`echo "hook $QN_EVENT $QN_PATH $QN_TITLE"`},
+		})
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	writeFiles(t, dir, map[string]string{"Projects/p.md": "---\ntitle: Plan\n---\n"})
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(out.String(), "hook created Projects/p.md Plan") {
+		if time.Now().After(deadline) {
+			t.Fatalf("hook did not run, output:\n%s", out.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Watch() error: %v", err)
+	}
+	if !strings.Contains(out.String(), "created   Projects/p.md") {
+		t.Errorf("change not printed:\n%s", out.String())
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
@@ -0,0 +1,460 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// uiResult is a note in a listing or search results page.
+type uiResult struct {
+	Title   string
+	Href    string
+	Folder  string
+	Excerpt string
+}
+
+// captureForm holds the quick-capture fields so they survive a failed
+// submission.
+type captureForm struct {
+	Title  string
+	Folder string
+	Tags   string
+	Body   string
+	URLs   string
+	// Duplicates are the URLs other notes already reference, shown with
+	// a choice of linking to those notes or creating the note anyway.
+	Duplicates []uiDuplicate
+}
+
+// uiDuplicate is a captured URL and the notes already referencing it.
+type uiDuplicate struct {
+	URL   string
+	Notes []pageLink
+}
+
+type uiPage struct {
+	Title   string
+	Query   string
+	Folders []pageLink
+	Tags    []pageLink
+	Error   string
+
+	// Note pages.
+	Date      string
+	Folder    pageLink
+	NoteTags  []pageLink
+	Body      template.HTML
+	Backlinks []pageLink
+
+	// Listing and search pages.
+	Results []uiResult
+	Empty   string
+
+	// Capture shows the quick-capture form on the home page.
+	Capture        bool
+	Form           captureForm
+	CaptureFolders []string
+}
+
+var uiTemplate = template.Must(template.New("ui").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}} · qn</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 0; color: #222; line-height: 1.5; }
+header { display: flex; gap: 1rem; align-items: center; padding: 0.5rem 1rem; border-bottom: 1px solid #ddd; }
+header form { flex: 1; }
+header input { width: 100%; max-width: 24rem; padding: 0.25rem 0.5rem; }
+.layout { display: flex; gap: 2rem; padding: 1rem; }
+aside { flex: 0 0 12rem; font-size: 0.9rem; }
+aside ul { list-style: none; padding: 0; margin: 0 0 1rem; }
+main { flex: 1; max-width: 46rem; }
+nav, .meta, .folder { font-size: 0.9rem; color: #666; }
+a { color: #0b5fa5; }
+pre { background: #f5f5f5; padding: 0.75rem; overflow-x: auto; }
+code { font-size: 0.9em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ddd; padding: 0.25rem 0.5rem; }
+li.task { list-style: none; }
+.missing { color: #999; }
+.tag { margin-right: 0.5rem; }
+.results li { margin-bottom: 0.5rem; }
+.excerpt { display: block; font-size: 0.9rem; color: #444; }
+.error { color: #a00; }
+.capture label { display: block; margin-top: 0.5rem; }
+.capture input, .capture textarea, .capture select { width: 100%; box-sizing: border-box; }
+</style>
+</head>
+<body>
+<header>
+<a href="/"><strong>qn</strong></a>
+<form action="/search" method="get"><input type="search" name="q" value="{{.Query}}" placeholder="Search notes" aria-label="Search notes"></form>
+</header>
+<div class="layout">
+<aside>
+<h3>Folders</h3>
+<ul>
+{{- range .Folders}}
+<li><a href="{{.Href}}">{{.Title}}</a></li>
+{{- end}}
+</ul>
+{{- if .Tags}}
+<h3>Tags</h3>
+<ul>
+{{- range .Tags}}
+<li><a class="tag" href="{{.Href}}">{{.Title}}</a></li>
+{{- end}}
+</ul>
+{{- end}}
+</aside>
+<main>
+<h1>{{.Title}}</h1>
+{{- if .Error}}
+<p class="error">{{.Error}}</p>
+{{- end}}
+{{- if .Folder.Title}}
+<p class="meta">{{if .Date}}{{.Date}} · {{end}}<a href="{{.Folder.Href}}">{{.Folder.Title}}</a>{{range .NoteTags}} <a class="tag" href="{{.Href}}">#{{.Title}}</a>{{end}}</p>
+{{.Body}}
+{{- if .Backlinks}}
+<section class="backlinks">
+<h2>Backlinks</h2>
+<ul>
+{{- range .Backlinks}}
+<li><a href="{{.Href}}">{{.Title}}</a></li>
+{{- end}}
+</ul>
+</section>
+{{- end}}
+{{- end}}
+{{- if .Results}}
+<ul class="results">
+{{- range .Results}}
+<li><a href="{{.Href}}">{{.Title}}</a> <span class="folder">({{.Folder}})</span>{{if .Excerpt}}<span class="excerpt">{{.Excerpt}}</span>{{end}}</li>
+{{- end}}
+</ul>
+{{- else if .Empty}}
+<p>{{.Empty}}</p>
+{{- end}}
+{{- if .Capture}}
+<section class="capture">
+<h2>Quick capture</h2>
+<form action="/capture" method="post">
+<label>Title <input name="title" value="{{.Form.Title}}" required></label>
+<label>Folder <select name="folder">
+{{- range .CaptureFolders}}
+<option{{if eq . $.Form.Folder}} selected{{end}}>{{.}}</option>
+{{- end}}
+</select></label>
+<label>Tags <input name="tags" value="{{.Form.Tags}}" placeholder="comma-separated"></label>
+<label>Body <textarea name="body" rows="4">{{.Form.Body}}</textarea></label>
+<label>URLs <input name="urls" value="{{.Form.URLs}}" placeholder="comma-separated, Resources only"></label>
+{{- if .Form.Duplicates}}
+<div class="error">
+{{- range .Form.Duplicates}}
+<p>{{.URL}} is already referenced in{{range $i, $n := .Notes}}{{if $i}},{{end}} <a href="{{$n.Href}}">{{$n.Title}}</a>{{end}}.</p>
+{{- end}}
+</div>
+<p><button type="submit" name="on_duplicate_url" value="link">Create and link to existing notes</button>
+<button type="submit" name="on_duplicate_url" value="ignore">Create anyway</button></p>
+{{- else}}
+<p><button type="submit">Create</button></p>
+{{- end}}
+</form>
+</section>
+{{- end}}
+</main>
+</div>
+</body>
+</html>
+`))
+
+// registerUI adds the browser pages to s.
+func (s *Server) registerUI() {
+	s.mux.HandleFunc("GET /{$}", s.handleHome)
+	s.mux.HandleFunc("GET /search", s.handleSearchPage)
+	s.mux.HandleFunc("GET /folders/{folder}", s.handleFolderPage)
+	s.mux.HandleFunc("GET /tags/{tag}", s.handleTagPage)
+	s.mux.HandleFunc("GET /notes/{note...}", s.handleNotePage)
+	s.mux.HandleFunc("POST /capture", s.handleCapture)
+}
+
+func (s *Server) handleHome(w http.ResponseWriter, r *http.Request) {
+	s.renderHome(w, http.StatusOK, captureForm{Folder: DefaultFolder()}, "")
+}
+
+func (s *Server) renderHome(w http.ResponseWriter, status int, form captureForm, errMsg string) {
+	notes, ok := s.scanForUI(w)
+	if !ok {
+		return
+	}
+	sortByUpdated(notes)
+	if len(notes) > 10 {
+		notes = notes[:10]
+	}
+	page := s.uiPage("Recent notes", notes)
+	page.Results = s.uiResults(notes, "")
+	page.Empty = "No notes yet."
+	page.Capture = true
+	page.Form = form
+	page.CaptureFolders = Folders
+	page.Error = errMsg
+	s.renderUI(w, status, page)
+}
+
+func (s *Server) handleSearchPage(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	notes, ok := s.scanForUI(w)
+	if !ok {
+		return
+	}
+	page := s.uiPage("Search", notes)
+	page.Query = query
+	if query == "" {
+		page.Empty = "Type something to search for."
+		s.renderUI(w, http.StatusOK, page)
+		return
+	}
+
+	q := strings.ToLower(query)
+	var found []Note
+	for _, res := range searchNotes(notes, q) {
+		found = append(found, res.Note)
+	}
+	page.Title = fmt.Sprintf("Results for %q", query)
+	page.Results = s.uiResults(found, q)
+	page.Empty = fmt.Sprintf("No notes found matching %q.", query)
+	s.renderUI(w, http.StatusOK, page)
+}
+
+func (s *Server) handleFolderPage(w http.ResponseWriter, r *http.Request) {
+	folder := r.PathValue("folder")
+	if !isNoteFolder(folder) {
+		s.renderUIError(w, http.StatusNotFound, fmt.Sprintf("No folder named %q.", folder))
+		return
+	}
+	notes, ok := s.scanForUI(w)
+	if !ok {
+		return
+	}
+	var inFolder []Note
+	for _, n := range notes {
+		if n.Folder == folder {
+			inFolder = append(inFolder, n)
+		}
+	}
+	sortByTitle(inFolder)
+	page := s.uiPage(folder, notes)
+	page.Results = s.uiResults(inFolder, "")
+	page.Empty = "No notes in " + folder + "."
+	s.renderUI(w, http.StatusOK, page)
+}
+
+func (s *Server) handleTagPage(w http.ResponseWriter, r *http.Request) {
+	tag := r.PathValue("tag")
+	notes, ok := s.scanForUI(w)
+	if !ok {
+		return
+	}
+	var tagged []Note
+	for _, n := range notes {
+		if containsTag(n.Frontmatter.Tags, tag) {
+			tagged = append(tagged, n)
+		}
+	}
+	sortByTitle(tagged)
+	page := s.uiPage("#"+tag, notes)
+	page.Results = s.uiResults(tagged, "")
+	page.Empty = "No notes tagged #" + tag + "."
+	s.renderUI(w, http.StatusOK, page)
+}
+
+func (s *Server) handleNotePage(w http.ResponseWriter, r *http.Request) {
+	notes, ok := s.scanForUI(w)
+	if !ok {
+		return
+	}
+	note, err := pickNote(notes, s.baseDir, r.PathValue("note"))
+	if err != nil {
+		s.renderUIError(w, lookupStatus(err), err.Error())
+		return
+	}
+
+	index := NewLinkIndex(notes)
+	resolve := func(target string) (string, bool) {
+		linked, ok := index.Resolve(target)
+		if !ok {
+			return "", false
+		}
+		return s.noteHref(linked), true
+	}
+
+	title := noteTitle(note)
+	page := s.uiPage(title, notes)
+	page.Date = note.Frontmatter.Date
+	page.Folder = pageLink{Title: note.Folder, Href: "/folders/" + url.PathEscape(note.Folder)}
+	page.Body = template.HTML(RenderMarkdown(stripTitleHeading(note.Body, title), resolve))
+	for _, t := range note.Frontmatter.Tags {
+		page.NoteTags = append(page.NoteTags, pageLink{Title: t, Href: tagHref(t)})
+	}
+	for _, b := range index.Backlinks(note) {
+		page.Backlinks = append(page.Backlinks, pageLink{Title: noteTitle(b), Href: s.noteHref(b)})
+	}
+	s.renderUI(w, http.StatusOK, page)
+}
+
+// handleCapture creates a note from the quick-capture form and redirects
+// to it. Taken filenames get a -N suffix so nothing typed is lost.
+func (s *Server) handleCapture(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBytes)
+	if err := r.ParseForm(); err != nil {
+		s.renderUIError(w, http.StatusBadRequest, "Invalid form: "+err.Error())
+		return
+	}
+	form := captureForm{
+		Title:  strings.TrimSpace(r.PostForm.Get("title")),
+		Folder: r.PostForm.Get("folder"),
+		Tags:   r.PostForm.Get("tags"),
+		Body:   strings.TrimSpace(r.PostForm.Get("body")),
+		URLs:   r.PostForm.Get("urls"),
+	}
+	if form.Title == "" {
+		s.renderHome(w, http.StatusBadRequest, form, "Title is required.")
+		return
+	}
+	if !isNoteFolder(form.Folder) {
+		s.renderHome(w, http.StatusBadRequest, form, fmt.Sprintf("Unknown folder %q.", form.Folder))
+		return
+	}
+
+	note := NewNote{
+		Title:  form.Title,
+		Folder: form.Folder,
+		Tags:   NormalizeTags(form.Tags),
+		Body:   form.Body,
+	}
+	if form.Folder == "Resources" {
+		for _, u := range strings.Split(form.URLs, ",") {
+			if u = strings.TrimSpace(u); u != "" {
+				note.URLs = append(note.URLs, u)
+			}
+		}
+	}
+
+	// Until the user picks what to do about URLs already in the vault,
+	// stop and show them where they are.
+	onDuplicate := r.PostForm.Get("on_duplicate_url")
+	if onDuplicate != DuplicateLink && onDuplicate != DuplicateIgnore {
+		onDuplicate = DuplicateAbort
+	}
+	s.writeMu.Lock()
+	dups, links, err := resolveDuplicateURLs(s.baseDir, note.URLs, onDuplicate)
+	var path string
+	if err == nil {
+		note.URLs = append(note.URLs, links...)
+		path, err = CreateNote(s.baseDir, note, CollisionSuffix)
+	}
+	if err == nil {
+		autoCommit(s.log, s.baseDir, fmt.Sprintf("qn: create %q in %s", note.Title, note.Folder), path)
+	}
+	s.writeMu.Unlock()
+	if errors.Is(err, ErrDuplicateURL) {
+		for _, d := range dups {
+			ud := uiDuplicate{URL: d.URL}
+			for _, rel := range d.Notes {
+				ud.Notes = append(ud.Notes, pageLink{Title: rel, Href: "/notes/" + escapePath(rel)})
+			}
+			form.Duplicates = append(form.Duplicates, ud)
+		}
+		s.renderHome(w, http.StatusConflict, form, "Some of these URLs are already in the vault.")
+		return
+	}
+	if err != nil {
+		s.renderHome(w, http.StatusInternalServerError, form, err.Error())
+		return
+	}
+
+	rel, _ := vaultRelPath(s.baseDir, path)
+	http.Redirect(w, r, "/notes/"+escapePath(rel), http.StatusSeeOther)
+}
+
+// scanForUI scans the vault, rendering an error page if that fails.
+func (s *Server) scanForUI(w http.ResponseWriter) ([]Note, bool) {
+	notes, err := ScanNotes(s.baseDir)
+	if err != nil {
+		s.renderUIError(w, http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+	return notes, true
+}
+
+// uiPage returns a page with the folder and tag navigation filled in.
+func (s *Server) uiPage(title string, notes []Note) uiPage {
+	page := uiPage{Title: title}
+	for _, f := range NoteFolders {
+		page.Folders = append(page.Folders, pageLink{Title: f, Href: "/folders/" + url.PathEscape(f)})
+	}
+	for _, tc := range countTags(notes) {
+		page.Tags = append(page.Tags, pageLink{Title: fmt.Sprintf("#%s (%d)", tc.Tag, tc.Count), Href: tagHref(tc.Tag)})
+	}
+	return page
+}
+
+// uiResults lists notes, with an excerpt around the lowercased query q
+// when it is not empty.
+func (s *Server) uiResults(notes []Note, q string) []uiResult {
+	results := make([]uiResult, 0, len(notes))
+	for _, n := range notes {
+		res := uiResult{Title: noteTitle(n), Href: s.noteHref(n), Folder: n.Folder}
+		if q != "" {
+			res.Excerpt = findExcerpt(n.Body, q)
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+func (s *Server) noteHref(n Note) string {
+	rel, err := vaultRelPath(s.baseDir, n.FilePath)
+	if err != nil {
+		rel = n.FilePath
+	}
+	return "/notes/" + escapePath(rel)
+}
+
+func (s *Server) renderUIError(w http.ResponseWriter, status int, msg string) {
+	page := s.uiPage(http.StatusText(status), nil)
+	page.Error = msg
+	s.renderUI(w, status, page)
+}
+
+func (s *Server) renderUI(w http.ResponseWriter, status int, page uiPage) {
+	var buf bytes.Buffer
+	if err := uiTemplate.Execute(&buf, page); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = w.Write(buf.Bytes())
+}
+
+func tagHref(tag string) string {
+	return "/tags/" + url.PathEscape(tag)
+}
+
+// escapePath escapes each segment of a slash-separated path for use in a
+// URL.
+func escapePath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, seg := range segments {
+		segments[i] = url.PathEscape(seg)
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,195 @@
+package internal
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// getPage requests target from srv and returns the status and body.
+func getPage(t *testing.T, srv http.Handler, target string) (int, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", target, nil)
+	req.Host = "localhost:7777"
+	srv.ServeHTTP(rec, req)
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("GET %s: Content-Type = %q, want HTML", target, ct)
+	}
+	return rec.Code, rec.Body.String()
+}
+
+func TestUIHome(t *testing.T) {
+	srv := NewServer(setupServerVault(t), "", nil)
+
+	code, body := getPage(t, srv, "/")
+	if code != http.StatusOK {
+		t.Fatalf("status = %d", code)
+	}
+	for _, want := range []string{
+		`<a href="/folders/Areas">Areas</a>`,
+		`<a class="tag" href="/tags/fitness">#fitness (2)</a>`,
+		`<a href="/notes/Resources/go-concurrency.md">Go Concurrency</a>`,
+		`<form action="/capture" method="post">`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("home page missing %q", want)
+		}
+	}
+}
+
+func TestUINotePage(t *testing.T) {
+	srv := NewServer(setupServerVault(t), "", nil)
+
+	code, body := getPage(t, srv, "/notes/Inbox/2026-01-05-runs.md")
+	if code != http.StatusOK {
+		t.Fatalf("status = %d", code)
+	}
+	if !strings.Contains(body, "<h1>Running Log</h1>") || !strings.Contains(body, "<p>Five kilometres today.</p>") {
+		t.Errorf("note not rendered:\n%s", body)
+	}
+	if !strings.Contains(body, `<h2>Backlinks</h2>`) || !strings.Contains(body, `<a href="/notes/Areas/health.md">Health</a>`) {
+		t.Errorf("backlink missing:\n%s", body)
+	}
+
+	_, body = getPage(t, srv, "/notes/health")
+	if !strings.Contains(body, `<a class="wikilink" href="/notes/Inbox/2026-01-05-runs.md">Running Log</a>`) {
+		t.Errorf("wikilink not resolved:\n%s", body)
+	}
+
+	if code, _ := getPage(t, srv, "/notes/missing"); code != http.StatusNotFound {
+		t.Errorf("missing note: status = %d, want 404", code)
+	}
+}
+
+func TestUIListings(t *testing.T) {
+	srv := NewServer(setupServerVault(t), "", nil)
+
+	_, body := getPage(t, srv, "/search?q=kilometres")
+	if !strings.Contains(body, "Running Log") || !strings.Contains(body, `<span class="excerpt">Five kilometres today.</span>`) {
+		t.Errorf("search results missing excerpt:\n%s", body)
+	}
+	if strings.Contains(body, "Go Concurrency</a> <span") {
+		t.Errorf("search returned a non-matching note")
+	}
+
+	_, body = getPage(t, srv, "/tags/fitness")
+	if !strings.Contains(body, ">Health</a>") || !strings.Contains(body, ">Running Log</a>") || strings.Contains(body, ">Go Concurrency</a> <span") {
+		t.Errorf("tag page lists the wrong notes:\n%s", body)
+	}
+
+	_, body = getPage(t, srv, "/folders/Resources")
+	if !strings.Contains(body, ">Go Concurrency</a>") || strings.Contains(body, ">Health</a> <span") {
+		t.Errorf("folder page lists the wrong notes:\n%s", body)
+	}
+	if code, _ := getPage(t, srv, "/folders/Nowhere"); code != http.StatusNotFound {
+		t.Errorf("unknown folder: status = %d, want 404", code)
+	}
+}
+
+func TestUICapture(t *testing.T) {
+	dir := setupServerVault(t)
+	srv := NewServer(dir, "", nil)
+
+	post := func(form url.Values, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/capture", strings.NewReader(form.Encode()))
+		req.Host = "localhost:7777"
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+
+	form := url.Values{"title": {"Go Docs"}, "folder": {"Resources"}, "tags": {"Go, Docs"}, "urls": {"https://go.dev/doc"}}
+	rec := post(form, nil)
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/notes/Resources/go-docs.md" {
+		t.Fatalf("status = %d, Location = %q", rec.Code, rec.Header().Get("Location"))
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "Resources", "go-docs.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "tags: [go, docs]") || !strings.Contains(string(data), "- https://go.dev/doc") {
+		t.Errorf("captured note:\n%s", data)
+	}
+
+	// A URL already in the vault stops the capture and shows where it is.
+	rec = post(form, nil)
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), `https://go.dev/doc is already referenced in <a href="/notes/Resources/go-docs.md">Resources/go-docs.md</a>.`) ||
+		!strings.Contains(rec.Body.String(), `name="on_duplicate_url" value="link"`) {
+		t.Errorf("duplicate URL: status = %d, body:\n%s", rec.Code, rec.Body)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Resources", "go-docs-2.md")); !os.IsNotExist(err) {
+		t.Errorf("duplicate URL should not create a note, stat error: %v", err)
+	}
+
+	// Choosing to link saves the same title with a suffix rather than
+	// losing it, and links to the existing note.
+	form.Set("on_duplicate_url", "link")
+	if rec := post(form, nil); rec.Header().Get("Location") != "/notes/Resources/go-docs-2.md" {
+		t.Errorf("second capture: Location = %q", rec.Header().Get("Location"))
+	}
+	data, err = os.ReadFile(filepath.Join(dir, "Resources", "go-docs-2.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "- See [[Go Docs]]") {
+		t.Errorf("linked capture:\n%s", data)
+	}
+
+	rec = post(url.Values{"title": {""}, "folder": {"Inbox"}, "body": {"keep me"}}, nil)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "Title is required.") || !strings.Contains(rec.Body.String(), "keep me</textarea>") {
+		t.Errorf("missing title: status = %d, body:\n%s", rec.Code, rec.Body)
+	}
+
+	rec = post(url.Values{"title": {"Evil"}, "folder": {"Inbox"}}, map[string]string{"Sec-Fetch-Site": "cross-site"})
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("cross-site post: status = %d, want 403", rec.Code)
+	}
+}
+
+func TestUIToken(t *testing.T) {
+	srv := NewServer(setupServerVault(t), "s3cret", nil)
+
+	if code, _ := getPage(t, srv, "/"); code != http.StatusUnauthorized {
+		t.Errorf("no token: status = %d, want 401", code)
+	}
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest("GET", "/search?q=go&token=s3cret", nil))
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/search?q=go" {
+		t.Fatalf("token login: status = %d, Location = %q", rec.Code, rec.Header().Get("Location"))
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != tokenCookie {
+		t.Fatalf("cookies = %v", cookies)
+	}
+
+	req := httptest.NewRequest("GET", "/search?q=go", nil)
+	req.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("with cookie: status = %d, want 200", rec.Code)
+	}
+}
+
+func TestUIHost(t *testing.T) {
+	srv := NewServer(setupServerVault(t), "", nil)
+	for _, target := range []string{"/", "/search?q=go", "/folders/Areas", "/tags/go", "/notes/Areas/health.md"} {
+		req := httptest.NewRequest("GET", target, nil)
+		req.Host = "evil.example"
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		if rec.Code != http.StatusForbidden || strings.Contains(rec.Body.String(), "Health") {
+			t.Errorf("GET %s from evil.example: status = %d, body:\n%s", target, rec.Code, rec.Body)
+		}
+	}
+}
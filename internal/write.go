@@ -0,0 +1,148 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// WriteOptions configures WriteFileAtomic.
+type WriteOptions struct {
+	// Exclusive makes the write fail with an error wrapping fs.ErrExist
+	// instead of replacing a file that already exists at the path.
+	Exclusive bool
+	// Backup keeps the previous contents of the file as path + ".bak".
+	Backup bool
+}
+
+// WriteFileAtomic writes data to path without ever leaving a partially
+// written file behind. The data goes to a temp file in the same directory,
+// which is synced to disk and then renamed over path. An existing file's
+// permission bits are preserved; new files get 0644.
+func WriteFileAtomic(path string, data []byte, opts WriteOptions) error {
+	dir := filepath.Dir(path)
+	perm := fs.FileMode(0o644)
+
+	info, err := os.Stat(path)
+	switch {
+	case err == nil:
+		if opts.Exclusive {
+			return &fs.PathError{Op: "create", Path: path, Err: fs.ErrExist}
+		}
+		perm = info.Mode().Perm()
+		if opts.Backup {
+			if err := backupFile(path); err != nil {
+				return err
+			}
+		}
+	case !errors.Is(err, fs.ErrNotExist):
+		return err
+	}
+
+	tmp, err := writeTemp(dir, filepath.Base(path), data, perm)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp) }()
+
+	if opts.Exclusive {
+		// A hard link fails if path exists, so a file created between the
+		// Stat above and now is still never replaced.
+		if err := os.Link(tmp, path); err != nil {
+			if errors.Is(err, fs.ErrExist) {
+				return err
+			}
+			// The filesystem may not support hard links; fall back to a
+			// plain exclusive create.
+			return createExclusive(path, data, perm)
+		}
+	} else if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+
+	syncDir(dir)
+	return nil
+}
+
+// RewriteFile replaces the contents of an existing note atomically. A .bak
+// copy of the previous contents is kept when MDNOTES_BACKUP is set to a
+// true value.
+func RewriteFile(path string, data []byte) error {
+	backup, _ := strconv.ParseBool(os.Getenv("MDNOTES_BACKUP"))
+	return WriteFileAtomic(path, data, WriteOptions{Backup: backup})
+}
+
+// writeTemp writes data to a new synced temp file in dir and returns its path.
+func writeTemp(dir, name string, data []byte, perm fs.FileMode) (string, error) {
+	f, err := os.CreateTemp(dir, "."+name+".tmp-*")
+	if err != nil {
+		return "", err
+	}
+	tmp := f.Name()
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmp)
+		return "", err
+	}
+	if err := f.Chmod(perm); err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmp)
+		return "", err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmp)
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return "", err
+	}
+	return tmp, nil
+}
+
+// backupFile copies path to path + ".bak", replacing any older backup.
+func backupFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("backing up %s: %w", path, err)
+	}
+	if err := WriteFileAtomic(path+".bak", data, WriteOptions{}); err != nil {
+		return fmt.Errorf("backing up %s: %w", path, err)
+	}
+	return nil
+}
+
+// createExclusive writes data to a new file at path. It fails with an error
+// wrapping fs.ErrExist if the file already exists.
+func createExclusive(path string, data []byte, perm fs.FileMode) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		_ = os.Remove(path)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		_ = os.Remove(path)
+		return err
+	}
+	return f.Close()
+}
+
+// syncDir flushes a directory entry change to disk. Errors are ignored
+// because not every platform supports syncing directories.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	_ = d.Sync()
+	_ = d.Close()
+}
@@ -0,0 +1,104 @@
+package internal
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomicCreatesAndReplaces(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "note.md")
+
+	if err := WriteFileAtomic(path, []byte("one\n"), WriteOptions{}); err != nil {
+		t.Fatalf("WriteFileAtomic() error: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o644 {
+		t.Errorf("new file mode = %v, want 0644", info.Mode().Perm())
+	}
+
+	if err := os.Chmod(path, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFileAtomic(path, []byte("two\n"), WriteOptions{}); err != nil {
+		t.Fatalf("WriteFileAtomic() replace error: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if string(data) != "two\n" {
+		t.Errorf("content = %q, want %q", data, "two\n")
+	}
+	info, _ = os.Stat(path)
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("mode after replace = %v, want 0600", info.Mode().Perm())
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("expected no leftover temp files, got %d entries", len(entries))
+	}
+}
+
+func TestWriteFileAtomicExclusive(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "note.md")
+	if err := os.WriteFile(path, []byte("original\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := WriteFileAtomic(path, []byte("new\n"), WriteOptions{Exclusive: true})
+	if !errors.Is(err, fs.ErrExist) {
+		t.Fatalf("WriteFileAtomic(Exclusive) error = %v, want fs.ErrExist", err)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != "original\n" {
+		t.Errorf("existing file was modified: %q", data)
+	}
+
+	fresh := filepath.Join(dir, "fresh.md")
+	if err := WriteFileAtomic(fresh, []byte("fresh\n"), WriteOptions{Exclusive: true}); err != nil {
+		t.Fatalf("WriteFileAtomic(Exclusive) new file error: %v", err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("expected 2 files and no temp files, got %d entries", len(entries))
+	}
+}
+
+func TestRewriteFileBackup(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "note.md")
+	if err := os.WriteFile(path, []byte("before\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("MDNOTES_BACKUP", "")
+	if err := RewriteFile(path, []byte("middle\n")); err != nil {
+		t.Fatalf("RewriteFile() error: %v", err)
+	}
+	if _, err := os.Stat(path + ".bak"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected no backup without MDNOTES_BACKUP, stat error: %v", err)
+	}
+
+	t.Setenv("MDNOTES_BACKUP", "1")
+	if err := RewriteFile(path, []byte("after\n")); err != nil {
+		t.Fatalf("RewriteFile() error: %v", err)
+	}
+	bak, err := os.ReadFile(path + ".bak")
+	if err != nil {
+		t.Fatalf("reading backup: %v", err)
+	}
+	if string(bak) != "middle\n" {
+		t.Errorf("backup = %q, want %q", bak, "middle\n")
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != "after\n" {
+		t.Errorf("content = %q, want %q", data, "after\n")
+	}
+}